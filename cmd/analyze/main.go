@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/TonyGLL/gofetch/internal/analysis"
+	"github.com/TonyGLL/gofetch/internal/builder"
+	"github.com/TonyGLL/gofetch/internal/config"
+)
+
+func main() {
+	analyzerName := flag.String("analyzer", "", "Language or analyzer name (default: the configured language)")
+	field := flag.String("field", "", "Use the analyzer assigned to this field")
+	asJSON := flag.Bool("json", false, "Print the stages as JSON, like GET /api/v1/analyze")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [text]\n\nShows every step of an analyzer over the text, or standard input when no text is given.\n\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	text := strings.Join(flag.Args(), " ")
+	if text == "" {
+		input, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			log.Fatalf("Error reading standard input: %v", err)
+		}
+		text = string(input)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Error loading configuration: %v", err)
+	}
+	multi, err := builder.NewAnalyzer(&cfg)
+	if err != nil {
+		log.Fatalf("Error creating analyzer: %v", err)
+	}
+
+	analyzer := multi.For(*analyzerName)
+	if *analyzerName != "" {
+		var ok bool
+		if analyzer, ok = multi.Analyzer(*analyzerName); !ok {
+			log.Fatalf("Unknown analyzer %q", *analyzerName)
+		}
+	}
+	if *field != "" {
+		analyzer = multi.ForField(*field, analyzer.Name())
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(analyzer.Explanation(text)); err != nil {
+			log.Fatalf("Error encoding stages: %v", err)
+		}
+		return
+	}
+	printStages(os.Stdout, analyzer, text)
+}
+
+// printStages writes every step of the analyzer as a line of tokens, with
+// the byte offsets of the tokenizer's tokens and the tokens each filter
+// removed.
+func printStages(w io.Writer, analyzer *analysis.Analyzer, text string) {
+	fmt.Fprintf(w, "analyzer:   %s\ndefinition: %s\n\n", analyzer.Name(), analyzer.Definition())
+	for i, stage := range analyzer.Explain(text) {
+		terms := make([]string, len(stage.Tokens))
+		for j, token := range stage.Tokens {
+			if i == 0 {
+				terms[j] = fmt.Sprintf("%d:%s[%d:%d]", token.Position, token.Term, token.Start, token.End)
+			} else {
+				terms[j] = fmt.Sprintf("%d:%s", token.Position, token.Term)
+			}
+		}
+		fmt.Fprintf(w, "%-32s %s\n", stage.Name, strings.Join(terms, " "))
+		if len(stage.Removed) > 0 {
+			removed := make([]string, len(stage.Removed))
+			for j, token := range stage.Removed {
+				removed[j] = token.Term
+			}
+			fmt.Fprintf(w, "%-32s removed: %s\n", "", strings.Join(removed, " "))
+		}
+	}
+}
@@ -30,8 +30,11 @@ func main() {
 		}
 	}()
 
-	an := builder.NewAnalyzer()
-	idx := builder.NewIndexer(an, store)
+	an, err := builder.NewAnalyzer(&cfg)
+	if err != nil {
+		log.Fatalf("Error creating analyzer: %v", err)
+	}
+	idx := builder.NewIndexer(an, store, &cfg)
 
 	// Application entry point
 	fmt.Println("Crawler application started")
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/TonyGLL/gofetch/internal/builder"
+	"github.com/TonyGLL/gofetch/internal/config"
+	"github.com/TonyGLL/gofetch/internal/indexer"
+)
+
+func main() {
+	defaults := indexer.DefaultFieldMapping()
+	file := flag.String("file", "", "JSONL or CSV file to import (\"-\" reads from stdin)")
+	format := flag.String("format", "", "Input format: jsonl or csv (default: from the file extension)")
+	idField := flag.String("id", defaults.ID, "Key or column holding the document ID")
+	urlField := flag.String("url", defaults.URL, "Key or column holding the document URL")
+	titleField := flag.String("title", defaults.Title, "Key or column holding the title")
+	bodyField := flag.String("body", defaults.Body, "Key or column holding the body")
+	languageField := flag.String("language", defaults.Language, "Key or column holding the language")
+	labelField := flag.String("label-field", defaults.Label, "Key or column holding the source label")
+	fields := flag.String("fields", "", "Comma-separated keys or columns indexed as extra fields")
+	metadata := flag.String("metadata", "", "Comma-separated keys or columns stored as metadata (\"*\" for all unmapped)")
+	label := flag.String("label", "", "Label for records without one")
+	batchSize := flag.Int("batch", 100, "Documents per bulk write")
+	flag.Parse()
+
+	if *file == "" {
+		log.Fatal("the -file flag is required")
+	}
+	if *format == "" {
+		*format = formatFromPath(*file)
+	}
+
+	opts := indexer.ImportOptions{
+		Format: *format,
+		Mapping: indexer.FieldMapping{
+			ID:       *idField,
+			URL:      *urlField,
+			Title:    *titleField,
+			Body:     *bodyField,
+			Language: *languageField,
+			Label:    *labelField,
+			Fields:   splitList(*fields),
+			Metadata: splitList(*metadata),
+		},
+		Label:     *label,
+		BatchSize: *batchSize,
+	}
+	if err := run(*file, opts); err != nil {
+		fmt.Printf("Import error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(file string, opts indexer.ImportOptions) error {
+	var input io.Reader = os.Stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return fmt.Errorf("error opening %s: %w", file, err)
+		}
+		defer f.Close()
+		input = f
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("error loading configuration: %w", err)
+	}
+
+	ctx := context.Background()
+	store, err := builder.NewMongoStore(ctx, &cfg)
+	if err != nil {
+		return fmt.Errorf("error creating MongoStore: %w", err)
+	}
+	defer func() {
+		if err := store.Disconnect(ctx); err != nil {
+			panic(err)
+		}
+	}()
+
+	an, err := builder.NewAnalyzer(&cfg)
+	if err != nil {
+		return fmt.Errorf("error creating analyzer: %w", err)
+	}
+	idx := builder.NewIndexer(an, store, &cfg)
+	report, err := idx.ImportDocuments(ctx, input, opts)
+
+	fmt.Println("\n=== IMPORT SUMMARY ===")
+	fmt.Printf("Records read: %d\n", report.Read)
+	fmt.Printf("Created:      %d\n", report.Created)
+	fmt.Printf("Updated:      %d\n", report.Updated)
+	fmt.Printf("Superseded:   %d\n", report.Superseded)
+	fmt.Printf("Failed:       %d\n", report.Failed)
+	for _, msg := range report.Errors {
+		fmt.Printf("  - %s\n", msg)
+	}
+	fmt.Printf("Elapsed:      %s\n", report.Duration)
+	return err
+}
+
+// formatFromPath guesses the input format from a file extension.
+func formatFromPath(path string) string {
+	if strings.ToLower(filepath.Ext(path)) == ".csv" {
+		return indexer.FormatCSV
+	}
+	return indexer.FormatJSONL
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
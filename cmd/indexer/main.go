@@ -26,9 +26,12 @@ func main() {
 		}
 	}()
 
-	an := builder.NewAnalyzer()
-	idx := builder.NewIndexer(an, store)
-	if err := idx.IndexDirectory(cfg.Indexer.Path); err != nil {
+	an, err := builder.NewAnalyzer(&cfg)
+	if err != nil {
+		log.Fatalf("Error creating analyzer: %v", err)
+	}
+	idx := builder.NewIndexer(an, store, &cfg)
+	if err := idx.IndexSources(builder.NewIndexSources(&cfg)); err != nil {
 		fmt.Printf("Index error: %v\n", err)
 	} else {
 		fmt.Println("Indexing completed OK")
@@ -1,18 +1,20 @@
 package analysis
 
 import (
+	"fmt"
 	"os"
-	"regexp"
 	"strings"
-	"unicode"
 
 	"github.com/TonyGLL/gofetch/pkg/storage"
-	"github.com/kljensen/snowball"
 )
 
+// Analyzer turns text into index terms with a Tokenizer followed by a
+// sequence of TokenFilters.
 type Analyzer struct {
-	stopwords map[string]struct{}
+	name      string
 	language  string
+	tokenizer Tokenizer
+	filters   []TokenFilter
 }
 
 func NewEnglishAnalyzer() *Analyzer {
@@ -23,55 +25,58 @@ func NewSpanishAnalyzer() *Analyzer {
 	return New(storage.SpanishStopwords, "spanish")
 }
 
-func New(stopwords []string, language string) *Analyzer {
-	return &Analyzer{
-		stopwords: buildStopwordSet(stopwords),
-		language:  language,
-	}
+// foldingLanguages are the languages whose built-in analyzer indexes accent
+// folded terms next to the original ones. Folding is left out for languages
+// where diacritics distinguish letters rather than decorate them.
+var foldingLanguages = map[string]bool{
+	"english": true,
+	"spanish": true,
+	"french":  true,
 }
 
-func (a *Analyzer) Analyze(text string) []string {
-	// Tokenize input text
-	words := tokenize(text)
-
-	// Normalize every word, filter stopwords, and stem
-	result := []string{}
-	for _, word := range words {
-		normWord := normalize(word)
-		if normWord == "" {
-			continue
-		}
-		if _, isStopword := a.stopwords[normWord]; isStopword {
-			continue
-		}
+// elisionLanguages are the languages whose built-in analyzer removes elided
+// articles ("l'homme").
+var elisionLanguages = map[string]bool{
+	"french": true,
+}
 
-		stemmedWord, err := snowball.Stem(normWord, a.language, true)
-		if err != nil {
-			// If stemming fails, use the normalized word as a fallback.
-			result = append(result, normWord)
-		} else {
-			result = append(result, stemmedWord)
-		}
+// New creates the standard analyzer for a language: standard tokenizer, NFKC
+// normalization, CJK bigrams for any Chinese, Japanese or Korean text,
+// lowercase, stopwords and Snowball stemming. For the
+// languages in foldingLanguages, both "canción" and "cancion" are indexed
+// for the word "canción", so queries match with or without accents. The
+// analyzer is named after the language.
+func New(stopwords []string, language string) *Analyzer {
+	nfkc, _ := NewNormalizeFilter("nfkc")
+	filters := []TokenFilter{nfkc, CJKBigramFilter{}, LowercaseFilter{}}
+	if elisionLanguages[language] {
+		filters = append(filters, ElisionFilter{})
+	}
+	filters = append(filters, NewStopFilter(language, stopwords))
+	if foldingLanguages[language] {
+		filters = append(filters, ASCIIFoldingFilter{PreserveOriginal: true})
 	}
-	return result
+	if stemmer, err := NewStemmerFilter(language); err == nil {
+		filters = append(filters, stemmer)
+	}
+	filters = append(filters, UniqueFilter{})
+	return NewPipeline(language, language, StandardTokenizer{}, filters...)
 }
 
-func tokenize(text string) []string {
-	re := regexp.MustCompile(`[[:alpha:]]+`)
-	words := re.FindAllString(text, -1)
-	return words
+// NewLanguageAnalyzer creates the built-in analyzer of a language with its
+// bundled stopword list. Every Snowball language is supported.
+func NewLanguageAnalyzer(language string) (*Analyzer, error) {
+	language = strings.ToLower(language)
+	words, ok := Stopwords(language)
+	if !ok || !snowballLanguages[language] {
+		return nil, fmt.Errorf("unsupported language %q", language)
+	}
+	return New(words, language), nil
 }
 
-func normalize(word string) string {
-	lowerWord := strings.ToLower(word)
-	var builder strings.Builder
-	for _, r := range lowerWord {
-		// Keep letters and hyphen
-		if unicode.IsLetter(r) || r == '-' {
-			builder.WriteRune(r)
-		}
-	}
-	return builder.String()
+// Language returns the natural language the analyzer is built for.
+func (a *Analyzer) Language() string {
+	return a.language
 }
 
 func buildStopwordSet(stopwords []string) map[string]struct{} {
@@ -83,11 +88,48 @@ func buildStopwordSet(stopwords []string) map[string]struct{} {
 }
 
 func NewFromEnv() *Analyzer {
-	lang := os.Getenv("ANALYZER_LANGUAGE")
-	switch strings.ToLower(lang) {
-	case "spanish":
-		return NewSpanishAnalyzer()
-	default:
+	return newLanguageAnalyzer(os.Getenv("ANALYZER_LANGUAGE"))
+}
+
+// NewMultiFromEnv builds a MultiAnalyzer from ANALYZER_LANGUAGE. The value
+// "auto" enables per-document language detection across the supported
+// languages that have a detection profile; any other value pins the whole
+// corpus to that language. The code analyzer is always registered for
+// source files, and every other language analyzer is registered by name for
+// documents and sources that set their language.
+func NewMultiFromEnv() *MultiAnalyzer {
+	var multi *MultiAnalyzer
+	lang := strings.ToLower(os.Getenv("ANALYZER_LANGUAGE"))
+	if lang != AutoLanguage {
+		multi = NewMultiAnalyzer(newLanguageAnalyzer(lang), nil, NewCodeAnalyzer())
+	} else {
+		detector := NewDetector(SupportedLanguages...)
+		analyzers := []*Analyzer{NewCodeAnalyzer()}
+		for _, detected := range detector.Languages() {
+			analyzers = append(analyzers, newLanguageAnalyzer(detected))
+		}
+		multi = NewMultiAnalyzer(NewEnglishAnalyzer(), detector, analyzers...)
+	}
+	for _, language := range SupportedLanguages {
+		if _, ok := multi.Analyzer(language); !ok {
+			multi.Register(newLanguageAnalyzer(language))
+		}
+	}
+	if _, ok := multi.Analyzer(CJKLanguage); !ok {
+		multi.Register(NewCJKAnalyzer())
+	}
+	return multi
+}
+
+// newLanguageAnalyzer returns the built-in analyzer of lang, or the English
+// one when lang is not supported.
+func newLanguageAnalyzer(lang string) *Analyzer {
+	if strings.EqualFold(lang, CJKLanguage) {
+		return NewCJKAnalyzer()
+	}
+	a, err := NewLanguageAnalyzer(lang)
+	if err != nil {
 		return NewEnglishAnalyzer()
 	}
+	return a
 }
@@ -2,6 +2,7 @@ package analysis
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/TonyGLL/gofetch/pkg/storage"
@@ -43,7 +44,7 @@ func TestAnalyzer_Analyze(t *testing.T) {
 			language:       "english",
 			stopwords:      storage.EnglishStopwords,
 			inputText:      "123.45, -¡!@#$%^&*()_+",
-			expectedTokens: []string{},
+			expectedTokens: []string{"123.45"},
 		},
 		{
 			name:           "English stemming with common variations",
@@ -72,3 +73,67 @@ func TestAnalyzer_Analyze(t *testing.T) {
 		})
 	}
 }
+
+// benchmarkText is a few kilobytes of mixed English and Spanish prose, with
+// the accents, numbers and punctuation of typical documents.
+var benchmarkText = strings.Repeat(`The indexer walks every configured source, reads each file and analyzes
+its title and body before writing the postings to MongoDB. Running the
+analyzer over thousands of documents must stay fast: 1,250 files of 12.5 KB
+each are indexed in well under a minute on a laptop.
+La canción del verano se escuchó en todas las estaciones de radio, y los
+niños cantaban el estribillo camino a la escuela. ¿Qué pasará mañana?
+`, 8)
+
+func BenchmarkAnalyzer_Tokens(b *testing.B) {
+	analyzer := NewEnglishAnalyzer()
+	b.SetBytes(int64(len(benchmarkText)))
+	b.ReportAllocs()
+	for b.Loop() {
+		analyzer.Tokens(benchmarkText)
+	}
+}
+
+func BenchmarkAnalyzer_Stream(b *testing.B) {
+	analyzer := NewEnglishAnalyzer()
+	b.SetBytes(int64(len(benchmarkText)))
+	b.ReportAllocs()
+	for b.Loop() {
+		stream := analyzer.Stream(strings.NewReader(benchmarkText))
+		for stream.Next() {
+		}
+	}
+}
+
+func BenchmarkAnalyzer_StreamString(b *testing.B) {
+	analyzer := NewEnglishAnalyzer()
+	b.SetBytes(int64(len(benchmarkText)))
+	b.ReportAllocs()
+	for b.Loop() {
+		stream := analyzer.StreamString(benchmarkText)
+		for stream.Next() {
+		}
+	}
+}
+
+// BenchmarkAnalyzer_Analyze is the whole-slice path documents were indexed
+// with before token streams, to compare with BenchmarkAnalyzer_StreamString.
+func BenchmarkAnalyzer_Analyze(b *testing.B) {
+	analyzer := NewEnglishAnalyzer()
+	b.SetBytes(int64(len(benchmarkText)))
+	b.ReportAllocs()
+	for b.Loop() {
+		analyzer.Analyze(benchmarkText)
+	}
+}
+
+// BenchmarkAnalyzer_LetterTokenizer runs the English filters after the
+// regular expression tokenizer the analyzers split text with at first.
+func BenchmarkAnalyzer_LetterTokenizer(b *testing.B) {
+	english := NewEnglishAnalyzer()
+	analyzer := NewPipeline("letter", english.Language(), LetterTokenizer{}, english.filters...)
+	b.SetBytes(int64(len(benchmarkText)))
+	b.ReportAllocs()
+	for b.Loop() {
+		analyzer.Analyze(benchmarkText)
+	}
+}
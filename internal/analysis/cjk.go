@@ -0,0 +1,108 @@
+package analysis
+
+import (
+	"slices"
+	"unicode"
+	"unicode/utf8"
+)
+
+// CJKLanguage is the name of the built-in analyzer for Chinese, Japanese and
+// Korean text.
+const CJKLanguage = "cjk"
+
+// NewCJKAnalyzer creates an analyzer for text that is mostly Chinese,
+// Japanese or Korean. Latin words in it are lowercased and English
+// stopwords removed, without stemming.
+func NewCJKAnalyzer() *Analyzer {
+	nfkc, _ := NewNormalizeFilter("nfkc")
+	words, _ := Stopwords("english")
+	return NewPipeline(CJKLanguage, CJKLanguage, StandardTokenizer{},
+		nfkc,
+		CJKBigramFilter{},
+		LowercaseFilter{},
+		NewStopFilter("english", words),
+	)
+}
+
+// isCJK reports whether r belongs to a script written without spaces
+// between words, or to Hangul.
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// CJKBigramFilter replaces runs of Han, Hiragana, Katakana and Hangul
+// characters with their overlapping character bigrams, so "東京タワー" is
+// indexed as "東京", "京タ", "タワ" and "ワー" and a query for "東京" matches
+// it without a dictionary. A run is made of CJK tokens that follow each other
+// without a gap in the text; a run of one character is kept as a unigram.
+// Other tokens are left unchanged, so mixed text keeps the rest of the
+// pipeline for its Latin words. Bigrams get consecutive positions, and the
+// tokens after a run are shifted to follow them.
+type CJKBigramFilter struct{}
+
+func (CJKBigramFilter) Filter(tokens []Token) []Token {
+	if !slices.ContainsFunc(tokens, isCJKToken) {
+		return tokens
+	}
+	out := make([]Token, 0, len(tokens))
+	shift := 0
+	for i := 0; i < len(tokens); {
+		if !isCJKToken(tokens[i]) {
+			token := tokens[i]
+			token.Position += shift
+			out = append(out, token)
+			i++
+			continue
+		}
+
+		// Collect the characters of the run and their offsets.
+		type char struct{ start, end int }
+		var chars []char
+		var runes []rune
+		j := i
+		for j < len(tokens) && isCJKToken(tokens[j]) && (j == i || tokens[j].Start == tokens[j-1].End) {
+			// A term changed by an earlier filter no longer maps onto the text
+			// byte by byte: its characters then all get the token's offsets.
+			exact := tokens[j].End-tokens[j].Start == len(tokens[j].Term)
+			offset := tokens[j].Start
+			for _, r := range tokens[j].Term {
+				size := utf8.RuneLen(r)
+				if exact {
+					chars = append(chars, char{offset, offset + size})
+				} else {
+					chars = append(chars, char{tokens[j].Start, tokens[j].End})
+				}
+				runes = append(runes, r)
+				offset += size
+			}
+			j++
+		}
+
+		first := tokens[i].Position + shift
+		emitted := 1
+		if len(runes) == 1 {
+			out = append(out, Token{Term: string(runes), Position: first, Start: chars[0].start, End: chars[0].end})
+		} else {
+			emitted = len(runes) - 1
+			for k := 0; k < emitted; k++ {
+				out = append(out, Token{
+					Term:     string(runes[k : k+2]),
+					Position: first + k,
+					Start:    chars[k].start,
+					End:      chars[k+1].end,
+				})
+			}
+		}
+		shift += emitted - (tokens[j-1].Position - tokens[i].Position + 1)
+		i = j
+	}
+	return out
+}
+
+func (CJKBigramFilter) String() string { return "cjk_bigram" }
+
+// isCJKToken reports whether a token is a CJK word.
+func isCJKToken(token Token) bool {
+	r, _ := utf8.DecodeRuneInString(token.Term)
+	return isCJK(r)
+}
@@ -0,0 +1,66 @@
+package analysis
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestCJKBigramFilter(t *testing.T) {
+	testCases := []struct {
+		name           string
+		inputText      string
+		expectedTokens []Token
+	}{
+		{
+			name:      "Han and Katakana run",
+			inputText: "東京タワー",
+			expectedTokens: []Token{
+				{Term: "東京", Position: 0, Start: 0, End: 6},
+				{Term: "京タ", Position: 1, Start: 3, End: 9},
+				{Term: "タワ", Position: 2, Start: 6, End: 12},
+				{Term: "ワー", Position: 3, Start: 9, End: 15},
+			},
+		},
+		{
+			name:      "Latin words keep their place around CJK runs",
+			inputText: "Go 言語 and 日",
+			expectedTokens: []Token{
+				{Term: "Go", Position: 0, Start: 0, End: 2},
+				{Term: "言語", Position: 1, Start: 3, End: 9},
+				{Term: "and", Position: 2, Start: 10, End: 13},
+				{Term: "日", Position: 3, Start: 14, End: 17},
+			},
+		},
+		{
+			name:      "Hangul words are split at spaces",
+			inputText: "한국어 문서",
+			expectedTokens: []Token{
+				{Term: "한국", Position: 0, Start: 0, End: 6},
+				{Term: "국어", Position: 1, Start: 3, End: 9},
+				{Term: "문서", Position: 2, Start: 10, End: 16},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tokens := CJKBigramFilter{}.Filter(StandardTokenizer{}.Tokenize(tc.inputText))
+			if !reflect.DeepEqual(tokens, tc.expectedTokens) {
+				t.Errorf("Expected tokens %v, but got %v", tc.expectedTokens, tokens)
+			}
+		})
+	}
+}
+
+func TestAnalyzer_MixedCJKText(t *testing.T) {
+	english := NewEnglishAnalyzer()
+	document := english.Analyze("検索エンジンの running tests")
+	for _, query := range []string{"検索", "エンジン", "run"} {
+		for _, term := range english.Analyze(query) {
+			if !slices.Contains(document, term) {
+				t.Errorf("Query %q term %q not in document terms %v", query, term, document)
+			}
+		}
+	}
+}
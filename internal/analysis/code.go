@@ -0,0 +1,233 @@
+package analysis
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// CodeLanguage is the language of documents analyzed as source code.
+const CodeLanguage = "code"
+
+// minCodeTokenLength drops loop counters and other one-letter identifiers.
+const minCodeTokenLength = 2
+
+// NewCodeAnalyzer creates the analyzer for source code. Identifiers are kept
+// whole and also split at camelCase and snake_case boundaries, so
+// "GetPostingsForTerms" matches both itself and "postings". Tokens are
+// lowercased but neither stemmed nor filtered with prose stopwords.
+func NewCodeAnalyzer() *Analyzer {
+	return NewPipeline(CodeLanguage, CodeLanguage,
+		IdentifierTokenizer{},
+		WordDelimiterFilter{},
+		LowercaseFilter{},
+		LengthFilter{Min: minCodeTokenLength},
+	)
+}
+
+// SplitIdentifier splits an identifier into its lowercased words at
+// underscores and case changes: "parseHTTPRequest2" becomes
+// ["parse", "http", "request2"]. Digits stay with the word before them.
+func SplitIdentifier(ident string) []string {
+	var parts []string
+	for _, chunk := range strings.FieldsFunc(ident, func(r rune) bool { return r == '_' }) {
+		runes := []rune(chunk)
+		start := 0
+		for i := 1; i < len(runes); i++ {
+			prev, cur := runes[i-1], runes[i]
+			var next rune
+			if i+1 < len(runes) {
+				next = runes[i+1]
+			}
+			lowerToUpper := (unicode.IsLower(prev) || unicode.IsDigit(prev)) && unicode.IsUpper(cur)
+			acronymEnd := unicode.IsUpper(prev) && unicode.IsUpper(cur) && unicode.IsLower(next)
+			if lowerToUpper || acronymEnd {
+				parts = append(parts, strings.ToLower(string(runes[start:i])))
+				start = i
+			}
+		}
+		parts = append(parts, strings.ToLower(string(runes[start:])))
+	}
+	return parts
+}
+
+// CodeSyntax describes the comment and string literal delimiters of a
+// programming language.
+type CodeSyntax struct {
+	LineComments  []string
+	BlockComments [][2]string
+	Strings       []string // Delimiters, longest first, e.g. `"""` before `"`
+	RawStrings    []string // Delimiters in which backslash is not an escape
+}
+
+var (
+	cLikeSyntax = CodeSyntax{
+		LineComments:  []string{"//"},
+		BlockComments: [][2]string{{"/*", "*/"}},
+		Strings:       []string{`"`, "'"},
+	}
+	goSyntax = CodeSyntax{
+		LineComments:  []string{"//"},
+		BlockComments: [][2]string{{"/*", "*/"}},
+		Strings:       []string{`"`, "'", "`"},
+		RawStrings:    []string{"`"},
+	}
+	jsSyntax = CodeSyntax{
+		LineComments:  []string{"//"},
+		BlockComments: [][2]string{{"/*", "*/"}},
+		Strings:       []string{`"`, "'", "`"},
+	}
+	hashSyntax = CodeSyntax{
+		LineComments: []string{"#"},
+		Strings:      []string{`"`, "'"},
+	}
+	pythonSyntax = CodeSyntax{
+		LineComments: []string{"#"},
+		Strings:      []string{`"""`, "'''", `"`, "'"},
+	}
+	sqlSyntax = CodeSyntax{
+		LineComments:  []string{"--"},
+		BlockComments: [][2]string{{"/*", "*/"}},
+		Strings:       []string{"'", `"`},
+	}
+	phpSyntax = CodeSyntax{
+		LineComments:  []string{"//", "#"},
+		BlockComments: [][2]string{{"/*", "*/"}},
+		Strings:       []string{`"`, "'"},
+	}
+)
+
+// codeSyntaxes maps the recognised source file extensions to their syntax.
+var codeSyntaxes = map[string]CodeSyntax{
+	".go":    goSyntax,
+	".js":    jsSyntax,
+	".jsx":   jsSyntax,
+	".mjs":   jsSyntax,
+	".cjs":   jsSyntax,
+	".ts":    jsSyntax,
+	".tsx":   jsSyntax,
+	".java":  cLikeSyntax,
+	".kt":    cLikeSyntax,
+	".scala": cLikeSyntax,
+	".c":     cLikeSyntax,
+	".h":     cLikeSyntax,
+	".cc":    cLikeSyntax,
+	".cpp":   cLikeSyntax,
+	".hpp":   cLikeSyntax,
+	".cs":    cLikeSyntax,
+	".rs":    cLikeSyntax,
+	".swift": cLikeSyntax,
+	".php":   phpSyntax,
+	".py":    pythonSyntax,
+	".rb":    hashSyntax,
+	".sh":    hashSyntax,
+	".bash":  hashSyntax,
+	".sql":   sqlSyntax,
+}
+
+// IsCodeFile reports whether path has a recognised source file extension.
+func IsCodeFile(path string) bool {
+	_, ok := codeSyntaxes[strings.ToLower(filepath.Ext(path))]
+	return ok
+}
+
+// CodeSyntaxFor returns the syntax for a source file, falling back to
+// C-style comments and strings for unrecognised extensions.
+func CodeSyntaxFor(path string) CodeSyntax {
+	if syntax, ok := codeSyntaxes[strings.ToLower(filepath.Ext(path))]; ok {
+		return syntax
+	}
+	return cLikeSyntax
+}
+
+// CodeParts is source code split into the code itself, its comments and
+// the contents of its string literals.
+type CodeParts struct {
+	Code     string
+	Comments string
+	Strings  string
+}
+
+// SplitCode separates the comments and string literals of source code. Each
+// comment and literal is replaced by a space in Code and ends a line in
+// Comments or Strings.
+func SplitCode(text string, syntax CodeSyntax) CodeParts {
+	var code, comments, literals strings.Builder
+	for i := 0; i < len(text); {
+		if delim := matchPrefix(text[i:], syntax.LineComments); delim != "" {
+			end := strings.IndexByte(text[i:], '\n')
+			if end < 0 {
+				end = len(text) - i
+			}
+			comments.WriteString(text[i+len(delim) : i+end])
+			comments.WriteByte('\n')
+			code.WriteByte(' ')
+			i += end
+			continue
+		}
+		if open, closing := matchBlock(text[i:], syntax.BlockComments); open != "" {
+			body := text[i+len(open):]
+			end := strings.Index(body, closing)
+			if end < 0 {
+				end = len(body)
+				closing = ""
+			}
+			comments.WriteString(body[:end])
+			comments.WriteByte('\n')
+			code.WriteByte(' ')
+			i += len(open) + end + len(closing)
+			continue
+		}
+		if delim := matchPrefix(text[i:], syntax.Strings); delim != "" {
+			n := scanString(text[i+len(delim):], delim, slices.Contains(syntax.RawStrings, delim))
+			literals.WriteString(text[i+len(delim) : i+len(delim)+n])
+			literals.WriteByte('\n')
+			code.WriteByte(' ')
+			i += len(delim) + n
+			if strings.HasPrefix(text[i:], delim) {
+				i += len(delim)
+			}
+			continue
+		}
+		code.WriteByte(text[i])
+		i++
+	}
+	return CodeParts{Code: code.String(), Comments: comments.String(), Strings: literals.String()}
+}
+
+// scanString returns the length of a string literal body up to its closing
+// delimiter. Single-character delimiters other than backquotes do not span
+// lines, which keeps a stray apostrophe from swallowing the rest of a file.
+func scanString(s, delim string, raw bool) int {
+	multiline := len(delim) > 1 || delim == "`"
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && !raw:
+			i++
+		case strings.HasPrefix(s[i:], delim):
+			return i
+		case s[i] == '\n' && !multiline:
+			return i
+		}
+	}
+	return len(s)
+}
+
+func matchPrefix(s string, prefixes []string) string {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return p
+		}
+	}
+	return ""
+}
+
+func matchBlock(s string, blocks [][2]string) (string, string) {
+	for _, b := range blocks {
+		if strings.HasPrefix(s, b[0]) {
+			return b[0], b[1]
+		}
+	}
+	return "", ""
+}
@@ -0,0 +1,95 @@
+package analysis
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCodeAnalyzer_Analyze(t *testing.T) {
+	testCases := []struct {
+		name           string
+		inputText      string
+		expectedTokens []string
+	}{
+		{
+			name:           "camelCase identifier is kept whole and split",
+			inputText:      "store.GetPostingsForTerms(ctx)",
+			expectedTokens: []string{"store", "getpostingsforterms", "get", "postings", "for", "terms", "ctx"},
+		},
+		{
+			name:           "snake_case identifier keeps underscores and digits",
+			inputText:      "max_doc_len2 = utf8",
+			expectedTokens: []string{"max_doc_len2", "max", "doc", "len2", "utf8"},
+		},
+		{
+			name:           "Acronyms, number literals and short names",
+			inputText:      "parseHTTPRequest(i, 0x1F, 10)",
+			expectedTokens: []string{"parsehttprequest", "parse", "http", "request"},
+		},
+		{
+			name:           "No stemming or stopwords",
+			inputText:      "the running tests",
+			expectedTokens: []string{"the", "running", "tests"},
+		},
+	}
+
+	analyzer := NewCodeAnalyzer()
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tokens := analyzer.Analyze(tc.inputText)
+			if !reflect.DeepEqual(tokens, tc.expectedTokens) {
+				t.Errorf("Expected tokens %v, but got %v", tc.expectedTokens, tokens)
+			}
+		})
+	}
+}
+
+func TestSplitCode(t *testing.T) {
+	src := "// Package x does things.\n" +
+		"func Get() string {\n" +
+		"\t/* block\n comment */ return \"hello \\\"world\\\"\" + `raw\\n` // trailing\n" +
+		"}\n"
+	parts := SplitCode(src, CodeSyntaxFor("main.go"))
+
+	if strings.Contains(parts.Code, "Package") || strings.Contains(parts.Code, "hello") || !strings.Contains(parts.Code, "func Get()") {
+		t.Errorf("Unexpected code: %q", parts.Code)
+	}
+	for _, want := range []string{"Package x does things.", "block\n comment", "trailing"} {
+		if !strings.Contains(parts.Comments, want) {
+			t.Errorf("Expected comments to contain %q, got %q", want, parts.Comments)
+		}
+	}
+	if parts.Strings != "hello \\\"world\\\"\nraw\\n\n" {
+		t.Errorf("Unexpected strings: %q", parts.Strings)
+	}
+
+	py := SplitCode("x = '''doc # not a comment'''  # real\n", CodeSyntaxFor("tool.py"))
+	if py.Strings != "doc # not a comment\n" || py.Comments != " real\n" {
+		t.Errorf("Unexpected python split: %+v", py)
+	}
+}
+
+func TestIsCodeFile(t *testing.T) {
+	if !IsCodeFile("internal/search/searcher.go") || !IsCodeFile("ui/App.TSX") || IsCodeFile("README.md") {
+		t.Error("IsCodeFile did not recognise source file extensions")
+	}
+}
+
+func TestCodeAnalyzer_PartsSharePosition(t *testing.T) {
+	// Parts of an identifier are alternatives for it, so phrases and NEAR
+	// distances across the identifier count it as one word.
+	tokens := NewCodeAnalyzer().Tokens("store.GetPostingsForTerms(ctx)")
+	positions := make(map[string]int, len(tokens))
+	for _, token := range tokens {
+		positions[token.Term] = token.Position
+	}
+	for _, part := range []string{"getpostingsforterms", "get", "postings", "for", "terms"} {
+		if positions[part] != 1 {
+			t.Errorf("Expected %q at position 1, got %d", part, positions[part])
+		}
+	}
+	if positions["ctx"] != 2 {
+		t.Errorf("Expected ctx right after the identifier at position 2, got %d", positions["ctx"])
+	}
+}
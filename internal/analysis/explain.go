@@ -0,0 +1,101 @@
+package analysis
+
+// Stage is the token stream after one step of an analyzer: the tokenizer or
+// one of its filters.
+type Stage struct {
+	Name    string  // The tokenizer or filter, as written in the definition
+	Tokens  []Token // The tokens after this step
+	Removed []Token // Tokens of the previous step whose text no token covers any more
+}
+
+// Explain runs the pipeline over text like Tokens and returns the tokens after
+// every step, so the effect of each filter can be inspected: where stopwords
+// were removed, how words were stemmed or expanded.
+func (a *Analyzer) Explain(text string) []Stage {
+	tokens := a.tokenizer.Tokenize(text)
+	stages := make([]Stage, 0, len(a.filters)+1)
+	stages = append(stages, Stage{Name: a.tokenizer.String(), Tokens: tokens})
+	for _, filter := range a.filters {
+		var out []Token
+		if len(tokens) > 0 {
+			// Filters may rewrite their input in place: keep each stage intact.
+			out = filter.Filter(append([]Token(nil), tokens...))
+		}
+		stages = append(stages, Stage{Name: filter.String(), Tokens: out, Removed: removedTokens(tokens, out)})
+		tokens = out
+	}
+	return stages
+}
+
+// removedTokens returns the tokens of before whose text does not overlap any
+// token of after.
+func removedTokens(before, after []Token) []Token {
+	var removed []Token
+	for _, token := range before {
+		covered := false
+		for _, kept := range after {
+			if kept.Start < token.End && token.Start < kept.End {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			removed = append(removed, token)
+		}
+	}
+	return removed
+}
+
+// Explanation shows every step of an analyzer over a text, as returned by
+// the analyze endpoint and the analyze command.
+type Explanation struct {
+	Analyzer   string           `json:"analyzer"`
+	Definition string           `json:"definition"`
+	Stages     []ExplainedStage `json:"stages"`
+	Terms      []string         `json:"terms"`
+}
+
+// ExplainedStage is the token stream after the tokenizer or one filter.
+type ExplainedStage struct {
+	Name    string           `json:"name"`
+	Tokens  []ExplainedToken `json:"tokens"`
+	Removed []ExplainedToken `json:"removed,omitempty"`
+}
+
+// ExplainedToken is a token with its position and byte offsets in the text.
+type ExplainedToken struct {
+	Term     string `json:"term"`
+	Position int    `json:"position"`
+	Start    int    `json:"start"`
+	End      int    `json:"end"`
+}
+
+// Explanation runs the analyzer over text and describes every step.
+func (a *Analyzer) Explanation(text string) Explanation {
+	stages := a.Explain(text)
+	explanation := Explanation{
+		Analyzer:   a.Name(),
+		Definition: a.Definition(),
+		Stages:     make([]ExplainedStage, len(stages)),
+		Terms:      []string{},
+	}
+	for i, stage := range stages {
+		explanation.Stages[i] = ExplainedStage{
+			Name:    stage.Name,
+			Tokens:  explainedTokens(stage.Tokens),
+			Removed: explainedTokens(stage.Removed),
+		}
+	}
+	for _, token := range stages[len(stages)-1].Tokens {
+		explanation.Terms = append(explanation.Terms, token.Term)
+	}
+	return explanation
+}
+
+func explainedTokens(tokens []Token) []ExplainedToken {
+	out := make([]ExplainedToken, len(tokens))
+	for i, token := range tokens {
+		out[i] = ExplainedToken{Term: token.Term, Position: token.Position, Start: token.Start, End: token.End}
+	}
+	return out
+}
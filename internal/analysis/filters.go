@@ -0,0 +1,439 @@
+package analysis
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/kljensen/snowball"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// snowballLanguages are the languages supported by the stemmer filter.
+var snowballLanguages = map[string]bool{
+	"english":   true,
+	"spanish":   true,
+	"french":    true,
+	"russian":   true,
+	"swedish":   true,
+	"norwegian": true,
+	"hungarian": true,
+}
+
+// LowercaseFilter lowercases every token and drops empty ones.
+type LowercaseFilter struct{}
+
+func (LowercaseFilter) Filter(tokens []Token) []Token {
+	out := tokens[:0]
+	for _, token := range tokens {
+		token.Term = strings.ToLower(token.Term)
+		if token.Term != "" {
+			out = append(out, token)
+		}
+	}
+	return out
+}
+
+func (LowercaseFilter) String() string { return "lowercase" }
+
+// NormalizeFilter applies a Unicode normalization form to every token, so
+// text in composed and decomposed form ("é" as one code point or as "e" and
+// a combining accent) gives the same terms. The compatibility forms (NFKC,
+// NFKD) also fold ligatures, full-width characters and superscripts.
+type NormalizeFilter struct {
+	form norm.Form
+	name string
+}
+
+// normalizationForms are the forms accepted by NewNormalizeFilter.
+var normalizationForms = map[string]norm.Form{
+	"nfc":  norm.NFC,
+	"nfd":  norm.NFD,
+	"nfkc": norm.NFKC,
+	"nfkd": norm.NFKD,
+}
+
+// NewNormalizeFilter creates a normalization filter for the form "nfc",
+// "nfd", "nfkc" or "nfkd". An empty form selects NFC.
+func NewNormalizeFilter(form string) (NormalizeFilter, error) {
+	name := strings.ToLower(form)
+	if name == "" {
+		name = "nfc"
+	}
+	f, ok := normalizationForms[name]
+	if !ok {
+		return NormalizeFilter{}, fmt.Errorf("unknown normalization form %q", form)
+	}
+	return NormalizeFilter{form: f, name: name}, nil
+}
+
+func (f NormalizeFilter) Filter(tokens []Token) []Token {
+	for i, token := range tokens {
+		if !isASCII(token.Term) && !f.form.IsNormalString(token.Term) {
+			tokens[i].Term = f.form.String(token.Term)
+		}
+	}
+	return tokens
+}
+
+func (f NormalizeFilter) String() string { return "normalize(" + f.name + ")" }
+
+// ASCIIFoldingFilter removes diacritics and replaces letters without an
+// ASCII decomposition ("ß", "æ", "ø") with their usual transliteration, so
+// "canción" matches "cancion". With PreserveOriginal the unfolded token is
+// kept too, at the same position, so a query spelled with the accents scores
+// documents spelled the same way higher.
+type ASCIIFoldingFilter struct {
+	PreserveOriginal bool
+}
+
+// foldedLetters are the letters that do not decompose into a base letter
+// and combining marks.
+var foldedLetters = strings.NewReplacer(
+	"ß", "ss", "æ", "ae", "Æ", "AE", "œ", "oe", "Œ", "OE",
+	"ø", "o", "Ø", "O", "đ", "d", "Đ", "D", "ł", "l", "Ł", "L", "þ", "th", "Þ", "TH",
+)
+
+// foldedLetterSet lists the letters of foldedLetters, so terms without any
+// of them skip the replacer, which allocates for every term.
+const foldedLetterSet = "ßæÆœŒøØđĐłŁþÞ"
+
+func (f ASCIIFoldingFilter) Filter(tokens []Token) []Token {
+	if f.PreserveOriginal {
+		out := make([]Token, 0, len(tokens)+len(tokens)/4)
+		for _, token := range tokens {
+			out = append(out, token)
+			if folded := foldASCII(token.Term); folded != token.Term && folded != "" {
+				token.Term = folded
+				out = append(out, token)
+			}
+		}
+		return out
+	}
+	out := tokens[:0]
+	for _, token := range tokens {
+		token.Term = foldASCII(token.Term)
+		if token.Term != "" {
+			out = append(out, token)
+		}
+	}
+	return out
+}
+
+func (f ASCIIFoldingFilter) String() string {
+	if f.PreserveOriginal {
+		return "asciifolding(preserve_original)"
+	}
+	return "asciifolding"
+}
+
+// foldTransformers reuse the transformers that strip combining marks:
+// building one allocates its buffers, and they are not safe for concurrent use.
+var foldTransformers = sync.Pool{
+	New: func() any {
+		return transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	},
+}
+
+func foldASCII(term string) string {
+	if isASCII(term) {
+		return term
+	}
+	if strings.ContainsAny(term, foldedLetterSet) {
+		term = foldedLetters.Replace(term)
+	}
+	t := foldTransformers.Get().(transform.Transformer)
+	folded, _, err := transform.String(t, term)
+	foldTransformers.Put(t)
+	if err != nil {
+		return term
+	}
+	return folded
+}
+
+// isASCII reports whether s has only ASCII characters, which need no
+// normalization or folding.
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// StopFilter drops the tokens in a stopword list.
+type StopFilter struct {
+	name  string
+	words map[string]struct{}
+}
+
+// NewStopFilter creates a stop filter; name identifies the list in the
+// analyzer definition, e.g. "english".
+func NewStopFilter(name string, words []string) StopFilter {
+	return StopFilter{name: name, words: buildStopwordSet(words)}
+}
+
+func (f StopFilter) Filter(tokens []Token) []Token {
+	out := tokens[:0]
+	for _, token := range tokens {
+		if _, isStopword := f.words[token.Term]; !isStopword {
+			out = append(out, token)
+		}
+	}
+	return out
+}
+
+func (f StopFilter) String() string {
+	return fmt.Sprintf("stop(%s:%d)", f.name, len(f.words))
+}
+
+// ElisionFilter removes the elided articles and pronouns of French and
+// Italian words, so "l'homme" is indexed as "homme".
+type ElisionFilter struct{}
+
+// elisions are the prefixes removed by ElisionFilter.
+var elisions = []string{"l", "d", "j", "m", "n", "s", "t", "c", "qu", "jusqu", "quoiqu", "lorsqu", "puisqu", "dell", "un", "nell", "sull", "all"}
+
+func (ElisionFilter) Filter(tokens []Token) []Token {
+	for i, token := range tokens {
+		if prefix, rest, ok := cutApostrophe(token.Term); ok && rest != "" && slices.Contains(elisions, strings.ToLower(prefix)) {
+			tokens[i].Term = rest
+		}
+	}
+	return tokens
+}
+
+func (ElisionFilter) String() string { return "elision" }
+
+func cutApostrophe(term string) (before, after string, found bool) {
+	if i := strings.IndexAny(term, "'’"); i >= 0 {
+		_, size := utf8.DecodeRuneInString(term[i:])
+		return term[:i], term[i+size:], true
+	}
+	return term, "", false
+}
+
+// StemmerFilter reduces tokens to their Snowball stem. Stems are cached, as
+// a few thousand words make up most of any text.
+type StemmerFilter struct {
+	language string
+	cache    *stemCache
+}
+
+// NewStemmerFilter creates a Snowball stemmer for one of the supported languages.
+func NewStemmerFilter(language string) (StemmerFilter, error) {
+	language = strings.ToLower(language)
+	if !snowballLanguages[language] {
+		return StemmerFilter{}, fmt.Errorf("no stemmer for language %q", language)
+	}
+	return StemmerFilter{language: language, cache: &stemCache{stems: make(map[string]string)}}, nil
+}
+
+func (f StemmerFilter) Filter(tokens []Token) []Token {
+	for i, token := range tokens {
+		if stemmed, ok := f.cache.get(token.Term); ok {
+			tokens[i].Term = stemmed
+			continue
+		}
+		stemmed, err := snowball.Stem(token.Term, f.language, true)
+		if err == nil {
+			f.cache.put(token.Term, stemmed)
+			tokens[i].Term = stemmed
+		} // If stemming fails, keep the token as a fallback.
+	}
+	return tokens
+}
+
+// stemCacheSize is the number of stems a StemmerFilter keeps. The cache is
+// emptied when it fills up, which keeps the common words after a while.
+const stemCacheSize = 50000
+
+// stemCache maps words to their stems. A nil cache stores nothing.
+type stemCache struct {
+	mu    sync.RWMutex
+	stems map[string]string
+}
+
+func (c *stemCache) get(word string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	stem, ok := c.stems[word]
+	return stem, ok
+}
+
+func (c *stemCache) put(word, stem string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.stems) >= stemCacheSize {
+		clear(c.stems)
+	}
+	// Words are cut from the text they were found in: copy them so the cache
+	// does not keep whole documents alive.
+	c.stems[strings.Clone(word)] = stem
+}
+
+func (f StemmerFilter) String() string {
+	return "stemmer(" + f.language + ")"
+}
+
+// LengthFilter drops tokens shorter than Min or longer than Max runes. A
+// zero Max means no upper limit.
+type LengthFilter struct {
+	Min int
+	Max int
+}
+
+func (f LengthFilter) Filter(tokens []Token) []Token {
+	out := tokens[:0]
+	for _, token := range tokens {
+		n := utf8.RuneCountInString(token.Term)
+		if n >= f.Min && (f.Max == 0 || n <= f.Max) {
+			out = append(out, token)
+		}
+	}
+	return out
+}
+
+func (f LengthFilter) String() string {
+	return fmt.Sprintf("length(%d,%d)", f.Min, f.Max)
+}
+
+// NGramFilter replaces every token with its character n-grams of Min to Max
+// runes, all at the token's position. Tokens shorter than Min are kept whole.
+type NGramFilter struct {
+	Min int
+	Max int
+}
+
+func (f NGramFilter) Filter(tokens []Token) []Token {
+	out := make([]Token, 0, len(tokens))
+	for _, token := range tokens {
+		runes := []rune(token.Term)
+		if len(runes) < f.Min {
+			out = append(out, token)
+			continue
+		}
+		for n := f.Min; n <= f.Max && n <= len(runes); n++ {
+			for start := 0; start+n <= len(runes); start++ {
+				token.Term = string(runes[start : start+n])
+				out = append(out, token)
+			}
+		}
+	}
+	return out
+}
+
+func (f NGramFilter) String() string {
+	return fmt.Sprintf("ngram(%d,%d)", f.Min, f.Max)
+}
+
+// EdgeNGramFilter replaces every token with its prefixes of Min to Max runes,
+// all at the token's position, so a query for "index" matches "indexer".
+// Tokens shorter than Min are kept whole.
+type EdgeNGramFilter struct {
+	Min int
+	Max int
+}
+
+func (f EdgeNGramFilter) Filter(tokens []Token) []Token {
+	out := make([]Token, 0, len(tokens))
+	for _, token := range tokens {
+		runes := []rune(token.Term)
+		if len(runes) < f.Min {
+			out = append(out, token)
+			continue
+		}
+		for n := f.Min; n <= f.Max && n <= len(runes); n++ {
+			token.Term = string(runes[:n])
+			out = append(out, token)
+		}
+	}
+	return out
+}
+
+func (f EdgeNGramFilter) String() string {
+	return fmt.Sprintf("edge_ngram(%d,%d)", f.Min, f.Max)
+}
+
+// TruncateFilter cuts tokens to at most Length runes. It matches query terms
+// to an EdgeNGramFilter with the same maximum.
+type TruncateFilter struct {
+	Length int
+}
+
+func (f TruncateFilter) Filter(tokens []Token) []Token {
+	for i, token := range tokens {
+		if runes := []rune(token.Term); len(runes) > f.Length {
+			tokens[i].Term = string(runes[:f.Length])
+		}
+	}
+	return tokens
+}
+
+func (f TruncateFilter) String() string {
+	return fmt.Sprintf("truncate(%d)", f.Length)
+}
+
+// WordDelimiterFilter keeps every token, without leading or trailing
+// underscores, and adds its camelCase and snake_case parts when it has
+// more than one. Parts share the position of the original token.
+type WordDelimiterFilter struct{}
+
+func (WordDelimiterFilter) Filter(tokens []Token) []Token {
+	out := make([]Token, 0, len(tokens))
+	for _, token := range tokens {
+		whole := strings.Trim(token.Term, "_")
+		if whole == "" {
+			continue
+		}
+		token.Term = whole
+		out = append(out, token)
+		parts := SplitIdentifier(whole)
+		if len(parts) < 2 {
+			continue
+		}
+		for _, part := range parts {
+			token.Term = part
+			out = append(out, token)
+		}
+	}
+	return out
+}
+
+func (WordDelimiterFilter) String() string { return "word_delimiter" }
+
+// UniqueFilter drops a token when an earlier token at the same position has
+// the same term, e.g. when the folded and original forms stem alike, so the
+// term is not counted twice.
+type UniqueFilter struct{}
+
+func (UniqueFilter) Filter(tokens []Token) []Token {
+	out := tokens[:0]
+	for _, token := range tokens {
+		duplicate := false
+		for j := len(out) - 1; j >= 0 && out[j].Position == token.Position; j-- {
+			if out[j].Term == token.Term {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			out = append(out, token)
+		}
+	}
+	return out
+}
+
+func (UniqueFilter) String() string { return "unique" }
@@ -0,0 +1,87 @@
+package analysis
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestNormalizeFilter(t *testing.T) {
+	nfc, err := NewNormalizeFilter("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	decomposed := "cancio\u0301n" // "o" followed by a combining acute accent
+	got := nfc.Filter([]Token{{Term: decomposed}})
+	if got[0].Term != "canción" {
+		t.Errorf("Expected NFC to compose the accent, got %q", got[0].Term)
+	}
+
+	nfkc, _ := NewNormalizeFilter("NFKC")
+	got = nfkc.Filter([]Token{{Term: "ﬁle"}, {Term: "ＡＢＣ１"}})
+	if got[0].Term != "file" || got[1].Term != "ABC1" {
+		t.Errorf("Expected NFKC to fold compatibility characters, got %v", got)
+	}
+
+	if _, err := NewNormalizeFilter("nfx"); err == nil {
+		t.Error("Expected an error for an unknown form")
+	}
+}
+
+func TestASCIIFoldingFilter_PreserveOriginal(t *testing.T) {
+	tokens := []Token{{Term: "canción", Position: 0}, {Term: "de", Position: 1}}
+	got := ASCIIFoldingFilter{PreserveOriginal: true}.Filter(tokens)
+	expected := []Token{{Term: "canción", Position: 0}, {Term: "cancion", Position: 0}, {Term: "de", Position: 1}}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, but got %v", expected, got)
+	}
+}
+
+func TestUniqueFilter(t *testing.T) {
+	tokens := []Token{{Term: "run", Position: 0}, {Term: "run", Position: 0}, {Term: "run", Position: 1}}
+	got := UniqueFilter{}.Filter(tokens)
+	if len(got) != 2 || got[1].Position != 1 {
+		t.Errorf("Expected duplicates at the same position to be dropped, got %v", got)
+	}
+}
+
+func TestAnalyzer_AccentInsensitive(t *testing.T) {
+	spanish := NewSpanishAnalyzer()
+	document := spanish.Analyze("La canción del niño")
+	for _, query := range []string{"canción", "cancion", "CANCIÓN", "nino", "niño"} {
+		for _, term := range spanish.Analyze(query) {
+			if !slices.Contains(document, term) {
+				t.Errorf("Query %q term %q not in document terms %v", query, term, document)
+			}
+		}
+	}
+	if accented := spanish.Analyze("niño"); len(accented) != 2 {
+		t.Errorf("Expected the accented query to keep both forms, got %v", accented)
+	}
+}
+
+func TestPrefixAnalyzers(t *testing.T) {
+	index, search := NewPrefixAnalyzers(2, 5)
+	if got := index.Analyze("Índexer a"); !reflect.DeepEqual(got, []string{"in", "ind", "inde", "index", "a"}) {
+		t.Errorf("Unexpected prefixes %v", got)
+	}
+	if got := search.Analyze("INDEX indexing"); !reflect.DeepEqual(got, []string{"index", "index"}) {
+		t.Errorf("Expected whole query terms cut to the longest prefix, got %v", got)
+	}
+
+	multi := NewMultiAnalyzer(NewEnglishAnalyzer(), nil)
+	multi.Register(index)
+	multi.Register(search)
+	if err := multi.SetFieldAnalyzer("prefix", index.Name()); err != nil {
+		t.Fatal(err)
+	}
+	if err := multi.SetFieldSearchAnalyzer("prefix", search.Name()); err != nil {
+		t.Fatal(err)
+	}
+	if got := multi.AnalyzeFieldQuery("prefix", "ind", ""); !reflect.DeepEqual(got, []string{"ind"}) {
+		t.Errorf("Expected the search analyzer for queries, got %v", got)
+	}
+	if multi.ForField("prefix", "english") != index {
+		t.Error("Expected documents to use the index analyzer")
+	}
+}
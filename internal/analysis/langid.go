@@ -0,0 +1,174 @@
+package analysis
+
+import (
+	"embed"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// profileSize is the number of ranked n-grams kept for every language profile.
+const profileSize = 300
+
+// minDetectLetters is the minimum number of letters needed to attempt a detection.
+const minDetectLetters = 20
+
+// maxDetectRunes bounds how much of a document is used to build its profile.
+const maxDetectRunes = 10000
+
+//go:embed profiles/*.txt
+var profileFiles embed.FS
+
+var (
+	builtinProfilesOnce sync.Once
+	builtinProfiles     map[string]map[string]int
+)
+
+// Detector identifies the language of a text using character n-gram profiles
+// (Cavnar & Trenkle). Each language profile is built from an embedded sample
+// text and ranks its most frequent 1- to 3-grams.
+type Detector struct {
+	profiles map[string]map[string]int
+}
+
+// NewDetector creates a Detector for the given languages. Languages without an
+// embedded profile are ignored. With no arguments every bundled profile is used.
+func NewDetector(languages ...string) *Detector {
+	all := loadBuiltinProfiles()
+	profiles := make(map[string]map[string]int)
+	if len(languages) == 0 {
+		for lang, p := range all {
+			profiles[lang] = p
+		}
+	}
+	for _, lang := range languages {
+		if p, ok := all[lang]; ok {
+			profiles[lang] = p
+		}
+	}
+	return &Detector{profiles: profiles}
+}
+
+// Languages returns the languages the detector can recognize, sorted by name.
+func (d *Detector) Languages() []string {
+	langs := make([]string, 0, len(d.profiles))
+	for lang := range d.profiles {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Detect returns the most likely language of text, or an empty string when the
+// text is too short to decide.
+func (d *Detector) Detect(text string) string {
+	if len(d.profiles) == 0 {
+		return ""
+	}
+	letters := 0
+	for _, r := range text {
+		if unicode.IsLetter(r) {
+			letters++
+			if letters >= minDetectLetters {
+				break
+			}
+		}
+	}
+	if letters < minDetectLetters {
+		return ""
+	}
+
+	if runes := []rune(text); len(runes) > maxDetectRunes {
+		text = string(runes[:maxDetectRunes])
+	}
+	docProfile := buildProfile(text)
+
+	best, bestDistance := "", -1
+	for _, lang := range d.Languages() {
+		distance := profileDistance(docProfile, d.profiles[lang])
+		if bestDistance < 0 || distance < bestDistance {
+			best, bestDistance = lang, distance
+		}
+	}
+	return best
+}
+
+// profileDistance computes the "out-of-place" measure between a document
+// profile and a language profile. Lower is closer.
+func profileDistance(doc, lang map[string]int) int {
+	distance := 0
+	for gram, rank := range doc {
+		langRank, ok := lang[gram]
+		if !ok {
+			distance += profileSize
+			continue
+		}
+		if rank > langRank {
+			distance += rank - langRank
+		} else {
+			distance += langRank - rank
+		}
+	}
+	return distance
+}
+
+// buildProfile ranks the most frequent character n-grams of text.
+func buildProfile(text string) map[string]int {
+	counts := make(map[string]int)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}) {
+		padded := []rune("_" + word + "_")
+		for n := 1; n <= 3; n++ {
+			for i := 0; i+n <= len(padded); i++ {
+				gram := string(padded[i : i+n])
+				if gram == "_" {
+					continue
+				}
+				counts[gram]++
+			}
+		}
+	}
+
+	grams := make([]string, 0, len(counts))
+	for gram := range counts {
+		grams = append(grams, gram)
+	}
+	sort.Slice(grams, func(i, j int) bool {
+		if counts[grams[i]] != counts[grams[j]] {
+			return counts[grams[i]] > counts[grams[j]]
+		}
+		return grams[i] < grams[j]
+	})
+	if len(grams) > profileSize {
+		grams = grams[:profileSize]
+	}
+
+	profile := make(map[string]int, len(grams))
+	for rank, gram := range grams {
+		profile[gram] = rank
+	}
+	return profile
+}
+
+// loadBuiltinProfiles builds the profiles of the embedded sample texts once.
+func loadBuiltinProfiles() map[string]map[string]int {
+	builtinProfilesOnce.Do(func() {
+		builtinProfiles = make(map[string]map[string]int)
+		entries, err := profileFiles.ReadDir("profiles")
+		if err != nil {
+			return
+		}
+		for _, entry := range entries {
+			data, err := profileFiles.ReadFile(path.Join("profiles", entry.Name()))
+			if err != nil {
+				continue
+			}
+			lang := strings.TrimSuffix(entry.Name(), path.Ext(entry.Name()))
+			builtinProfiles[lang] = buildProfile(string(data))
+		}
+	})
+	return builtinProfiles
+}
@@ -0,0 +1,96 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDetector_Detect(t *testing.T) {
+	testCases := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{
+			name:     "English sentence",
+			text:     "The crawler respects robots.txt and stores every page it visits in the index.",
+			expected: "english",
+		},
+		{
+			name:     "Spanish sentence",
+			text:     "El rastreador respeta el archivo robots.txt y guarda cada página que visita en el índice.",
+			expected: "spanish",
+		},
+		{
+			name:     "Too short to decide",
+			text:     "hola",
+			expected: "",
+		},
+	}
+
+	detector := NewDetector("english", "spanish")
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := detector.Detect(tc.text); got != tc.expected {
+				t.Errorf("Expected language %q, but got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestDetector_DetectSupportedLanguages(t *testing.T) {
+	sentences := map[string]string{
+		"english":   "The crawler respects robots.txt and stores every page it visits in the index.",
+		"french":    "Le robot respecte le fichier robots.txt et enregistre chaque page qu'il visite dans l'index.",
+		"hungarian": "A robot betartja a robots.txt fájlt, és minden meglátogatott oldalt eltárol az indexben.",
+		"norwegian": "Søkeroboten følger robots.txt og lagrer hver side den besøker i indeksen.",
+		"russian":   "Поисковый робот соблюдает файл robots.txt и сохраняет каждую посещенную страницу в индексе.",
+		"spanish":   "El rastreador respeta el archivo robots.txt y guarda cada página que visita en el índice.",
+		"swedish":   "Sökroboten följer robots.txt och sparar varje sida som den besöker i indexet.",
+	}
+
+	detector := NewDetector(SupportedLanguages...)
+	if got := detector.Languages(); !reflect.DeepEqual(got, SupportedLanguages) {
+		t.Fatalf("Expected a profile for every supported language %v, got %v", SupportedLanguages, got)
+	}
+	for lang, text := range sentences {
+		if got := detector.Detect(text); got != lang {
+			t.Errorf("Expected %q for %q, but got %q", lang, text, got)
+		}
+	}
+}
+
+func TestMultiAnalyzer_AnalyzeQuery(t *testing.T) {
+	multi := NewMultiAnalyzer(NewEnglishAnalyzer(), NewDetector(), NewSpanishAnalyzer())
+
+	if got := multi.Detect("Los niños jugaban cerca del río mientras sus padres hablaban."); got != "spanish" {
+		t.Errorf("Expected spanish, but got %q", got)
+	}
+
+	english := multi.AnalyzeQuery("running", "english")
+	if !reflect.DeepEqual(english, []string{"run"}) {
+		t.Errorf("Expected [run], but got %v", english)
+	}
+
+	all := multi.AnalyzeQuery("corriendo", "")
+	if !reflect.DeepEqual(all, []string{"corriendo", "corr"}) {
+		t.Errorf("Expected terms from every language, but got %v", all)
+	}
+}
+
+func TestMultiAnalyzer_AnalyzeFieldPhrase(t *testing.T) {
+	multi := NewMultiAnalyzer(NewEnglishAnalyzer(), nil, NewSpanishAnalyzer())
+
+	english := multi.AnalyzeFieldPhrase("body", "state of the art", "english")
+	expected := [][]Token{{{Term: "state", Position: 0, Start: 0, End: 5}, {Term: "art", Position: 3, Start: 13, End: 16}}}
+	if !reflect.DeepEqual(english, expected) {
+		t.Errorf("Expected stopwords to leave a gap, got %v", english)
+	}
+
+	if all := multi.AnalyzeFieldPhrase("body", "running shoes", ""); len(all) != 2 {
+		t.Errorf("Expected one analysis per language, got %v", all)
+	}
+	if same := multi.AnalyzeFieldPhrase("body", "404", ""); len(same) != 1 {
+		t.Errorf("Expected identical analyses once, got %v", same)
+	}
+}
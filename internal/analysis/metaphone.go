@@ -0,0 +1,531 @@
+package analysis
+
+import (
+	"strings"
+)
+
+// metaphoneLength is the length of Double Metaphone codes.
+const metaphoneLength = 4
+
+// DoubleMetaphone returns the primary and alternate Double Metaphone codes of
+// a word (Lawrence Philips, 2000). The codes describe how the word sounds in
+// English, accounting for names of Germanic, Slavic, Romance and other
+// origins: "Mohammed" and "Muhammad" both encode to "MHMT", "Smith" and
+// "Schmidt" share the alternate code "XMT". The alternate code equals the
+// primary one when the word has a single likely pronunciation. Accents are
+// folded first; a word without letters has empty codes.
+func DoubleMetaphone(word string) (primary, alternate string) {
+	value := strings.ToUpper(foldASCII(word))
+	if value == "" {
+		return "", ""
+	}
+	m := &metaphone{value: value, slavoGermanic: isSlavoGermanic(value)}
+	m.encode()
+	return m.primary.String(), m.alternate.String()
+}
+
+type metaphone struct {
+	value              string
+	slavoGermanic      bool
+	primary, alternate strings.Builder
+}
+
+func isSlavoGermanic(value string) bool {
+	return strings.Contains(value, "W") || strings.Contains(value, "K") ||
+		strings.Contains(value, "CZ") || strings.Contains(value, "WITZ")
+}
+
+// at returns the byte at index, or 0 outside the value.
+func (m *metaphone) at(index int) byte {
+	if index < 0 || index >= len(m.value) {
+		return 0
+	}
+	return m.value[index]
+}
+
+// has reports whether the value has one of the strings at index.
+func (m *metaphone) has(index, length int, options ...string) bool {
+	if index < 0 || index+length > len(m.value) {
+		return false
+	}
+	sub := m.value[index : index+length]
+	for _, option := range options {
+		if sub == option {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *metaphone) isVowel(index int) bool {
+	return strings.IndexByte("AEIOUY", m.at(index)) >= 0
+}
+
+func (m *metaphone) done() bool {
+	return m.primary.Len() >= metaphoneLength && m.alternate.Len() >= metaphoneLength
+}
+
+// add appends to both codes.
+func (m *metaphone) add(code string) {
+	m.addBoth(code, code)
+}
+
+// addBoth appends different codes to the primary and the alternate code.
+func (m *metaphone) addBoth(primary, alternate string) {
+	m.addPrimary(primary)
+	m.addAlternate(alternate)
+}
+
+func (m *metaphone) addPrimary(code string) {
+	if room := metaphoneLength - m.primary.Len(); room > 0 {
+		m.primary.WriteString(code[:min(room, len(code))])
+	}
+}
+
+func (m *metaphone) addAlternate(code string) {
+	if room := metaphoneLength - m.alternate.Len(); room > 0 {
+		m.alternate.WriteString(code[:min(room, len(code))])
+	}
+}
+
+// skip returns index+2 when the next letter repeats the current one, for
+// letters whose double sounds the same.
+func (m *metaphone) skip(index int) int {
+	if m.at(index+1) == m.at(index) {
+		return index + 2
+	}
+	return index + 1
+}
+
+func (m *metaphone) encode() {
+	index := 0
+	if m.has(0, 2, "GN", "KN", "PN", "WR", "PS") {
+		index = 1 // Silent first letter
+	}
+	if m.at(0) == 'X' {
+		m.add("S") // "Xavier"
+		index = 1
+	}
+
+	for !m.done() && index < len(m.value) {
+		switch c := m.at(index); c {
+		case 'A', 'E', 'I', 'O', 'U', 'Y':
+			if index == 0 {
+				m.add("A")
+			}
+			index++
+		case 'B':
+			m.add("P")
+			index = m.skip(index)
+		case 'C':
+			index = m.encodeC(index)
+		case 'D':
+			index = m.encodeD(index)
+		case 'F', 'K', 'N', 'Q':
+			if c == 'Q' {
+				c = 'K'
+			}
+			m.add(string(c))
+			index = m.skip(index)
+		case 'G':
+			index = m.encodeG(index)
+		case 'H':
+			if (index == 0 || m.isVowel(index-1)) && m.isVowel(index+1) {
+				m.add("H")
+				index += 2
+			} else {
+				index++
+			}
+		case 'J':
+			index = m.encodeJ(index)
+		case 'L':
+			index = m.encodeL(index)
+		case 'M':
+			m.add("M")
+			if m.at(index+1) == 'M' || (m.has(index-1, 3, "UMB") && (index+1 == len(m.value)-1 || m.has(index+2, 2, "ER"))) {
+				index += 2 // "dumb", "thumb"
+			} else {
+				index++
+			}
+		case 'P':
+			if m.at(index+1) == 'H' {
+				m.add("F")
+				index += 2
+			} else {
+				m.add("P")
+				if m.has(index+1, 1, "P", "B") {
+					index += 2
+				} else {
+					index++
+				}
+			}
+		case 'R':
+			index = m.encodeR(index)
+		case 'S':
+			index = m.encodeS(index)
+		case 'T':
+			index = m.encodeT(index)
+		case 'V':
+			m.add("F")
+			index = m.skip(index)
+		case 'W':
+			index = m.encodeW(index)
+		case 'X':
+			index = m.encodeX(index)
+		case 'Z':
+			index = m.encodeZ(index)
+		default:
+			index++
+		}
+	}
+}
+
+func (m *metaphone) encodeC(index int) int {
+	switch {
+	case m.isGermanicCH(index):
+		m.add("K") // "bacher", "macher"
+		return index + 2
+	case index == 0 && m.has(index, 6, "CAESAR"):
+		m.add("S")
+		return index + 2
+	case m.has(index, 2, "CH"):
+		return m.encodeCH(index)
+	case m.has(index, 2, "CZ") && !m.has(index-2, 4, "WICZ"):
+		m.addBoth("S", "X") // "Czerny"
+		return index + 2
+	case m.has(index+1, 3, "CIA"):
+		m.add("X") // "focaccia"
+		return index + 3
+	case m.has(index, 2, "CC") && !(index == 1 && m.at(0) == 'M'):
+		// Double "cc", but not "McClelland"
+		if m.has(index+2, 1, "I", "E", "H") && !m.has(index+2, 2, "HU") {
+			if (index == 1 && m.at(index-1) == 'A') || m.has(index-1, 5, "UCCEE", "UCCES") {
+				m.add("KS") // "accident", "accede", "succeed"
+			} else {
+				m.add("X") // "bacci", "bertucci"
+			}
+			return index + 3
+		}
+		m.add("K") // Pierce's rule
+		return index + 2
+	case m.has(index, 2, "CK", "CG", "CQ"):
+		m.add("K")
+		return index + 2
+	case m.has(index, 2, "CI", "CE", "CY"):
+		if m.has(index, 3, "CIO", "CIE", "CIA") {
+			m.addBoth("S", "X") // Italian
+		} else {
+			m.add("S")
+		}
+		return index + 2
+	}
+	m.add("K")
+	switch {
+	case m.has(index+1, 2, " C", " Q", " G"):
+		return index + 3 // "Mac Caffrey", "Mac Gregor"
+	case m.has(index+1, 1, "C", "K", "Q") && !m.has(index+1, 2, "CE", "CI"):
+		return index + 2
+	}
+	return index + 1
+}
+
+// isGermanicCH reports whether the "ch" at index sounds like "k", as in
+// "Bacher" and "Chianti".
+func (m *metaphone) isGermanicCH(index int) bool {
+	if m.has(index, 4, "CHIA") {
+		return true
+	}
+	if index <= 1 || m.isVowel(index-2) || !m.has(index-1, 3, "ACH") {
+		return false
+	}
+	c := m.at(index + 2)
+	return (c != 'I' && c != 'E') || m.has(index-2, 6, "BACHER", "MACHER")
+}
+
+func (m *metaphone) encodeCH(index int) int {
+	switch {
+	case index > 0 && m.has(index, 4, "CHAE"):
+		m.addBoth("K", "X") // "Michael"
+	case index == 0 && (m.has(index+1, 5, "HARAC", "HARIS") || m.has(index+1, 3, "HOR", "HYM", "HIA", "HEM")) && !m.has(0, 5, "CHORE"):
+		m.add("K") // Greek roots: "chemistry", "chorus"
+	case m.has(0, 4, "VAN ", "VON ") || m.has(0, 3, "SCH") ||
+		m.has(index-2, 6, "ORCHES", "ARCHIT", "ORCHID") ||
+		m.has(index+2, 1, "T", "S") ||
+		((m.has(index-1, 1, "A", "O", "U", "E") || index == 0) &&
+			(m.has(index+2, 1, "L", "R", "N", "M", "B", "H", "F", "V", "W", " ") || index+1 == len(m.value)-1)):
+		m.add("K") // Germanic or Greek "kh" sound
+	case index > 0:
+		if m.has(0, 2, "MC") {
+			m.add("K")
+		} else {
+			m.addBoth("X", "K")
+		}
+	default:
+		m.add("X")
+	}
+	return index + 2
+}
+
+func (m *metaphone) encodeD(index int) int {
+	switch {
+	case m.has(index, 2, "DG"):
+		if m.has(index+2, 1, "I", "E", "Y") {
+			m.add("J") // "edge"
+			return index + 3
+		}
+		m.add("TK") // "Edgar"
+		return index + 2
+	case m.has(index, 2, "DT", "DD"):
+		m.add("T")
+		return index + 2
+	}
+	m.add("T")
+	return index + 1
+}
+
+func (m *metaphone) encodeG(index int) int {
+	next := m.at(index + 1)
+	switch {
+	case next == 'H':
+		return m.encodeGH(index)
+	case next == 'N':
+		switch {
+		case index == 1 && m.isVowel(0) && !m.slavoGermanic:
+			m.addBoth("KN", "N")
+		case !m.has(index+2, 2, "EY") && next != 'Y' && !m.slavoGermanic:
+			m.addBoth("N", "KN")
+		default:
+			m.add("KN")
+		}
+		return index + 2
+	case m.has(index+1, 2, "LI") && !m.slavoGermanic:
+		m.addBoth("KL", "L") // "tagliaro"
+		return index + 2
+	case index == 0 && (next == 'Y' || m.has(index+1, 2, "ES", "EP", "EB", "EL", "EY", "IB", "IL", "IN", "IE", "EI", "ER")):
+		m.addBoth("K", "J") // -ges-, -gep-, -gel-, -gie- at the beginning
+		return index + 2
+	case (m.has(index+1, 2, "ER") || next == 'Y') &&
+		!m.has(0, 6, "DANGER", "RANGER", "MANGER") &&
+		!m.has(index-1, 1, "E", "I") && !m.has(index-1, 3, "RGY", "OGY"):
+		m.addBoth("K", "J") // -ger-, -gy-
+		return index + 2
+	case m.has(index+1, 1, "E", "I", "Y") || m.has(index-1, 4, "AGGI", "OGGI"):
+		switch {
+		case m.has(0, 4, "VAN ", "VON ") || m.has(0, 3, "SCH") || m.has(index+1, 2, "ET"):
+			m.add("K") // Germanic
+		case m.has(index+1, 3, "IER"):
+			m.add("J")
+		default:
+			m.addBoth("J", "K")
+		}
+		return index + 2
+	case next == 'G':
+		m.add("K")
+		return index + 2
+	}
+	m.add("K")
+	return index + 1
+}
+
+func (m *metaphone) encodeGH(index int) int {
+	switch {
+	case index > 0 && !m.isVowel(index-1):
+		m.add("K")
+	case index == 0:
+		if m.at(index+2) == 'I' {
+			m.add("J") // "Ghislane"
+		} else {
+			m.add("K") // "Ghana"
+		}
+	case (index > 1 && m.has(index-2, 1, "B", "H", "D")) ||
+		(index > 2 && m.has(index-3, 1, "B", "H", "D")) ||
+		(index > 3 && m.has(index-4, 1, "B", "H")):
+		// Parker's rule: silent, as in "Hugh" and "bough"
+	case index > 2 && m.at(index-1) == 'U' && m.has(index-3, 1, "C", "G", "L", "R", "T"):
+		m.add("F") // "laugh", "McLaughlin", "cough", "rough"
+	case m.at(index-1) != 'I':
+		m.add("K")
+	}
+	return index + 2
+}
+
+func (m *metaphone) encodeJ(index int) int {
+	if m.has(index, 4, "JOSE") || m.has(0, 4, "SAN ") {
+		// Spanish: "Jose", "San Jacinto"
+		if (index == 0 && m.at(index+4) == ' ') || len(m.value) == 4 || m.has(0, 4, "SAN ") {
+			m.add("H")
+		} else {
+			m.addBoth("J", "H")
+		}
+		return index + 1
+	}
+	switch {
+	case index == 0:
+		m.addBoth("J", "A") // "Jankelowicz"
+	case m.isVowel(index-1) && !m.slavoGermanic && (m.at(index+1) == 'A' || m.at(index+1) == 'O'):
+		m.addBoth("J", "H") // Spanish pronunciation, "bajador"
+	case index == len(m.value)-1:
+		m.addPrimary("J")
+	case !m.has(index+1, 1, "L", "T", "K", "S", "N", "M", "B", "Z") && !m.has(index-1, 1, "S", "K", "L"):
+		m.add("J")
+	}
+	return m.skip(index)
+}
+
+func (m *metaphone) encodeL(index int) int {
+	if m.at(index+1) != 'L' {
+		m.add("L")
+		return index + 1
+	}
+	n := len(m.value)
+	spanish := (index == n-3 && m.has(index-1, 4, "ILLO", "ILLA", "ALLE")) ||
+		((m.has(n-2, 2, "AS", "OS") || m.has(n-1, 1, "A", "O")) && m.has(index-1, 4, "ALLE"))
+	if spanish {
+		m.addPrimary("L") // "Cabrillo", "Gallegos"
+	} else {
+		m.add("L")
+	}
+	return index + 2
+}
+
+func (m *metaphone) encodeR(index int) int {
+	if index == len(m.value)-1 && !m.slavoGermanic && m.has(index-2, 2, "IE") && !m.has(index-4, 2, "ME", "MA") {
+		m.addAlternate("R") // French: "Rogier"
+	} else {
+		m.add("R")
+	}
+	return m.skip(index)
+}
+
+func (m *metaphone) encodeS(index int) int {
+	switch {
+	case m.has(index-1, 3, "ISL", "YSL"):
+		return index + 1 // "island", "isle", "carlisle"
+	case index == 0 && m.has(index, 5, "SUGAR"):
+		m.addBoth("X", "S")
+		return index + 1
+	case m.has(index, 2, "SH"):
+		if m.has(index+1, 4, "HEIM", "HOEK", "HOLM", "HOLZ") {
+			m.add("S") // Germanic
+		} else {
+			m.add("X")
+		}
+		return index + 2
+	case m.has(index, 3, "SIO", "SIA") || m.has(index, 4, "SIAN"):
+		if m.slavoGermanic {
+			m.add("S")
+		} else {
+			m.addBoth("S", "X") // Italian and Armenian
+		}
+		return index + 3
+	case (index == 0 && m.has(index+1, 1, "M", "N", "L", "W")) || m.has(index+1, 1, "Z"):
+		// "Smith" matches "Schmidt", "Snider" matches "Schneider"
+		m.addBoth("S", "X")
+		if m.has(index+1, 1, "Z") {
+			return index + 2
+		}
+		return index + 1
+	case m.has(index, 2, "SC"):
+		return m.encodeSC(index)
+	}
+	if index == len(m.value)-1 && m.has(index-2, 2, "AI", "OI") {
+		m.addAlternate("S") // French: "Resnais", "Artois"
+	} else {
+		m.add("S")
+	}
+	if m.has(index+1, 1, "S", "Z") {
+		return index + 2
+	}
+	return index + 1
+}
+
+func (m *metaphone) encodeSC(index int) int {
+	switch {
+	case m.at(index+2) == 'H':
+		// Schlesinger's rule
+		switch {
+		case m.has(index+3, 2, "ER", "EN"):
+			m.addBoth("X", "SK") // "Schermerhorn", "Schenker"
+		case m.has(index+3, 2, "OO", "UY", "ED", "EM"):
+			m.add("SK") // Dutch: "school", "schooner"
+		case index == 0 && !m.isVowel(3) && m.at(3) != 'W':
+			m.addBoth("X", "S")
+		default:
+			m.add("X")
+		}
+	case m.has(index+2, 1, "I", "E", "Y"):
+		m.add("S")
+	default:
+		m.add("SK")
+	}
+	return index + 3
+}
+
+func (m *metaphone) encodeT(index int) int {
+	switch {
+	case m.has(index, 4, "TION"), m.has(index, 3, "TIA", "TCH"):
+		m.add("X")
+		return index + 3
+	case m.has(index, 2, "TH"), m.has(index, 3, "TTH"):
+		if m.has(index+2, 2, "OM", "AM") || m.has(0, 4, "VAN ", "VON ") || m.has(0, 3, "SCH") {
+			m.add("T") // "Thomas", "Thames", Germanic
+		} else {
+			m.addBoth("0", "T")
+		}
+		return index + 2
+	}
+	m.add("T")
+	if m.has(index+1, 1, "T", "D") {
+		return index + 2
+	}
+	return index + 1
+}
+
+func (m *metaphone) encodeW(index int) int {
+	switch {
+	case m.has(index, 2, "WR"):
+		m.add("R")
+		return index + 2
+	case index == 0 && (m.isVowel(index+1) || m.has(index, 2, "WH")):
+		if m.isVowel(index + 1) {
+			m.addBoth("A", "F") // "Wasserman" matches "Vasserman"
+		} else {
+			m.add("A") // "Uomo" matches "Womo"
+		}
+	case (index == len(m.value)-1 && m.isVowel(index-1)) ||
+		m.has(index-1, 5, "EWSKI", "EWSKY", "OWSKI", "OWSKY") || m.has(0, 3, "SCH"):
+		m.addAlternate("F") // "Arnow" matches "Arnoff"
+	case m.has(index, 4, "WICZ", "WITZ"):
+		m.addBoth("TS", "FX") // Polish: "Filipowicz"
+		return index + 4
+	}
+	return index + 1
+}
+
+func (m *metaphone) encodeX(index int) int {
+	if index == 0 {
+		m.add("S")
+		return index + 1
+	}
+	french := index == len(m.value)-1 && (m.has(index-3, 3, "IAU", "EAU") || m.has(index-2, 2, "AU", "OU"))
+	if !french {
+		m.add("KS") // Not "Breaux"
+	}
+	if m.has(index+1, 1, "C", "X") {
+		return index + 2
+	}
+	return index + 1
+}
+
+func (m *metaphone) encodeZ(index int) int {
+	if m.at(index+1) == 'H' {
+		m.add("J") // Pinyin: "Zhao"
+		return index + 2
+	}
+	if m.has(index+1, 2, "ZO", "ZI", "ZA") || (m.slavoGermanic && index > 0 && m.at(index-1) != 'T') {
+		m.addBoth("S", "TS")
+	} else {
+		m.add("S")
+	}
+	return m.skip(index)
+}
@@ -0,0 +1,322 @@
+package analysis
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// AutoLanguage is the ANALYZER_LANGUAGE value that enables language detection.
+const AutoLanguage = "auto"
+
+// SupportedLanguages lists the languages with a built-in analyzer: every
+// language with a Snowball stemmer.
+var SupportedLanguages = []string{"english", "french", "hungarian", "norwegian", "russian", "spanish", "swedish"}
+
+// MultiAnalyzer routes text to a language-specific Analyzer. Documents are
+// tagged with a detected language at index time, and queries can be analyzed
+// for one language or for all of them at once.
+//
+// Besides the document analyzers, named analyzers can be registered and
+// assigned to fields (every document's "title" analyzed the same way) or to
+// sources (see UseForDocuments).
+type MultiAnalyzer struct {
+	fallback  *Analyzer
+	analyzers map[string]*Analyzer         // Document analyzers, used for unassigned fields
+	named     map[string]*Analyzer         // Every analyzer, by name
+	fields    map[string]string            // Field name to analyzer name
+	languages map[string]map[string]string // Field name to analyzer name by document language
+	search    map[string]string            // Field name to query analyzer name, when it differs
+	detector  *Detector
+	synonyms  atomic.Pointer[querySynonyms]
+}
+
+// querySynonyms are the synonyms applied to every query, compiled for each
+// analyzer the first time it analyzes a query.
+type querySynonyms struct {
+	file    string
+	set     *SynonymSet
+	mu      sync.Mutex
+	filters map[*Analyzer]SynonymFilter
+}
+
+func (q *querySynonyms) filter(a *Analyzer) SynonymFilter {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	f, ok := q.filters[a]
+	if !ok {
+		f = q.set.Filter(a)
+		q.filters[a] = f
+	}
+	return f
+}
+
+// NewMultiAnalyzer creates a MultiAnalyzer. The fallback analyzer is used when
+// the detector is nil, cannot decide, or detects a language with no analyzer.
+func NewMultiAnalyzer(fallback *Analyzer, detector *Detector, analyzers ...*Analyzer) *MultiAnalyzer {
+	m := &MultiAnalyzer{
+		fallback:  fallback,
+		analyzers: map[string]*Analyzer{fallback.Name(): fallback},
+		named:     map[string]*Analyzer{fallback.Name(): fallback},
+		fields:    make(map[string]string),
+		languages: make(map[string]map[string]string),
+		search:    make(map[string]string),
+		detector:  detector,
+	}
+	for _, a := range analyzers {
+		m.analyzers[a.Name()] = a
+		m.named[a.Name()] = a
+	}
+	return m
+}
+
+// Register adds a named analyzer that fields and sources can refer to,
+// replacing any analyzer with the same name.
+func (m *MultiAnalyzer) Register(a *Analyzer) {
+	m.named[a.Name()] = a
+	if _, ok := m.analyzers[a.Name()]; ok {
+		m.analyzers[a.Name()] = a
+	}
+	if m.fallback.Name() == a.Name() {
+		m.fallback = a
+	}
+}
+
+// UseForDocuments makes a registered analyzer one of the document analyzers,
+// so queries on unassigned fields are also analyzed with it. Sources that
+// index with a custom analyzer need this for their documents to be found.
+func (m *MultiAnalyzer) UseForDocuments(name string) error {
+	a, ok := m.named[name]
+	if !ok {
+		return fmt.Errorf("unknown analyzer %q", name)
+	}
+	m.analyzers[name] = a
+	return nil
+}
+
+// SetFieldAnalyzer assigns a registered analyzer to a field, for documents
+// and queries alike.
+func (m *MultiAnalyzer) SetFieldAnalyzer(field, name string) error {
+	if _, ok := m.named[name]; !ok {
+		return fmt.Errorf("unknown analyzer %q for field %s", name, field)
+	}
+	m.fields[field] = name
+	return nil
+}
+
+// SetFieldLanguageAnalyzer assigns a registered analyzer to a field for the
+// documents and queries of one language, overriding the field's analyzer: the
+// phonetic field encodes Spanish text with Spanish rules.
+func (m *MultiAnalyzer) SetFieldLanguageAnalyzer(field, language, name string) error {
+	if _, ok := m.named[name]; !ok {
+		return fmt.Errorf("unknown analyzer %q for field %s in %s", name, field, language)
+	}
+	if m.languages[field] == nil {
+		m.languages[field] = make(map[string]string)
+	}
+	m.languages[field][language] = name
+	return nil
+}
+
+// SetFieldSearchAnalyzer assigns a registered analyzer to the queries on a
+// field, for fields whose documents are analyzed differently: a field indexed
+// with edge n-grams is searched with whole query terms.
+func (m *MultiAnalyzer) SetFieldSearchAnalyzer(field, name string) error {
+	if _, ok := m.named[name]; !ok {
+		return fmt.Errorf("unknown search analyzer %q for field %s", name, field)
+	}
+	m.search[field] = name
+	return nil
+}
+
+// Analyzer returns the registered analyzer with the given name.
+func (m *MultiAnalyzer) Analyzer(name string) (*Analyzer, bool) {
+	a, ok := m.named[name]
+	return a, ok
+}
+
+// FieldAnalyzer returns the name of the analyzer assigned to field, or "".
+func (m *MultiAnalyzer) FieldAnalyzer(field string) string {
+	return m.fields[field]
+}
+
+// Definitions returns the definition of every registered analyzer by name.
+func (m *MultiAnalyzer) Definitions() map[string]string {
+	defs := make(map[string]string, len(m.named))
+	for name, a := range m.named {
+		defs[name] = a.Definition()
+	}
+	return defs
+}
+
+// Detect returns the language a document should be analyzed with.
+func (m *MultiAnalyzer) Detect(text string) string {
+	if m.detector == nil {
+		return m.fallback.Language()
+	}
+	lang := m.detector.Detect(text)
+	if _, ok := m.analyzers[lang]; !ok {
+		return m.fallback.Language()
+	}
+	return lang
+}
+
+// For returns the analyzer for a language or analyzer name, or the fallback
+// analyzer when the name is unknown.
+func (m *MultiAnalyzer) For(language string) *Analyzer {
+	if a, ok := m.named[language]; ok {
+		return a
+	}
+	if a, ok := m.named[strings.ToLower(language)]; ok {
+		return a
+	}
+	return m.fallback
+}
+
+// ForField returns the analyzer assigned to field for the language, the one
+// assigned to field, or For(language) when the field has none.
+func (m *MultiAnalyzer) ForField(field, language string) *Analyzer {
+	if name, ok := m.languages[field][language]; ok {
+		return m.named[name]
+	}
+	if name, ok := m.fields[field]; ok {
+		return m.named[name]
+	}
+	return m.For(language)
+}
+
+// Languages returns the names of the document analyzers, sorted.
+func (m *MultiAnalyzer) Languages() []string {
+	langs := make([]string, 0, len(m.analyzers))
+	for lang := range m.analyzers {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// LoadQuerySynonyms reads a synonym file applied to every query on top of
+// the analyzers, so new synonyms take effect without re-indexing. It returns
+// the number of rules loaded.
+func (m *MultiAnalyzer) LoadQuerySynonyms(filename string) (int, error) {
+	set, err := LoadSynonyms(filename)
+	if err != nil {
+		return 0, err
+	}
+	m.synonyms.Store(&querySynonyms{file: filename, set: set, filters: make(map[*Analyzer]SynonymFilter)})
+	return set.Len(), nil
+}
+
+// ReloadQuerySynonyms reads the query synonym file again. On error the
+// synonyms loaded before stay in use.
+func (m *MultiAnalyzer) ReloadQuerySynonyms() (int, error) {
+	current := m.synonyms.Load()
+	if current == nil {
+		return 0, errors.New("no query synonym file configured")
+	}
+	return m.LoadQuerySynonyms(current.file)
+}
+
+// queryTokens analyzes a query with a and expands the query synonyms.
+func (m *MultiAnalyzer) queryTokens(a *Analyzer, query string) []Token {
+	q := m.synonyms.Load()
+	if q == nil {
+		return a.Tokens(query)
+	}
+	return q.filter(a).Filter(a.Tokens(query))
+}
+
+// AnalyzeQuery analyzes a query for the given language. With an empty language
+// the query is analyzed by every registered analyzer and the distinct terms are
+// returned in order of first appearance.
+func (m *MultiAnalyzer) AnalyzeQuery(query, language string) []string {
+	return m.distinctTerms(m.queryAnalyzers("", language), query)
+}
+
+// AnalyzeFieldQuery analyzes a query for one field: with the search analyzer
+// or the analyzer assigned to the field, or like AnalyzeQuery when it has
+// none. A field with language analyzers is searched with the one of the
+// language, or with all of them and the field's analyzer when the language is
+// empty, and the distinct terms are returned in order of first appearance.
+func (m *MultiAnalyzer) AnalyzeFieldQuery(field, query, language string) []string {
+	return m.distinctTerms(m.queryAnalyzers(field, language), query)
+}
+
+// AnalyzeFieldPhrase analyzes a phrase for one field with the analyzers
+// AnalyzeFieldQuery uses, and returns the tokens of each distinct analysis.
+// Token positions keep the gaps left by removed stopwords, so the phrase can
+// be matched against the positions of the indexed terms.
+func (m *MultiAnalyzer) AnalyzeFieldPhrase(field, phrase, language string) [][]Token {
+	var variants [][]Token
+	for _, a := range m.queryAnalyzers(field, language) {
+		tokens := m.queryTokens(a, phrase)
+		if len(tokens) == 0 || slices.ContainsFunc(variants, func(v []Token) bool { return slices.Equal(v, tokens) }) {
+			continue
+		}
+		variants = append(variants, tokens)
+	}
+	return variants
+}
+
+// queryAnalyzers returns the analyzers of the queries on a field in a
+// language, see AnalyzeFieldQuery. An empty field selects the document
+// analyzers.
+func (m *MultiAnalyzer) queryAnalyzers(field, language string) []*Analyzer {
+	if name, ok := m.search[field]; ok {
+		return []*Analyzer{m.named[name]}
+	}
+	if byLanguage, ok := m.languages[field]; ok {
+		if language != "" {
+			return []*Analyzer{m.ForField(field, language)}
+		}
+		analyzers := make([]*Analyzer, 0, len(byLanguage)+1)
+		if name, ok := m.fields[field]; ok {
+			analyzers = append(analyzers, m.named[name])
+		}
+		for _, lang := range sortedKeys(byLanguage) {
+			analyzers = append(analyzers, m.named[byLanguage[lang]])
+		}
+		return analyzers
+	}
+	if name, ok := m.fields[field]; ok {
+		return []*Analyzer{m.named[name]}
+	}
+	if language != "" {
+		return []*Analyzer{m.For(language)}
+	}
+	analyzers := make([]*Analyzer, 0, len(m.analyzers))
+	for _, lang := range m.Languages() {
+		analyzers = append(analyzers, m.analyzers[lang])
+	}
+	return analyzers
+}
+
+// distinctTerms analyzes a query with every analyzer and returns the
+// distinct terms in order of first appearance.
+func (m *MultiAnalyzer) distinctTerms(analyzers []*Analyzer, query string) []string {
+	seen := make(map[string]struct{})
+	terms := []string{}
+	for _, a := range analyzers {
+		for _, token := range m.queryTokens(a, query) {
+			if _, ok := seen[token.Term]; ok {
+				continue
+			}
+			seen[token.Term] = struct{}{}
+			terms = append(terms, token.Term)
+		}
+	}
+	return terms
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,80 @@
+package analysis
+
+import "fmt"
+
+// Names of the analyzers of the phonetic field, see NewPhoneticAnalyzer.
+const (
+	PhoneticAnalyzer        = "phonetic"
+	PhoneticSpanishAnalyzer = "phonetic_spanish"
+)
+
+// Phonetic encoders of PhoneticFilter.
+const (
+	DoubleMetaphoneEncoder = "double_metaphone"
+	SpanishPhoneticEncoder = "spanish"
+)
+
+// PhoneticFilter replaces every token with the code of how it sounds, so
+// names spelled differently match: "Smith" and "Schmidt", "Jiménez" and
+// "Giménez". With Double Metaphone the alternate code is added at the same
+// position when it differs from the primary one. Tokens without a code, such
+// as numbers, are dropped.
+type PhoneticFilter struct {
+	Encoder string
+}
+
+// NewPhoneticFilter returns a phonetic filter for an encoder name:
+// "double_metaphone" (the default) or "spanish".
+func NewPhoneticFilter(encoder string) (PhoneticFilter, error) {
+	switch encoder {
+	case "", DoubleMetaphoneEncoder:
+		return PhoneticFilter{Encoder: DoubleMetaphoneEncoder}, nil
+	case SpanishPhoneticEncoder:
+		return PhoneticFilter{Encoder: SpanishPhoneticEncoder}, nil
+	}
+	return PhoneticFilter{}, fmt.Errorf("unknown phonetic encoder %q", encoder)
+}
+
+func (f PhoneticFilter) Filter(tokens []Token) []Token {
+	out := make([]Token, 0, len(tokens))
+	for _, token := range tokens {
+		var primary, alternate string
+		if f.Encoder == SpanishPhoneticEncoder {
+			primary = SpanishPhonetic(token.Term)
+		} else {
+			primary, alternate = DoubleMetaphone(token.Term)
+		}
+		if primary == "" {
+			continue
+		}
+		token.Term = primary
+		out = append(out, token)
+		if alternate != "" && alternate != primary {
+			token.Term = alternate
+			out = append(out, token)
+		}
+	}
+	return out
+}
+
+func (f PhoneticFilter) String() string { return "phonetic(" + f.Encoder + ")" }
+
+// NewPhoneticAnalyzer creates the analyzer of the phonetic field for a
+// language: Spanish text is encoded with SpanishPhonetic, and any other
+// language with Double Metaphone. Stopwords are removed first so they do not
+// match every word that sounds like them.
+func NewPhoneticAnalyzer(language string) *Analyzer {
+	nfkc, _ := NewNormalizeFilter("nfkc")
+	name, encoder, stopLanguage := PhoneticAnalyzer, DoubleMetaphoneEncoder, "english"
+	if language == "spanish" {
+		name, encoder, stopLanguage = PhoneticSpanishAnalyzer, SpanishPhoneticEncoder, "spanish"
+	}
+	words, _ := Stopwords(stopLanguage)
+	return NewPipeline(name, language, StandardTokenizer{},
+		nfkc,
+		LowercaseFilter{},
+		NewStopFilter(stopLanguage, words),
+		PhoneticFilter{Encoder: encoder},
+		UniqueFilter{},
+	)
+}
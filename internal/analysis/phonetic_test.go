@@ -0,0 +1,107 @@
+package analysis
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestDoubleMetaphone(t *testing.T) {
+	testCases := []struct {
+		word, primary, alternate string
+	}{
+		{"Smith", "SM0", "XMT"},
+		{"Schmidt", "XMT", "SMT"},
+		{"Mohammed", "MHMT", "MHMT"},
+		{"Muhammad", "MHMT", "MHMT"},
+		{"Thompson", "TMPS", "TMPS"},
+		{"Michael", "MKL", "MXL"},
+		{"Caesar", "SSR", "SSR"},
+		{"laugh", "LF", "LF"},
+		{"Filipowicz", "FLPT", "FLPF"},
+		{"Müller", "MLR", "MLR"},
+		{"", "", ""},
+	}
+	for _, tc := range testCases {
+		primary, alternate := DoubleMetaphone(tc.word)
+		if primary != tc.primary || alternate != tc.alternate {
+			t.Errorf("DoubleMetaphone(%q) = %q, %q; expected %q, %q", tc.word, primary, alternate, tc.primary, tc.alternate)
+		}
+	}
+}
+
+func TestSpanishPhonetic(t *testing.T) {
+	testCases := map[string][]string{
+		"BSKS":   {"Vásquez", "Vázquez", "Basques"},
+		"JMNS":   {"Jiménez", "Giménez", "Ximenes"},
+		"ARNNDS": {"Hernández", "Ernandes"},
+		"GNSLS":  {"González", "Gonzales"},
+		"YRNT":   {"Llorente", "Yorente"},
+		"NNS":    {"Núñez", "Nunez", "NUÑEZ"},
+		"XBS":    {"Chávez"},
+		"KS":     {"Queso", "Keso"},
+	}
+	for code, words := range testCases {
+		for _, word := range words {
+			if got := SpanishPhonetic(word); got != code {
+				t.Errorf("SpanishPhonetic(%q) = %q; expected %q", word, got, code)
+			}
+		}
+	}
+}
+
+func TestPhoneticFilter(t *testing.T) {
+	tokens := PhoneticFilter{Encoder: DoubleMetaphoneEncoder}.Filter([]Token{
+		{Term: "smith", Position: 0},
+		{Term: "404", Position: 1},
+		{Term: "mohammed", Position: 2},
+	})
+	expected := []Token{
+		{Term: "SM0", Position: 0},
+		{Term: "XMT", Position: 0},
+		{Term: "MHMT", Position: 2},
+	}
+	if !reflect.DeepEqual(tokens, expected) {
+		t.Errorf("Expected %v, got %v", expected, tokens)
+	}
+
+	if _, err := NewPhoneticFilter("soundex"); err == nil {
+		t.Error("Expected an error for an unknown encoder")
+	}
+}
+
+func TestPhoneticAnalyzers(t *testing.T) {
+	english := NewPhoneticAnalyzer("english")
+	if got := english.Analyze("the Smiths"); !reflect.DeepEqual(got, []string{"SM0S", "XMTS"}) {
+		t.Errorf("Expected stopwords removed and both codes kept, got %v", got)
+	}
+	if a, b := english.Analyze("Jon Smith"), english.Analyze("John Schmidt"); !slices.Contains(a, "XMT") || !slices.Contains(b, "XMT") {
+		t.Errorf("Expected the names to share a code, got %v and %v", a, b)
+	}
+
+	multi := NewMultiAnalyzer(NewEnglishAnalyzer(), nil, NewSpanishAnalyzer())
+	multi.Register(english)
+	multi.Register(NewPhoneticAnalyzer("spanish"))
+	if err := multi.SetFieldAnalyzer("phonetic", PhoneticAnalyzer); err != nil {
+		t.Fatal(err)
+	}
+	if err := multi.SetFieldLanguageAnalyzer("phonetic", "spanish", PhoneticSpanishAnalyzer); err != nil {
+		t.Fatal(err)
+	}
+	if err := multi.SetFieldLanguageAnalyzer("phonetic", "spanish", "missing"); err == nil {
+		t.Error("Expected an error for an unknown analyzer")
+	}
+
+	if name := multi.ForField("phonetic", "spanish").Name(); name != PhoneticSpanishAnalyzer {
+		t.Errorf("Expected Spanish documents to use %s, got %s", PhoneticSpanishAnalyzer, name)
+	}
+	if name := multi.ForField("phonetic", "english").Name(); name != PhoneticAnalyzer {
+		t.Errorf("Expected other documents to use %s, got %s", PhoneticAnalyzer, name)
+	}
+	if got := multi.AnalyzeFieldQuery("phonetic", "Giménez", "spanish"); !reflect.DeepEqual(got, []string{"JMNS"}) {
+		t.Errorf("Expected the Spanish code only, got %v", got)
+	}
+	if got := multi.AnalyzeFieldQuery("phonetic", "Giménez", ""); !reflect.DeepEqual(got, []string{"JMNS", "KMNS"}) {
+		t.Errorf("Expected the codes of every language, got %v", got)
+	}
+}
@@ -0,0 +1,226 @@
+package analysis
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Token is a term produced by a Tokenizer and rewritten by TokenFilters.
+// Position is the index of the word in the original text; filters that add
+// tokens (synonyms, word parts) reuse the position of the token they expand,
+// and filters that drop tokens leave a gap. Start and End are the byte
+// offsets of the original word in the text, which filters leave unchanged.
+type Token struct {
+	Term     string
+	Position int
+	Start    int
+	End      int
+}
+
+// Tokenizer splits text into tokens. String describes the tokenizer and its
+// settings, and is part of the definition recorded for every analyzer.
+type Tokenizer interface {
+	Tokenize(text string) []Token
+	String() string
+}
+
+// TokenFilter rewrites a token stream: it may change, drop or add tokens.
+type TokenFilter interface {
+	Filter(tokens []Token) []Token
+	String() string
+}
+
+// NewPipeline creates an analyzer that runs the tokenizer and then every
+// filter in order. The language is the natural language the analyzer is meant
+// for, or "" when it is language-neutral.
+func NewPipeline(name, language string, tokenizer Tokenizer, filters ...TokenFilter) *Analyzer {
+	return &Analyzer{
+		name:      name,
+		language:  language,
+		tokenizer: tokenizer,
+		filters:   filters,
+	}
+}
+
+// Name returns the name the analyzer is registered under.
+func (a *Analyzer) Name() string {
+	return a.name
+}
+
+// Tokens runs the pipeline over text and returns the resulting tokens.
+func (a *Analyzer) Tokens(text string) []Token {
+	return a.filter(a.tokenizer.Tokenize(text))
+}
+
+// filter runs every filter over the tokens of the tokenizer.
+func (a *Analyzer) filter(tokens []Token) []Token {
+	for _, filter := range a.filters {
+		if len(tokens) == 0 {
+			break
+		}
+		tokens = filter.Filter(tokens)
+	}
+	return tokens
+}
+
+// Analyze runs the pipeline over text and returns the terms, in order.
+func (a *Analyzer) Analyze(text string) []string {
+	tokens := a.Tokens(text)
+	terms := make([]string, len(tokens))
+	for i, token := range tokens {
+		terms[i] = token.Term
+	}
+	return terms
+}
+
+// Definition describes the tokenizer and filters of the analyzer, e.g.
+// "standard | lowercase | stop(english:175) | stemmer(english)". Documents built
+// with a different definition need to be re-indexed.
+func (a *Analyzer) Definition() string {
+	parts := make([]string, 0, len(a.filters)+1)
+	parts = append(parts, a.tokenizer.String())
+	for _, filter := range a.filters {
+		parts = append(parts, filter.String())
+	}
+	return strings.Join(parts, " | ")
+}
+
+// AnalyzerSpec declares an analyzer by the names of its tokenizer and filters,
+// as read from configuration.
+type AnalyzerSpec struct {
+	Tokenizer string
+	Filters   []FilterSpec
+}
+
+// FilterSpec declares a token filter. Only the settings used by Type apply:
+// Language for stop and stemmer, Words or WordsFile for stop, Min and Max for
+// length, ngram and edge_ngram, Max for truncate, Synonyms or SynonymsFile for synonym, Form for normalize, PreserveOriginal for
+// asciifolding and Encoder for phonetic.
+type FilterSpec struct {
+	Type             string
+	Language         string
+	Words            []string
+	WordsFile        string // Stopword file, see LoadStopwords
+	Min              int
+	Max              int
+	Synonyms         []string // Rules such as "db, database" or "k8s => kubernetes"
+	SynonymsFile     string   // Synonym file, see SynonymSet
+	Form             string   // nfc, nfd, nfkc or nfkd
+	PreserveOriginal bool
+	Encoder          string // double_metaphone or spanish
+}
+
+// Build creates a named analyzer from its spec.
+func Build(name string, spec AnalyzerSpec) (*Analyzer, error) {
+	tokenizer, err := NewTokenizer(spec.Tokenizer)
+	if err != nil {
+		return nil, fmt.Errorf("analyzer %s: %w", name, err)
+	}
+	filters := make([]TokenFilter, 0, len(spec.Filters))
+	language := ""
+	for _, fs := range spec.Filters {
+		filter, err := NewFilter(fs)
+		if err != nil {
+			return nil, fmt.Errorf("analyzer %s: %w", name, err)
+		}
+		if fs.Type == "stemmer" {
+			language = fs.Language
+		}
+		filters = append(filters, filter)
+	}
+	return NewPipeline(name, language, tokenizer, filters...), nil
+}
+
+// NewTokenizer returns the tokenizer with the given name. An empty name
+// selects the standard tokenizer.
+func NewTokenizer(name string) (Tokenizer, error) {
+	switch strings.ToLower(name) {
+	case "", "standard":
+		return StandardTokenizer{}, nil
+	case "letter":
+		return LetterTokenizer{}, nil
+	case "whitespace":
+		return WhitespaceTokenizer{}, nil
+	case "identifier":
+		return IdentifierTokenizer{}, nil
+	case "keyword":
+		return KeywordTokenizer{}, nil
+	case "url":
+		return URLTokenizer{}, nil
+	default:
+		return nil, fmt.Errorf("unknown tokenizer %q", name)
+	}
+}
+
+// NewFilter returns the token filter declared by spec.
+func NewFilter(spec FilterSpec) (TokenFilter, error) {
+	switch strings.ToLower(spec.Type) {
+	case "normalize":
+		return NewNormalizeFilter(spec.Form)
+	case "lowercase":
+		return LowercaseFilter{}, nil
+	case "asciifolding":
+		return ASCIIFoldingFilter{PreserveOriginal: spec.PreserveOriginal}, nil
+	case "stop":
+		if len(spec.Words) > 0 {
+			return NewStopFilter("custom", spec.Words), nil
+		}
+		if spec.WordsFile != "" {
+			words, err := LoadStopwords(spec.WordsFile)
+			if err != nil {
+				return nil, err
+			}
+			return NewStopFilter(path.Base(spec.WordsFile), words), nil
+		}
+		words, ok := Stopwords(spec.Language)
+		if !ok {
+			return nil, fmt.Errorf("no stopwords for language %q", spec.Language)
+		}
+		return NewStopFilter(strings.ToLower(spec.Language), words), nil
+	case "stemmer":
+		return NewStemmerFilter(spec.Language)
+	case "elision":
+		return ElisionFilter{}, nil
+	case "length":
+		if spec.Max > 0 && spec.Max < spec.Min {
+			return nil, fmt.Errorf("length filter: max %d is below min %d", spec.Max, spec.Min)
+		}
+		return LengthFilter{Min: spec.Min, Max: spec.Max}, nil
+	case "synonym":
+		rules := spec.Synonyms
+		if spec.SynonymsFile != "" {
+			lines, err := readSynonymLines(spec.SynonymsFile)
+			if err != nil {
+				return nil, err
+			}
+			rules = append(append([]string(nil), rules...), lines...)
+		}
+		return NewSynonymFilter(rules)
+	case "ngram":
+		if spec.Min < 1 || spec.Max < spec.Min {
+			return nil, fmt.Errorf("ngram filter: invalid sizes %d-%d", spec.Min, spec.Max)
+		}
+		return NGramFilter{Min: spec.Min, Max: spec.Max}, nil
+	case "edge_ngram":
+		if spec.Min < 1 || spec.Max < spec.Min {
+			return nil, fmt.Errorf("edge_ngram filter: invalid sizes %d-%d", spec.Min, spec.Max)
+		}
+		return EdgeNGramFilter{Min: spec.Min, Max: spec.Max}, nil
+	case "truncate":
+		if spec.Max < 1 {
+			return nil, fmt.Errorf("truncate filter: invalid length %d", spec.Max)
+		}
+		return TruncateFilter{Length: spec.Max}, nil
+	case "cjk_bigram":
+		return CJKBigramFilter{}, nil
+	case "word_delimiter":
+		return WordDelimiterFilter{}, nil
+	case "unique":
+		return UniqueFilter{}, nil
+	case "phonetic":
+		return NewPhoneticFilter(spec.Encoder)
+	default:
+		return nil, fmt.Errorf("unknown token filter %q", spec.Type)
+	}
+}
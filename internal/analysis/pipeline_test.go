@@ -0,0 +1,174 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBuild_Pipeline(t *testing.T) {
+	testCases := []struct {
+		name           string
+		spec           AnalyzerSpec
+		inputText      string
+		expectedTokens []string
+	}{
+		{
+			name: "Accent folding before stemming",
+			spec: AnalyzerSpec{Tokenizer: "whitespace", Filters: []FilterSpec{
+				{Type: "lowercase"},
+				{Type: "asciifolding"},
+				{Type: "stemmer", Language: "spanish"},
+			}},
+			inputText:      "Canción CANCIONES",
+			expectedTokens: []string{"cancion", "cancion"},
+		},
+		{
+			name: "Synonyms are expanded and replaced",
+			spec: AnalyzerSpec{Tokenizer: "whitespace", Filters: []FilterSpec{
+				{Type: "lowercase"},
+				{Type: "synonym", Synonyms: []string{"db, database", "k8s => kubernetes"}},
+			}},
+			inputText:      "DB on k8s",
+			expectedTokens: []string{"db", "database", "on", "kubernetes"},
+		},
+		{
+			name: "Length filter drops short and long tokens",
+			spec: AnalyzerSpec{Tokenizer: "whitespace", Filters: []FilterSpec{
+				{Type: "length", Min: 2, Max: 4},
+			}},
+			inputText:      "a go-to word processor",
+			expectedTokens: []string{"word"},
+		},
+		{
+			name: "N-grams keep tokens shorter than the minimum",
+			spec: AnalyzerSpec{Filters: []FilterSpec{
+				{Type: "ngram", Min: 2, Max: 3},
+			}},
+			inputText:      "abcd x",
+			expectedTokens: []string{"ab", "bc", "cd", "abc", "bcd", "x"},
+		},
+		{
+			name:           "Keyword tokenizer keeps the whole value",
+			spec:           AnalyzerSpec{Tokenizer: "keyword", Filters: []FilterSpec{{Type: "lowercase"}}},
+			inputText:      "  Release-2.0 ",
+			expectedTokens: []string{"release-2.0"},
+		},
+		{
+			name: "Custom stopwords",
+			spec: AnalyzerSpec{Filters: []FilterSpec{
+				{Type: "lowercase"},
+				{Type: "stop", Words: []string{"foo"}},
+			}},
+			inputText:      "Foo bar",
+			expectedTokens: []string{"bar"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			analyzer, err := Build("test", tc.spec)
+			if err != nil {
+				t.Fatalf("Build returned an error: %v", err)
+			}
+			tokens := analyzer.Analyze(tc.inputText)
+			if !reflect.DeepEqual(tokens, tc.expectedTokens) {
+				t.Errorf("Expected tokens %v, but got %v", tc.expectedTokens, tokens)
+			}
+		})
+	}
+}
+
+func TestBuild_InvalidSpec(t *testing.T) {
+	specs := []AnalyzerSpec{
+		{Tokenizer: "sentence"},
+		{Filters: []FilterSpec{{Type: "uppercase"}}},
+		{Filters: []FilterSpec{{Type: "stemmer", Language: "klingon"}}},
+		{Filters: []FilterSpec{{Type: "stop", Language: "klingon"}}},
+		{Filters: []FilterSpec{{Type: "ngram", Min: 3, Max: 2}}},
+		{Filters: []FilterSpec{{Type: "synonym", Synonyms: []string{"lonely"}}}},
+	}
+	for _, spec := range specs {
+		if _, err := Build("invalid", spec); err == nil {
+			t.Errorf("Expected an error for spec %+v", spec)
+		}
+	}
+}
+
+func TestAnalyzer_TokenPositions(t *testing.T) {
+	analyzer := NewEnglishAnalyzer()
+	tokens := analyzer.Tokens("the quick fox")
+	expected := []Token{{Term: "quick", Position: 1, Start: 4, End: 9}, {Term: "fox", Position: 2, Start: 10, End: 13}}
+	if !reflect.DeepEqual(tokens, expected) {
+		t.Errorf("Expected stopwords to leave a gap, got %v", tokens)
+	}
+}
+
+func TestAnalyzer_Definition(t *testing.T) {
+	got := NewEnglishAnalyzer().Definition()
+	if got != "standard | normalize(nfkc) | cjk_bigram | lowercase | stop(english:175) | asciifolding(preserve_original) | stemmer(english) | unique" {
+		t.Fatalf("Unexpected definition %q", got)
+	}
+
+	a, _ := Build("a", AnalyzerSpec{Filters: []FilterSpec{{Type: "synonym", Synonyms: []string{"a, b", "c, d"}}}})
+	b, _ := Build("b", AnalyzerSpec{Filters: []FilterSpec{{Type: "synonym", Synonyms: []string{"c, d", "a, b"}}}})
+	c, _ := Build("c", AnalyzerSpec{Filters: []FilterSpec{{Type: "synonym", Synonyms: []string{"a, b", "c, e"}}}})
+	if a.Definition() != b.Definition() {
+		t.Errorf("Expected rule order not to change the definition: %q != %q", a.Definition(), b.Definition())
+	}
+	if a.Definition() == c.Definition() {
+		t.Errorf("Expected different rules to change the definition, both are %q", a.Definition())
+	}
+}
+
+func TestMultiAnalyzer_FieldAnalyzer(t *testing.T) {
+	multi := NewMultiAnalyzer(NewEnglishAnalyzer(), nil, NewSpanishAnalyzer())
+	tags, err := Build("tags", AnalyzerSpec{Tokenizer: "keyword", Filters: []FilterSpec{{Type: "lowercase"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	multi.Register(tags)
+	if err := multi.SetFieldAnalyzer("tags", "tags"); err != nil {
+		t.Fatal(err)
+	}
+	if err := multi.SetFieldAnalyzer("title", "missing"); err == nil {
+		t.Error("Expected an error for an unknown analyzer")
+	}
+
+	if got := multi.AnalyzeFieldQuery("tags", "Running Shoes", ""); !reflect.DeepEqual(got, []string{"running shoes"}) {
+		t.Errorf("Expected the field analyzer to be used, got %v", got)
+	}
+	if got := multi.AnalyzeFieldQuery("body", "running", "english"); !reflect.DeepEqual(got, []string{"run"}) {
+		t.Errorf("Expected the language analyzer for unassigned fields, got %v", got)
+	}
+	if multi.ForField("tags", "spanish") != tags || multi.ForField("body", "spanish").Name() != "spanish" {
+		t.Error("ForField did not route fields to their analyzers")
+	}
+	if _, ok := multi.Definitions()["tags"]; !ok {
+		t.Error("Expected registered analyzers in the definitions")
+	}
+}
+
+func TestAnalyzer_Explain(t *testing.T) {
+	analyzer := NewEnglishAnalyzer()
+	stages := analyzer.Explain("The Running dogs")
+	if len(stages) != 8 || stages[0].Name != "standard" || stages[4].Name != "stop(english:175)" {
+		t.Fatalf("Expected the tokenizer and every filter, got %+v", stages)
+	}
+	terms := func(tokens []Token) []string {
+		var out []string
+		for _, token := range tokens {
+			out = append(out, token.Term)
+		}
+		return out
+	}
+	if got := terms(stages[3].Tokens); !reflect.DeepEqual(got, []string{"the", "running", "dogs"}) {
+		t.Errorf("Expected the lowercased tokens to be kept after later filters ran, got %v", got)
+	}
+	if got := terms(stages[4].Removed); !reflect.DeepEqual(got, []string{"the"}) {
+		t.Errorf("Expected the stopword to be reported as removed, got %v", got)
+	}
+	last := stages[len(stages)-1].Tokens
+	if !reflect.DeepEqual(last, analyzer.Tokens("The Running dogs")) {
+		t.Errorf("Expected the last stage to match Tokens, got %v", last)
+	}
+}
@@ -0,0 +1,37 @@
+package analysis
+
+// Names of the analyzers of the prefix field, see NewPrefixAnalyzers.
+const (
+	PrefixAnalyzer       = "prefix"
+	PrefixSearchAnalyzer = "prefix_search"
+)
+
+// Default prefix lengths of the prefix field.
+const (
+	DefaultPrefixMin = 2
+	DefaultPrefixMax = 15
+)
+
+// NewPrefixAnalyzers creates the analyzers of a field that answers prefix
+// queries. Documents are indexed with the edge n-grams of their words, from
+// min to max runes long, and queries are analyzed into whole words cut to
+// max runes, so "index" matches the "index" prefix of "indexer". Words are
+// normalized, lowercased and folded but not stemmed, so prefixes follow the
+// spelling of the text. Zero lengths select the defaults.
+func NewPrefixAnalyzers(min, max int) (index, search *Analyzer) {
+	if min <= 0 {
+		min = DefaultPrefixMin
+	}
+	if max <= 0 {
+		max = DefaultPrefixMax
+	}
+	if max < min {
+		max = min
+	}
+	nfkc, _ := NewNormalizeFilter("nfkc")
+	index = NewPipeline(PrefixAnalyzer, "", StandardTokenizer{},
+		nfkc, CJKBigramFilter{}, LowercaseFilter{}, ASCIIFoldingFilter{}, EdgeNGramFilter{Min: min, Max: max})
+	search = NewPipeline(PrefixSearchAnalyzer, "", StandardTokenizer{},
+		nfkc, CJKBigramFilter{}, LowercaseFilter{}, ASCIIFoldingFilter{}, TruncateFilter{Length: max})
+	return index, search
+}
@@ -0,0 +1,127 @@
+package analysis
+
+import (
+	"strings"
+)
+
+// spanishPhoneticLength is the maximum length of Spanish phonetic codes.
+const spanishPhoneticLength = 6
+
+// SpanishPhonetic returns a phonetic code for a Spanish word, following the
+// pronunciation of Latin American and most peninsular Spanish:
+//
+//   - "b" and "v" sound alike, and "h" is silent;
+//   - "s", "z" and "c" before "e" or "i" sound alike (seseo);
+//   - "ll" and "y" before a vowel sound alike (yeísmo);
+//   - "j", "g" before "e" or "i" and "x" at the start (the old spelling
+//     of "j") sound alike;
+//   - "qu", "k" and "c" elsewhere sound alike, as do "gu" before "e" or "i"
+//     and "g" elsewhere;
+//   - "ñ" encodes like "n", as it is often typed without its tilde, and
+//     "ch" keeps its own code.
+//
+// As in Metaphone, only a leading vowel is kept, and repeated codes are
+// collapsed, so "Vásquez", "Vázquez" and "Basques" all encode to "BSKS" and
+// "Jiménez", "Giménez" and "Ximenes" to "JMNS", and "Núñez" and "Nunez"
+// to "NNS". Codes are cut to six
+// characters.
+func SpanishPhonetic(word string) string {
+	w := []rune(foldASCII(strings.ToLower(word)))
+	at := func(i int) rune {
+		if i < 0 || i >= len(w) {
+			return 0
+		}
+		return w[i]
+	}
+	isVowel := func(r rune) bool { return strings.ContainsRune("aeiou", r) }
+	frontVowel := func(r rune) bool { return r == 'e' || r == 'i' || r == 'y' }
+
+	var code strings.Builder
+	last := ""
+	add := func(c string) {
+		if c != last {
+			code.WriteString(c)
+			last = c
+		}
+	}
+	for i := 0; i < len(w); i++ {
+		r := w[i]
+		switch {
+		case isVowel(r):
+			if code.Len() == 0 {
+				add("A")
+			}
+			last = "" // A vowel separates repeated consonants
+		case r == 'b' || r == 'v':
+			add("B")
+		case r == 'c':
+			switch {
+			case at(i+1) == 'h':
+				add("X")
+				i++
+			case frontVowel(at(i + 1)):
+				add("S")
+			default:
+				add("K")
+			}
+		case r == 'g':
+			switch {
+			case at(i+1) == 'u' && frontVowel(at(i+2)):
+				add("G")
+				i++ // Silent "u" of "guerra"
+			case frontVowel(at(i + 1)):
+				add("J")
+			default:
+				add("G")
+			}
+		case r == 'h':
+			// Silent
+		case r == 'j':
+			add("J")
+		case r == 'k':
+			add("K")
+		case r == 'l':
+			if at(i+1) == 'l' {
+				add("Y")
+				i++
+			} else {
+				add("L")
+			}
+		case r == 'p':
+			if at(i+1) == 'h' {
+				add("F")
+				i++
+			} else {
+				add("P")
+			}
+		case r == 'q':
+			add("K")
+			if at(i+1) == 'u' {
+				i++
+			}
+		case r == 's' || r == 'z':
+			add("S")
+		case r == 'w':
+			add("U")
+		case r == 'x':
+			if i == 0 {
+				add("J") // Old spelling of "j": "Ximénez", "Xavier"
+			} else {
+				add("KS")
+			}
+		case r == 'y':
+			if isVowel(at(i + 1)) {
+				add("Y")
+			} else if code.Len() == 0 {
+				add("A")
+			}
+		case r >= 'a' && r <= 'z':
+			add(strings.ToUpper(string(r)))
+		}
+	}
+	result := code.String()
+	if len(result) > spanishPhoneticLength {
+		result = result[:spanishPhoneticLength]
+	}
+	return result
+}
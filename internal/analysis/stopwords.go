@@ -0,0 +1,87 @@
+package analysis
+
+import (
+	"bufio"
+	"embed"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/TonyGLL/gofetch/pkg/storage"
+)
+
+//go:embed stopwords/*.txt
+var stopwordFiles embed.FS
+
+var (
+	builtinStopwordsOnce sync.Once
+	builtinStopwords     map[string][]string
+)
+
+// Stopwords returns the bundled stopword list of a language.
+func Stopwords(language string) ([]string, bool) {
+	words, ok := loadBuiltinStopwords()[strings.ToLower(language)]
+	return words, ok
+}
+
+// LoadStopwords reads a stopword file: whitespace-separated words, with
+// comments starting at "|" (the Snowball format) or "#".
+func LoadStopwords(filename string) ([]string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	words, err := parseStopwords(f)
+	if err != nil {
+		return nil, fmt.Errorf("reading stopwords %s: %w", filename, err)
+	}
+	return words, nil
+}
+
+func parseStopwords(r io.Reader) ([]string, error) {
+	var words []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexAny(line, "|#"); i >= 0 {
+			line = line[:i]
+		}
+		for _, word := range strings.Fields(line) {
+			words = append(words, strings.ToLower(word))
+		}
+	}
+	return words, scanner.Err()
+}
+
+// loadBuiltinStopwords reads the embedded stopword files once. English and
+// Spanish come from the storage package.
+func loadBuiltinStopwords() map[string][]string {
+	builtinStopwordsOnce.Do(func() {
+		builtinStopwords = map[string][]string{
+			"english": storage.EnglishStopwords,
+			"spanish": storage.SpanishStopwords,
+		}
+		entries, err := stopwordFiles.ReadDir("stopwords")
+		if err != nil {
+			return
+		}
+		for _, entry := range entries {
+			f, err := stopwordFiles.Open(path.Join("stopwords", entry.Name()))
+			if err != nil {
+				continue
+			}
+			words, err := parseStopwords(f)
+			f.Close()
+			if err != nil {
+				continue
+			}
+			lang := strings.TrimSuffix(entry.Name(), path.Ext(entry.Name()))
+			builtinStopwords[lang] = words
+		}
+	})
+	return builtinStopwords
+}
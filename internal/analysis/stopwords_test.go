@@ -0,0 +1,66 @@
+package analysis
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestNewLanguageAnalyzer_EverySnowballLanguage(t *testing.T) {
+	testCases := []struct {
+		language       string
+		inputText      string
+		expectedTokens []string
+	}{
+		{language: "french", inputText: "L'homme et les chanteuses", expectedTokens: []string{"homm", "chanteux"}},
+		{language: "russian", inputText: "Он читал книги", expectedTokens: []string{"чита", "книг"}},
+		{language: "swedish", inputText: "Jag och hundarna", expectedTokens: []string{"hund"}},
+		{language: "norwegian", inputText: "Jeg og hundene", expectedTokens: []string{"hund"}},
+		{language: "hungarian", inputText: "A kutyák és a macskák", expectedTokens: []string{"kutya", "macska"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.language, func(t *testing.T) {
+			analyzer, err := NewLanguageAnalyzer(tc.language)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tokens := analyzer.Analyze(tc.inputText); !reflect.DeepEqual(tokens, tc.expectedTokens) {
+				t.Errorf("Expected tokens %v, but got %v", tc.expectedTokens, tokens)
+			}
+		})
+	}
+
+	if _, err := NewLanguageAnalyzer("klingon"); err == nil {
+		t.Error("Expected an error for an unsupported language")
+	}
+	for _, language := range SupportedLanguages {
+		if words, ok := Stopwords(language); !ok || len(words) == 0 {
+			t.Errorf("Expected a bundled stopword list for %s", language)
+		}
+	}
+}
+
+func TestLoadStopwords(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "stopwords.txt")
+	content := "| A comment\nFoo   | trailing comment\nbar baz\n# another comment\n\n"
+	if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	words, err := LoadStopwords(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(words, []string{"foo", "bar", "baz"}) {
+		t.Errorf("Unexpected stopwords %v", words)
+	}
+
+	analyzer, err := Build("custom", AnalyzerSpec{Filters: []FilterSpec{{Type: "lowercase"}, {Type: "stop", WordsFile: file}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tokens := analyzer.Analyze("Foo qux BAZ"); !reflect.DeepEqual(tokens, []string{"qux"}) {
+		t.Errorf("Expected the file stopwords to be removed, got %v", tokens)
+	}
+}
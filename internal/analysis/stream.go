@@ -0,0 +1,162 @@
+package analysis
+
+import (
+	"bytes"
+	"io"
+	"slices"
+	"strings"
+)
+
+// streamChunkSize is the amount of text a TokenStream analyzes at once. A
+// chunk ends at the first line break after this many bytes.
+const streamChunkSize = 64 << 10
+
+// streamBufferSize is the initial size of the buffer a TokenStream reads into.
+const streamBufferSize = 4 << 10
+
+// TokenStream returns the tokens of a text one at a time, like
+// bufio.Scanner:
+//
+//	stream := analyzer.Stream(f)
+//	for stream.Next() {
+//		token := stream.Token()
+//		...
+//	}
+//	if err := stream.Err(); err != nil {
+//		...
+//	}
+//
+// The text is analyzed a chunk of lines at a time, so only the current chunk
+// and its tokens are held in memory however long the input is. Positions and
+// offsets are those of the whole input. Filters that join several words, such
+// as multi-word synonyms, do not match across chunk boundaries.
+type TokenStream struct {
+	analyzer *Analyzer
+	whole    bool      // The tokenizer needs the whole text at once
+	reader   io.Reader // Nil when streaming a string
+	buf      []byte    // Text read and not analyzed yet
+	scanned  int       // Bytes of buf known to have no line break ending a chunk
+	text     string    // String not analyzed yet
+	done     bool
+	tokens   []Token
+	next     int
+	token    Token
+	offset   int // Byte offset of the chunk in the input
+	position int // Position of the first word of the chunk
+	err      error
+}
+
+// Stream returns a TokenStream over the text read from r.
+func (a *Analyzer) Stream(r io.Reader) *TokenStream {
+	_, whole := a.tokenizer.(KeywordTokenizer)
+	return &TokenStream{analyzer: a, whole: whole, reader: r}
+}
+
+// StreamString returns a TokenStream over a text already in memory. Its
+// chunks share the memory of the text, so only the tokens of one chunk are
+// held at a time.
+func (a *Analyzer) StreamString(text string) *TokenStream {
+	_, whole := a.tokenizer.(KeywordTokenizer)
+	return &TokenStream{analyzer: a, whole: whole, text: text}
+}
+
+// Next advances to the next token, analyzing more text when needed. It
+// returns false at the end of the input or on a read error.
+func (s *TokenStream) Next() bool {
+	for s.next >= len(s.tokens) {
+		if s.done {
+			return false
+		}
+		s.analyze(s.nextChunk())
+	}
+	s.token = s.tokens[s.next]
+	s.next++
+	return true
+}
+
+// Token returns the token found by the last call to Next.
+func (s *TokenStream) Token() Token {
+	return s.token
+}
+
+// Err returns the first error other than io.EOF met while reading.
+func (s *TokenStream) Err() error {
+	return s.err
+}
+
+// nextChunk returns the next chunk of the text: up to the first line break
+// after streamChunkSize bytes, or the rest of the text.
+func (s *TokenStream) nextChunk() string {
+	if s.reader == nil {
+		end := chunkEnd(s.text, s.whole)
+		chunk := s.text[:end]
+		s.text = s.text[end:]
+		s.done = s.text == ""
+		return chunk
+	}
+
+	end := -1
+	for {
+		if !s.whole && len(s.buf) >= streamChunkSize {
+			from := max(s.scanned, streamChunkSize-1)
+			if i := bytes.IndexByte(s.buf[from:], '\n'); i >= 0 {
+				end = from + i + 1
+				break
+			}
+			s.scanned = len(s.buf)
+		}
+		if s.err != nil {
+			break
+		}
+		if len(s.buf) == cap(s.buf) {
+			s.buf = slices.Grow(s.buf, max(cap(s.buf), streamBufferSize))
+		}
+		n, err := s.reader.Read(s.buf[len(s.buf):cap(s.buf)])
+		s.buf = s.buf[:len(s.buf)+n]
+		if err != nil {
+			s.err = err
+		}
+	}
+	if end < 0 {
+		end = len(s.buf)
+	}
+	chunk := string(s.buf[:end])
+	s.buf = s.buf[:copy(s.buf, s.buf[end:])]
+	s.scanned = 0
+	if s.err != nil && len(s.buf) == 0 {
+		s.done = true
+		if s.err == io.EOF {
+			s.err = nil
+		}
+	}
+	return chunk
+}
+
+// chunkEnd returns the end of the first chunk of text.
+func chunkEnd(text string, whole bool) int {
+	if whole || len(text) <= streamChunkSize {
+		return len(text)
+	}
+	if i := strings.IndexByte(text[streamChunkSize-1:], '\n'); i >= 0 {
+		return streamChunkSize + i
+	}
+	return len(text)
+}
+
+// analyze runs the analyzer over a chunk of the text.
+func (s *TokenStream) analyze(chunk string) {
+	tokens := s.analyzer.tokenizer.Tokenize(chunk)
+	words := 0
+	if len(tokens) > 0 {
+		words = tokens[len(tokens)-1].Position + 1
+	}
+	tokens = s.analyzer.filter(tokens)
+	for i := range tokens {
+		tokens[i].Position += s.position
+		tokens[i].Start += s.offset
+		tokens[i].End += s.offset
+	}
+	s.tokens, s.next = tokens, 0
+	s.position += words
+	s.offset += len(chunk)
+}
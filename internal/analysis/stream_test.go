@@ -0,0 +1,66 @@
+package analysis
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestAnalyzer_Stream(t *testing.T) {
+	// Long enough for several chunks, with a line longer than a chunk.
+	text := strings.Repeat("The quick brown foxes jumped over the lazy dogs.\nCanción del verano, 1.25 €\n", 3000) +
+		strings.Repeat("word ", streamChunkSize/2) + "\nlast line"
+	analyzer := NewEnglishAnalyzer()
+
+	var got []Token
+	stream := analyzer.Stream(iotest.HalfReader(strings.NewReader(text)))
+	for stream.Next() {
+		got = append(got, stream.Token())
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := analyzer.Tokens(text)
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected the stream to match Tokens: got %d tokens, expected %d", len(got), len(expected))
+	}
+
+	got = got[:0]
+	for stream := analyzer.StreamString(text); stream.Next(); {
+		got = append(got, stream.Token())
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected the string stream to match Tokens: got %d tokens, expected %d", len(got), len(expected))
+	}
+}
+
+func TestAnalyzer_StreamKeyword(t *testing.T) {
+	analyzer := NewPipeline("tags", "", KeywordTokenizer{}, LowercaseFilter{})
+	stream := analyzer.Stream(strings.NewReader("Release\nNotes"))
+	var terms []string
+	for stream.Next() {
+		terms = append(terms, stream.Token().Term)
+	}
+	if !reflect.DeepEqual(terms, []string{"release\nnotes"}) {
+		t.Errorf("Expected the keyword tokenizer to see the whole text, got %q", terms)
+	}
+}
+
+func TestAnalyzer_StreamError(t *testing.T) {
+	failure := errors.New("disk on fire")
+	reader := io.MultiReader(strings.NewReader("quick words\n"), iotest.ErrReader(failure))
+	stream := NewEnglishAnalyzer().Stream(reader)
+	var terms []string
+	for stream.Next() {
+		terms = append(terms, stream.Token().Term)
+	}
+	if !errors.Is(stream.Err(), failure) {
+		t.Errorf("Expected the read error, got %v", stream.Err())
+	}
+	if !reflect.DeepEqual(terms, []string{"quick", "word"}) {
+		t.Errorf("Expected the text read before the error, got %v", terms)
+	}
+}
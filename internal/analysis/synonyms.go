@@ -0,0 +1,229 @@
+package analysis
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// SynonymSet is a list of synonym rules in the Solr synonyms format, one
+// rule per line:
+//
+//	# Equivalent terms: each one is expanded to all of them
+//	db, database
+//	new york, nyc, big apple
+//	# One-way mappings: the terms on the left are replaced by those on the right
+//	k8s => kubernetes
+//
+// Entries may have several words. Rules are compiled into a SynonymFilter,
+// either as written or analyzed by an analyzer so they match its terms.
+type SynonymSet struct {
+	rules   []string
+	entries []synonymEntry
+}
+
+// synonymEntry expands the phrase from to the phrases in to.
+type synonymEntry struct {
+	from []string
+	to   [][]string
+}
+
+// ParseSynonyms parses synonym rules. Empty lines and lines starting with
+// "#" are ignored.
+func ParseSynonyms(rules []string) (*SynonymSet, error) {
+	s := &SynonymSet{}
+	for _, rule := range rules {
+		rule = strings.TrimSpace(rule)
+		if rule == "" || strings.HasPrefix(rule, "#") {
+			continue
+		}
+		if lhs, rhs, explicit := strings.Cut(rule, "=>"); explicit {
+			from, to := synonymPhrases(lhs), synonymPhrases(rhs)
+			if len(from) == 0 || len(to) == 0 {
+				return nil, fmt.Errorf("invalid synonym rule %q", rule)
+			}
+			for _, phrase := range from {
+				s.entries = append(s.entries, synonymEntry{from: phrase, to: to})
+			}
+		} else {
+			group := synonymPhrases(rule)
+			if len(group) < 2 {
+				return nil, fmt.Errorf("synonym rule %q needs at least two terms", rule)
+			}
+			for _, phrase := range group {
+				s.entries = append(s.entries, synonymEntry{from: phrase, to: group})
+			}
+		}
+		s.rules = append(s.rules, rule)
+	}
+	return s, nil
+}
+
+// LoadSynonyms reads a synonym file, see SynonymSet for the format.
+func LoadSynonyms(filename string) (*SynonymSet, error) {
+	lines, err := readSynonymLines(filename)
+	if err != nil {
+		return nil, err
+	}
+	set, err := ParseSynonyms(lines)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return set, nil
+}
+
+func readSynonymLines(filename string) ([]string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading synonyms %s: %w", filename, err)
+	}
+	return lines, nil
+}
+
+func synonymPhrases(list string) [][]string {
+	var phrases [][]string
+	for _, entry := range strings.Split(list, ",") {
+		if words := strings.Fields(strings.ToLower(entry)); len(words) > 0 {
+			phrases = append(phrases, words)
+		}
+	}
+	return phrases
+}
+
+// Len returns the number of rules in the set.
+func (s *SynonymSet) Len() int {
+	return len(s.rules)
+}
+
+// Filter compiles the rules into a token filter. With a nil analyzer the
+// rule words are matched as written, lowercased; otherwise every phrase is
+// first run through the analyzer, so "databases" in a query analyzed with
+// stemming still matches the rule "db, database". Only the first term at
+// every position is kept, and phrases the analyzer removes entirely, such
+// as stopwords, are dropped.
+func (s *SynonymSet) Filter(a *Analyzer) SynonymFilter {
+	analyze := func(words []string) []string { return words }
+	if a != nil {
+		analyze = func(words []string) []string {
+			var terms []string
+			last := -1
+			for _, token := range a.Tokens(strings.Join(words, " ")) {
+				if token.Position != last {
+					terms = append(terms, token.Term)
+					last = token.Position
+				}
+			}
+			return terms
+		}
+	}
+
+	f := SynonymFilter{rules: s.rules, entries: make(map[string][]synonymEntry)}
+	for _, entry := range s.entries {
+		from := analyze(entry.from)
+		if len(from) == 0 {
+			continue
+		}
+		compiled := synonymEntry{from: from}
+		for _, phrase := range entry.to {
+			if to := analyze(phrase); len(to) > 0 && !slices.ContainsFunc(compiled.to, func(p []string) bool { return slices.Equal(p, to) }) {
+				compiled.to = append(compiled.to, to)
+			}
+		}
+		f.entries[from[0]] = append(f.entries[from[0]], compiled)
+	}
+	for _, entries := range f.entries {
+		// Try the longest phrases first.
+		sort.SliceStable(entries, func(i, j int) bool { return len(entries[i].from) > len(entries[j].from) })
+	}
+	return f
+}
+
+// SynonymFilter expands or replaces synonyms. A phrase replaced by a
+// single term gets the position of its first word; the words of a
+// multi-word synonym get consecutive positions from there, up to the
+// position of the last word matched. Words past it share that position,
+// so "nyc" expanded to "new york" does not move the words that follow it.
+// Added tokens span the offsets of the whole matched phrase.
+type SynonymFilter struct {
+	rules   []string
+	entries map[string][]synonymEntry // By the first word of the phrase
+}
+
+// NewSynonymFilter parses synonym rules, see SynonymSet, into a filter that
+// matches the rule words as written. Rules are matched against the terms
+// produced by the filters before this one, so they usually go before
+// stemming.
+func NewSynonymFilter(rules []string) (SynonymFilter, error) {
+	set, err := ParseSynonyms(rules)
+	if err != nil {
+		return SynonymFilter{}, err
+	}
+	return set.Filter(nil), nil
+}
+
+func (f SynonymFilter) Filter(tokens []Token) []Token {
+	out := make([]Token, 0, len(tokens))
+	for i := 0; i < len(tokens); {
+		entry, ok := f.match(tokens[i:])
+		if !ok {
+			out = append(out, tokens[i])
+			i++
+			continue
+		}
+		n := len(entry.from)
+		first, last := tokens[i], tokens[i+n-1]
+		for _, phrase := range entry.to {
+			if slices.Equal(phrase, entry.from) {
+				out = append(out, tokens[i:i+n]...)
+				continue
+			}
+			for k, term := range phrase {
+				position := min(first.Position+k, last.Position)
+				out = append(out, Token{Term: term, Position: position, Start: first.Start, End: last.End})
+			}
+		}
+		i += n
+	}
+	return out
+}
+
+// match returns the longest rule phrase that tokens start with.
+func (f SynonymFilter) match(tokens []Token) (synonymEntry, bool) {
+	for _, entry := range f.entries[tokens[0].Term] {
+		if len(entry.from) > len(tokens) {
+			continue
+		}
+		matched := true
+		for k, word := range entry.from[1:] {
+			if tokens[k+1].Term != word {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return entry, true
+		}
+	}
+	return synonymEntry{}, false
+}
+
+func (f SynonymFilter) String() string {
+	rules := append([]string(nil), f.rules...)
+	sort.Strings(rules)
+	sum := sha1.Sum([]byte(strings.Join(rules, "\n")))
+	return fmt.Sprintf("synonym(%d:%x)", len(rules), sum[:4])
+}
@@ -0,0 +1,80 @@
+package analysis
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSynonymFilter_Phrases(t *testing.T) {
+	filter, err := NewSynonymFilter([]string{
+		"# Comments and blank lines are ignored",
+		"",
+		"new york, nyc",
+		"k8s, kube => kubernetes",
+		"big apple => new york city",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokens := filter.Filter(WhitespaceTokenizer{}.Tokenize("nyc and new york on kube in big apple"))
+	var got []Token
+	for _, token := range tokens {
+		got = append(got, Token{Term: token.Term, Position: token.Position})
+	}
+	expected := []Token{
+		{Term: "new", Position: 0}, {Term: "york", Position: 0}, {Term: "nyc", Position: 0},
+		{Term: "and", Position: 1},
+		{Term: "new", Position: 2}, {Term: "york", Position: 3}, {Term: "nyc", Position: 2},
+		{Term: "on", Position: 4},
+		{Term: "kubernetes", Position: 5},
+		{Term: "in", Position: 6},
+		{Term: "new", Position: 7}, {Term: "york", Position: 8}, {Term: "city", Position: 8},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, but got %v", expected, got)
+	}
+	if last := tokens[len(tokens)-1]; last.Start != 28 || last.End != 37 {
+		t.Errorf("Expected added tokens to span the matched phrase, got %d-%d", last.Start, last.End)
+	}
+}
+
+func TestMultiAnalyzer_QuerySynonyms(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "synonyms.txt")
+	if err := os.WriteFile(file, []byte("db, database\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	multi := NewMultiAnalyzer(NewEnglishAnalyzer(), nil)
+	if _, err := multi.ReloadQuerySynonyms(); err == nil {
+		t.Error("Expected an error when no synonym file is loaded")
+	}
+	if rules, err := multi.LoadQuerySynonyms(file); err != nil || rules != 1 {
+		t.Fatalf("LoadQuerySynonyms returned %d, %v", rules, err)
+	}
+
+	if got := multi.AnalyzeQuery("Databases", "english"); !reflect.DeepEqual(got, []string{"db", "databas"}) {
+		t.Errorf("Expected the analyzed rule to match the stemmed query, got %v", got)
+	}
+
+	if err := os.WriteFile(file, []byte("db, database\nk8s => kubernetes\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if rules, err := multi.ReloadQuerySynonyms(); err != nil || rules != 2 {
+		t.Fatalf("ReloadQuerySynonyms returned %d, %v", rules, err)
+	}
+	if got := multi.AnalyzeQuery("k8s", "english"); !reflect.DeepEqual(got, []string{"kubernet"}) {
+		t.Errorf("Expected reloaded synonyms to apply, got %v", got)
+	}
+
+	if err := os.WriteFile(file, []byte("lonely\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := multi.ReloadQuerySynonyms(); err == nil {
+		t.Error("Expected an invalid file to fail the reload")
+	}
+	if got := multi.AnalyzeQuery("k8s", "english"); !reflect.DeepEqual(got, []string{"kubernet"}) {
+		t.Errorf("Expected the previous synonyms to stay in use, got %v", got)
+	}
+}
@@ -0,0 +1,279 @@
+package analysis
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// letterPattern matches the ASCII letter runs emitted by LetterTokenizer.
+var letterPattern = regexp.MustCompile(`[[:alpha:]]+`)
+
+// StandardTokenizer splits text into words following the Unicode word
+// segmentation rules (UAX #29): words are runs of letters, combining marks
+// and digits in any script, so "canción", "naïve" and "404" are single
+// tokens. Within a word:
+//
+//   - an apostrophe between letters joins them ("don't", "l'homme");
+//   - "." and "," between digits join them ("1.25", "10,000");
+//   - "_" between word characters joins them ("snake_case").
+//
+// Hyphenated compounds are split into their parts, which get consecutive
+// positions so a phrase query for "state of the art" matches
+// "state-of-the-art". Each Han ideograph and Hiragana character is a word of
+// its own. URLs (http://, https://, ftp:// or www.) and email addresses are
+// kept whole.
+type StandardTokenizer struct{}
+
+func (StandardTokenizer) Tokenize(text string) []Token {
+	// Prose averages a word every six bytes or so.
+	tokens := make([]Token, 0, len(text)/6+1)
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if !isWordRune(r) {
+			i += size
+			continue
+		}
+		end := matchURL(text, i)
+		if end == i {
+			end = matchEmail(text, i)
+		}
+		if end == i {
+			end = scanWord(text, i)
+		}
+		tokens = append(tokens, Token{Term: text[i:end], Position: len(tokens), Start: i, End: end})
+		i = end
+	}
+	return tokens
+}
+
+func (StandardTokenizer) String() string { return "standard" }
+
+func isWordRune(r rune) bool {
+	if r < utf8.RuneSelf {
+		return 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9'
+	}
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)
+}
+
+// isSingleCharWord reports whether r is always a word on its own.
+func isSingleCharWord(r rune) bool {
+	return r >= utf8.RuneSelf && (unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r))
+}
+
+// scanWord returns the end of the word starting at text[start].
+func scanWord(text string, start int) int {
+	first, size := utf8.DecodeRuneInString(text[start:])
+	if isSingleCharWord(first) {
+		return start + size
+	}
+	prev, i := first, start+size
+	for i < len(text) {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if isWordRune(r) && !isSingleCharWord(r) {
+			prev, i = r, i+size
+			continue
+		}
+		next, nextSize := utf8.DecodeRuneInString(text[i+size:])
+		if !joins(prev, r, next) {
+			break
+		}
+		prev, i = next, i+size+nextSize
+	}
+	return i
+}
+
+// joins reports whether the punctuation mid between two word characters
+// keeps them in the same word.
+func joins(prev, mid, next rune) bool {
+	if isSingleCharWord(next) {
+		return false
+	}
+	switch mid {
+	case '\'', '’':
+		return unicode.IsLetter(prev) && unicode.IsLetter(next)
+	case '.', ',':
+		return unicode.IsDigit(prev) && unicode.IsDigit(next)
+	case '_':
+		return isWordRune(next)
+	}
+	return false
+}
+
+// urlPrefixes start the URLs kept whole by StandardTokenizer.
+var urlPrefixes = []string{"http://", "https://", "ftp://", "www."}
+
+// matchURL returns the end of the URL starting at text[start], or start when
+// there is none. Trailing punctuation is left out, so a URL at the end of a
+// sentence does not keep the period.
+func matchURL(text string, start int) int {
+	rest := text[start:]
+	prefix := ""
+	for _, p := range urlPrefixes {
+		if len(rest) > len(p) && strings.EqualFold(rest[:len(p)], p) {
+			prefix = p
+			break
+		}
+	}
+	if prefix == "" {
+		return start
+	}
+	end := start + len(prefix)
+	for end < len(text) {
+		r, size := utf8.DecodeRuneInString(text[end:])
+		if unicode.IsSpace(r) || strings.ContainsRune(`<>"`, r) {
+			break
+		}
+		end += size
+	}
+	end = start + len(strings.TrimRight(text[start:end], `.,;:!?'")]}`))
+	if end == start+len(prefix) {
+		return start // A bare "www." or "http://"
+	}
+	return end
+}
+
+// matchEmail returns the end of the email address starting at text[start],
+// or start when there is none. The domain needs at least one dot.
+func matchEmail(text string, start int) int {
+	at := start
+	for at < len(text) && isEmailLocal(text[at]) {
+		at++
+	}
+	if at == start || at >= len(text) || text[at] != '@' {
+		return start
+	}
+	end, dots := at+1, 0
+	for end < len(text) {
+		c := text[end]
+		if c == '.' && end+1 < len(text) && isDomainChar(text[end+1]) && isDomainChar(text[end-1]) {
+			dots++
+		} else if !isDomainChar(c) {
+			break
+		}
+		end++
+	}
+	if dots == 0 {
+		return start
+	}
+	return end
+}
+
+func isEmailLocal(c byte) bool {
+	return isDomainChar(c) || strings.IndexByte("._%+", c) >= 0
+}
+
+func isDomainChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-'
+}
+
+// LetterTokenizer emits runs of ASCII letters. Letters with diacritics split
+// words, so it only suits plain English text; prefer StandardTokenizer.
+type LetterTokenizer struct{}
+
+func (LetterTokenizer) Tokenize(text string) []Token {
+	spans := letterPattern.FindAllStringIndex(text, -1)
+	tokens := make([]Token, len(spans))
+	for i, span := range spans {
+		tokens[i] = Token{Term: text[span[0]:span[1]], Position: i, Start: span[0], End: span[1]}
+	}
+	return tokens
+}
+
+func (LetterTokenizer) String() string { return "letter" }
+
+// WhitespaceTokenizer splits text at whitespace, keeping punctuation.
+type WhitespaceTokenizer struct{}
+
+func (WhitespaceTokenizer) Tokenize(text string) []Token {
+	var tokens []Token
+	start := -1
+	for i, r := range text {
+		switch {
+		case !unicode.IsSpace(r) && start < 0:
+			start = i
+		case unicode.IsSpace(r) && start >= 0:
+			tokens = append(tokens, Token{Term: text[start:i], Position: len(tokens), Start: start, End: i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, Token{Term: text[start:], Position: len(tokens), Start: start, End: len(text)})
+	}
+	return tokens
+}
+
+func (WhitespaceTokenizer) String() string { return "whitespace" }
+
+// KeywordTokenizer emits the whole trimmed text as a single token, for
+// fields matched exactly such as IDs or tags.
+type KeywordTokenizer struct{}
+
+func (KeywordTokenizer) Tokenize(text string) []Token {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return nil
+	}
+	start := len(text) - len(strings.TrimLeftFunc(text, unicode.IsSpace))
+	return []Token{{Term: trimmed, Start: start, End: start + len(trimmed)}}
+}
+
+func (KeywordTokenizer) String() string { return "keyword" }
+
+// URLTokenizer splits URLs and file paths into their words: runs of letters,
+// marks and digits, so "https://go.dev/doc/effective_go" gives "https",
+// "go", "dev", "doc", "effective" and "go". Every other character, such as
+// "/", ".", ":", "_", "-", "?", "=" and "&", separates words.
+type URLTokenizer struct{}
+
+func (URLTokenizer) Tokenize(text string) []Token {
+	var tokens []Token
+	start := -1
+	for i, r := range text {
+		switch {
+		case isWordRune(r) && start < 0:
+			start = i
+		case !isWordRune(r) && start >= 0:
+			tokens = append(tokens, Token{Term: text[start:i], Position: len(tokens), Start: start, End: i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, Token{Term: text[start:], Position: len(tokens), Start: start, End: len(text)})
+	}
+	return tokens
+}
+
+func (URLTokenizer) String() string { return "url" }
+
+// IdentifierTokenizer emits the identifiers of source code: runs of letters,
+// digits and underscores that do not start with a digit.
+type IdentifierTokenizer struct{}
+
+func (IdentifierTokenizer) Tokenize(text string) []Token {
+	var tokens []Token
+	add := func(start, end int) {
+		if first, _ := utf8.DecodeRuneInString(text[start:]); unicode.IsDigit(first) {
+			return // A number literal such as 0x1F or 10
+		}
+		tokens = append(tokens, Token{Term: text[start:end], Position: len(tokens), Start: start, End: end})
+	}
+	start := -1
+	for i, r := range text {
+		isIdent := unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+		switch {
+		case isIdent && start < 0:
+			start = i
+		case !isIdent && start >= 0:
+			add(start, i)
+			start = -1
+		}
+	}
+	if start >= 0 {
+		add(start, len(text))
+	}
+	return tokens
+}
+
+func (IdentifierTokenizer) String() string { return "identifier" }
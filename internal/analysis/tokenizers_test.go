@@ -0,0 +1,90 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStandardTokenizer_Tokenize(t *testing.T) {
+	testCases := []struct {
+		name           string
+		inputText      string
+		expectedTokens []string
+	}{
+		{
+			name:           "Accented letters stay in the word",
+			inputText:      "La canción del niño, naïve",
+			expectedTokens: []string{"La", "canción", "del", "niño", "naïve"},
+		},
+		{
+			name:           "Numbers with separators",
+			inputText:      "Error 404 after 1.25s, 10,000 requests. Done.",
+			expectedTokens: []string{"Error", "404", "after", "1.25s", "10,000", "requests", "Done"},
+		},
+		{
+			name:           "Apostrophes join letters",
+			inputText:      "don't 'quote' l’homme",
+			expectedTokens: []string{"don't", "quote", "l’homme"},
+		},
+		{
+			name:           "Hyphenated compounds are split",
+			inputText:      "state-of-the-art",
+			expectedTokens: []string{"state", "of", "the", "art"},
+		},
+		{
+			name:           "Emails and URLs are kept whole",
+			inputText:      "Mail ana.perez@example.com or see https://example.com/docs?q=1. Also www.go.dev!",
+			expectedTokens: []string{"Mail", "ana.perez@example.com", "or", "see", "https://example.com/docs?q=1", "Also", "www.go.dev"},
+		},
+		{
+			name:           "Ideographs are single words",
+			inputText:      "東京タワー",
+			expectedTokens: []string{"東", "京", "タワー"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var terms []string
+			for _, token := range (StandardTokenizer{}).Tokenize(tc.inputText) {
+				if tc.inputText[token.Start:token.End] != token.Term {
+					t.Errorf("Offsets %d-%d do not match term %q", token.Start, token.End, token.Term)
+				}
+				terms = append(terms, token.Term)
+			}
+			if !reflect.DeepEqual(terms, tc.expectedTokens) {
+				t.Errorf("Expected tokens %v, but got %v", tc.expectedTokens, terms)
+			}
+		})
+	}
+}
+
+func TestURLTokenizer_Tokenize(t *testing.T) {
+	testCases := map[string][]string{
+		"https://go.dev/doc/effective_go":       {"https", "go", "dev", "doc", "effective", "go"},
+		"docs/plan_english.md":                  {"docs", "plan", "english", "md"},
+		"http://example.com/search?q=go&page=2": {"http", "example", "com", "search", "q", "go", "page", "2"},
+		"file:///home/ana/año-2024.txt":         {"file", "home", "ana", "año", "2024", "txt"},
+	}
+	for input, expected := range testCases {
+		var terms []string
+		for _, token := range (URLTokenizer{}).Tokenize(input) {
+			terms = append(terms, token.Term)
+		}
+		if !reflect.DeepEqual(terms, expected) {
+			t.Errorf("Tokenize(%q) = %v, expected %v", input, terms, expected)
+		}
+	}
+}
+
+func TestTokenizers_Offsets(t *testing.T) {
+	text := "  Get_value(x) \t"
+	tokenizers := []Tokenizer{LetterTokenizer{}, WhitespaceTokenizer{}, KeywordTokenizer{}, IdentifierTokenizer{}, URLTokenizer{}}
+	for _, tokenizer := range tokenizers {
+		for _, token := range tokenizer.Tokenize(text) {
+			if text[token.Start:token.End] != token.Term {
+				t.Errorf("%s: offsets %d-%d do not match term %q", tokenizer, token.Start, token.End, token.Term)
+			}
+		}
+	}
+}
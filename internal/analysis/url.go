@@ -0,0 +1,21 @@
+package analysis
+
+// URLAnalyzer is the name of the analyzer of the url field, see NewURLAnalyzer.
+const URLAnalyzer = "url"
+
+// urlStopwords are the URL parts found in nearly every URL.
+var urlStopwords = []string{"http", "https", "ftp", "www"}
+
+// NewURLAnalyzer creates the analyzer of the url field. URLs and file paths
+// are split into their words by URLTokenizer, so "url:effective" finds
+// "https://go.dev/doc/effective_go". Words are normalized, lowercased and
+// folded but not stemmed, and the scheme and "www" are dropped.
+func NewURLAnalyzer() *Analyzer {
+	nfkc, _ := NewNormalizeFilter("nfkc")
+	return NewPipeline(URLAnalyzer, "", URLTokenizer{},
+		nfkc,
+		LowercaseFilter{},
+		NewStopFilter(URLAnalyzer, urlStopwords),
+		ASCIIFoldingFilter{},
+	)
+}
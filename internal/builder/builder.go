@@ -2,10 +2,13 @@ package builder
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
 	"github.com/TonyGLL/gofetch/internal/analysis"
 	"github.com/TonyGLL/gofetch/internal/config"
 	"github.com/TonyGLL/gofetch/internal/indexer"
+	"github.com/TonyGLL/gofetch/internal/search"
 	"github.com/TonyGLL/gofetch/pkg/storage"
 )
 
@@ -14,12 +17,138 @@ func NewMongoStore(ctx context.Context, cfg *config.Config) (*storage.MongoStore
 	return storage.NewMongoStore(ctx, cfg.MongoURI, cfg.DBName)
 }
 
-// NewAnalyzer creates a new Analyzer instance.
-func NewAnalyzer() *analysis.Analyzer {
-	return analysis.NewFromEnv()
+// NewAnalyzer creates a new language-aware Analyzer instance with the custom
+// analyzers declared in cfg registered and assigned to their fields and sources.
+func NewAnalyzer(cfg *config.Config) (*analysis.MultiAnalyzer, error) {
+	multi := analysis.NewMultiFromEnv()
+
+	for language, file := range cfg.Analysis.Stopwords {
+		if _, err := analysis.NewLanguageAnalyzer(language); err != nil {
+			return nil, fmt.Errorf("stopwords: %w", err)
+		}
+		words, err := analysis.LoadStopwords(file)
+		if err != nil {
+			return nil, err
+		}
+		multi.Register(analysis.New(words, strings.ToLower(language)))
+	}
+
+	for name, ac := range cfg.Analysis.Analyzers {
+		spec := analysis.AnalyzerSpec{Tokenizer: ac.Tokenizer}
+		for _, fc := range ac.Filters {
+			spec.Filters = append(spec.Filters, analysis.FilterSpec{
+				Type:             fc.Type,
+				Language:         fc.Language,
+				Words:            fc.Words,
+				WordsFile:        fc.WordsFile,
+				Min:              fc.Min,
+				Max:              fc.Max,
+				Synonyms:         fc.Synonyms,
+				SynonymsFile:     fc.SynonymsFile,
+				Form:             fc.Form,
+				PreserveOriginal: fc.PreserveOriginal,
+				Encoder:          fc.Encoder,
+			})
+		}
+		analyzer, err := analysis.Build(name, spec)
+		if err != nil {
+			return nil, err
+		}
+		multi.Register(analyzer)
+	}
+
+	if cfg.Analysis.QuerySynonyms != "" {
+		if _, err := multi.LoadQuerySynonyms(cfg.Analysis.QuerySynonyms); err != nil {
+			return nil, err
+		}
+	}
+
+	// URLs and file paths are split into their words, unless the
+	// configuration assigns the url field an analyzer of its own.
+	multi.Register(analysis.NewURLAnalyzer())
+	if err := multi.SetFieldAnalyzer(storage.FieldURL, analysis.URLAnalyzer); err != nil {
+		return nil, err
+	}
+	for field, name := range cfg.Analysis.Fields {
+		if err := multi.SetFieldAnalyzer(field, name); err != nil {
+			return nil, err
+		}
+	}
+	for field, name := range cfg.Analysis.Search {
+		if err := multi.SetFieldSearchAnalyzer(field, name); err != nil {
+			return nil, err
+		}
+	}
+	if prefixes := cfg.Indexer.Prefixes; prefixes.Enabled {
+		index, search := analysis.NewPrefixAnalyzers(prefixes.Min, prefixes.Max)
+		multi.Register(index)
+		multi.Register(search)
+		if err := multi.SetFieldAnalyzer(storage.FieldPrefix, index.Name()); err != nil {
+			return nil, err
+		}
+		if err := multi.SetFieldSearchAnalyzer(storage.FieldPrefix, search.Name()); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Indexer.Phonetic.Enabled {
+		multi.Register(analysis.NewPhoneticAnalyzer("english"))
+		multi.Register(analysis.NewPhoneticAnalyzer("spanish"))
+		if err := multi.SetFieldAnalyzer(storage.FieldPhonetic, analysis.PhoneticAnalyzer); err != nil {
+			return nil, err
+		}
+		if err := multi.SetFieldLanguageAnalyzer(storage.FieldPhonetic, "spanish", analysis.PhoneticSpanishAnalyzer); err != nil {
+			return nil, err
+		}
+	}
+	for _, src := range cfg.Indexer.SourceList() {
+		if src.Analyzer != "" {
+			if err := multi.UseForDocuments(src.Analyzer); err != nil {
+				return nil, fmt.Errorf("source %s: %w", src.Path, err)
+			}
+		}
+		if src.Language != "" {
+			if err := multi.UseForDocuments(strings.ToLower(src.Language)); err != nil {
+				return nil, fmt.Errorf("source %s: %w", src.Path, err)
+			}
+		}
+	}
+	return multi, nil
+}
+
+// NewIndexSources maps the configured index roots to indexer sources.
+func NewIndexSources(cfg *config.Config) []indexer.Source {
+	configured := cfg.Indexer.SourceList()
+	sources := make([]indexer.Source, 0, len(configured))
+	for _, src := range configured {
+		sources = append(sources, indexer.Source{
+			Type:     src.Type,
+			Root:     src.Path,
+			Label:    src.Label,
+			Include:  src.Include,
+			Exclude:  src.Exclude,
+			Language: src.Language,
+			Analyzer: src.Analyzer,
+			Ref:      src.Ref,
+			Commits:  src.Commits,
+		})
+	}
+	return sources
+}
+
+// NewIndexer creates a new Indexer instance configured from cfg.
+func NewIndexer(analyzer *analysis.MultiAnalyzer, store *storage.MongoStore, cfg *config.Config) *indexer.Indexer {
+	idx := indexer.NewIndexer(analyzer, store)
+	idx.SetPassageOptions(indexer.PassageOptions{
+		Mode:    cfg.Indexer.Passages.Mode,
+		Size:    cfg.Indexer.Passages.Size,
+		Overlap: cfg.Indexer.Passages.Overlap,
+	})
+	return idx
 }
 
-// NewIndexer creates a new Indexer instance.
-func NewIndexer(analyzer *analysis.Analyzer, store *storage.MongoStore) *indexer.Indexer {
-	return indexer.NewIndexer(analyzer, store)
+// NewSearcher creates a new Searcher configured from cfg.
+func NewSearcher(analyzer *analysis.MultiAnalyzer, store *storage.MongoStore, cfg *config.Config) search.Searcher {
+	return search.NewSearcher(analyzer, store, search.SearcherOptions{
+		MaxExpansions: cfg.Search.MaxExpansions,
+	})
 }
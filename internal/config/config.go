@@ -9,16 +9,104 @@ import (
 // Config almacena toda la configuración de la aplicación.
 // Viper lee los valores desde un archivo de configuración o variables de entorno.
 type Config struct {
-	MongoURI   string        `mapstructure:"mongo_uri"`
-	DBName     string        `mapstructure:"db_name"`
-	ServerPort int           `mapstructure:"server_port"`
-	Crawler    CrawlerConfig `mapstructure:"crawler"`
-	Indexer    IndexerConfig `mapstructure:"indexer"`
+	MongoURI   string         `mapstructure:"mongo_uri"`
+	DBName     string         `mapstructure:"db_name"`
+	ServerPort int            `mapstructure:"server_port"`
+	Crawler    CrawlerConfig  `mapstructure:"crawler"`
+	Indexer    IndexerConfig  `mapstructure:"indexer"`
+	Analysis   AnalysisConfig `mapstructure:"analysis"`
+	Search     SearchConfig   `mapstructure:"search"`
+}
+
+// AnalysisConfig declares custom analyzers and assigns them to fields.
+type AnalysisConfig struct {
+	Analyzers map[string]AnalyzerConfig `mapstructure:"analyzers"`
+	Fields    map[string]string         `mapstructure:"fields"`    // Field name to analyzer name, e.g. title: exact
+	Search    map[string]string         `mapstructure:"search"`    // Field name to the analyzer of its queries, when it differs
+	Stopwords map[string]string         `mapstructure:"stopwords"` // Language to stopword file replacing its bundled list
+	// QuerySynonyms is a synonym file applied to queries only, so it can change
+	// without re-indexing and be reloaded on the running server.
+	QuerySynonyms string `mapstructure:"query_synonyms"`
+}
+
+// AnalyzerConfig is a tokenizer followed by an ordered list of token filters.
+type AnalyzerConfig struct {
+	Tokenizer string         `mapstructure:"tokenizer"` // standard (default), letter, whitespace, identifier, keyword or url
+	Filters   []FilterConfig `mapstructure:"filters"`
+}
+
+// FilterConfig declares one token filter; only the settings of its type apply.
+type FilterConfig struct {
+	Type             string   `mapstructure:"type"`              // normalize, lowercase, asciifolding, elision, stop, stemmer, length, synonym, ngram, edge_ngram, truncate, cjk_bigram, word_delimiter, unique or phonetic
+	Language         string   `mapstructure:"language"`          // stop and stemmer
+	Words            []string `mapstructure:"words"`             // stop: custom stopwords instead of the language list
+	WordsFile        string   `mapstructure:"words_file"`        // stop: file of custom stopwords
+	Min              int      `mapstructure:"min"`               // length and ngram
+	Max              int      `mapstructure:"max"`               // length and ngram
+	Synonyms         []string `mapstructure:"synonyms"`          // synonym: rules such as "db, database" or "k8s => kubernetes"
+	SynonymsFile     string   `mapstructure:"synonyms_file"`     // synonym: file of rules in the Solr synonyms format
+	Form             string   `mapstructure:"form"`              // normalize: nfc (default), nfd, nfkc or nfkd
+	PreserveOriginal bool     `mapstructure:"preserve_original"` // asciifolding: also keep the unfolded term
+	Encoder          string   `mapstructure:"encoder"`           // phonetic: double_metaphone (default) or spanish
 }
 
 // IndexerConfig stores the configuration for the indexer.
+// Path is kept for single-directory setups; Sources takes precedence when set.
 type IndexerConfig struct {
-	Path string `mapstructure:"path"`
+	Path     string         `mapstructure:"path"`
+	Sources  []SourceConfig `mapstructure:"sources"`
+	Passages PassageConfig  `mapstructure:"passages"`
+	Prefixes PrefixConfig   `mapstructure:"prefixes"`
+	Phonetic PhoneticConfig `mapstructure:"phonetic"`
+}
+
+// PrefixConfig controls the edge n-gram field used for prefix matches.
+type PrefixConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	Min     int  `mapstructure:"min"` // Shortest prefix indexed, default 2
+	Max     int  `mapstructure:"max"` // Longest prefix indexed, default 15
+}
+
+// PhoneticConfig controls the field of phonetic codes used for sounds-like
+// matches.
+type PhoneticConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+}
+
+// PassageConfig controls how long documents are split into passages.
+type PassageConfig struct {
+	Mode    string `mapstructure:"mode"`    // "heading" or "window"; empty disables passages
+	Size    int    `mapstructure:"size"`    // Maximum words per passage
+	Overlap int    `mapstructure:"overlap"` // Words shared by consecutive windows
+}
+
+// SourceConfig describes one directory tree or repository to index.
+type SourceConfig struct {
+	Type     string   `mapstructure:"type"` // "dir" (default), "git", "mbox" or "maildir"
+	Path     string   `mapstructure:"path"`
+	Label    string   `mapstructure:"label"`    // Stored on every document, e.g. "handbook"
+	Include  []string `mapstructure:"include"`  // Glob patterns; defaults to *.txt and *.md
+	Exclude  []string `mapstructure:"exclude"`  // Glob patterns for files or directories to skip
+	Language string   `mapstructure:"language"` // Forces an analyzer language instead of detection
+	Analyzer string   `mapstructure:"analyzer"` // Named analyzer from analysis.analyzers for this source
+	Ref      string   `mapstructure:"ref"`      // Git only: branch, tag or commit; defaults to HEAD
+	Commits  bool     `mapstructure:"commits"`  // Git only: also index commit messages
+}
+
+// SourceList returns the configured sources, falling back to Path.
+func (c IndexerConfig) SourceList() []SourceConfig {
+	if len(c.Sources) > 0 {
+		return c.Sources
+	}
+	if c.Path == "" {
+		return nil
+	}
+	return []SourceConfig{{Path: c.Path}}
+}
+
+// SearchConfig holds the settings of the search server.
+type SearchConfig struct {
+	MaxExpansions int `mapstructure:"max_expansions"` // Terms a pattern or fuzzy word expands to at most, default 1000
 }
 
 // CrawlerConfig almacena la configuración para el crawler.
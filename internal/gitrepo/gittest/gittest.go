@@ -0,0 +1,56 @@
+// Package gittest creates git repositories for tests with the git CLI.
+package gittest
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// Repo is a repository in a temporary directory, with a fixed author and no
+// user or system configuration.
+type Repo struct {
+	Dir string
+	t   testing.TB
+}
+
+// New creates an empty repository on the main branch. The test is skipped
+// when git is not installed.
+func New(t testing.TB) *Repo {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	r := &Repo{Dir: t.TempDir(), t: t}
+	r.Run("init", "-q", "-b", "main")
+	return r
+}
+
+// Run runs a git command in the repository and fails the test when it fails.
+func (r *Repo) Run(args ...string) {
+	r.t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.Dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=Ana", "GIT_AUTHOR_EMAIL=ana@example.com",
+		"GIT_COMMITTER_NAME=Ana", "GIT_COMMITTER_EMAIL=ana@example.com",
+		"GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_SYSTEM=/dev/null",
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		r.t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+// Write writes a file of the working tree, given by its slash-separated
+// path, creating its directories.
+func (r *Repo) Write(name, content string) {
+	r.t.Helper()
+	p := filepath.Join(r.Dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		r.t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte(content), 0o600); err != nil {
+		r.t.Fatal(err)
+	}
+}
@@ -0,0 +1,369 @@
+package gitrepo
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Tree entry modes that are not regular blobs.
+const (
+	modeTree      = "40000"
+	modeSubmodule = "160000"
+)
+
+// Signature identifies the author or committer of a commit.
+type Signature struct {
+	Name  string
+	Email string
+	When  time.Time
+}
+
+// Commit is a parsed commit object.
+type Commit struct {
+	Hash      Hash
+	Tree      Hash
+	Parents   []Hash
+	Author    Signature
+	Committer Signature
+	Message   string
+}
+
+// Subject returns the first line of the commit message.
+func (c *Commit) Subject() string {
+	subject, _, _ := strings.Cut(strings.TrimSpace(c.Message), "\n")
+	return subject
+}
+
+// Commit reads and parses a commit.
+func (r *Repo) Commit(h Hash) (*Commit, error) {
+	typ, data, err := r.Object(h)
+	if err != nil {
+		return nil, err
+	}
+	if typ != TypeCommit {
+		return nil, fmt.Errorf("object %s is not a commit", h)
+	}
+
+	c := &Commit{Hash: h}
+	header, message, _ := bytes.Cut(data, []byte("\n\n"))
+	c.Message = string(message)
+	for _, line := range strings.Split(string(header), "\n") {
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "tree":
+			if c.Tree, err = ParseHash(value); err != nil {
+				return nil, err
+			}
+		case "parent":
+			parent, err := ParseHash(value)
+			if err != nil {
+				return nil, err
+			}
+			c.Parents = append(c.Parents, parent)
+		case "author":
+			c.Author = parseSignature(value)
+		case "committer":
+			c.Committer = parseSignature(value)
+		}
+	}
+	return c, nil
+}
+
+// parseSignature parses "Name <email> 1700000000 +0100".
+func parseSignature(s string) Signature {
+	var sig Signature
+	open, closing := strings.LastIndex(s, "<"), strings.LastIndex(s, ">")
+	if open < 0 || closing < open {
+		sig.Name = strings.TrimSpace(s)
+		return sig
+	}
+	sig.Name = strings.TrimSpace(s[:open])
+	sig.Email = s[open+1 : closing]
+
+	fields := strings.Fields(s[closing+1:])
+	if len(fields) == 0 {
+		return sig
+	}
+	seconds, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return sig
+	}
+	sig.When = time.Unix(seconds, 0).UTC()
+	if len(fields) > 1 && len(fields[1]) == 5 {
+		hours, errH := strconv.Atoi(fields[1][1:3])
+		minutes, errM := strconv.Atoi(fields[1][3:5])
+		if errH == nil && errM == nil {
+			offset := hours*3600 + minutes*60
+			if fields[1][0] == '-' {
+				offset = -offset
+			}
+			sig.When = sig.When.In(time.FixedZone(fields[1], offset))
+		}
+	}
+	return sig
+}
+
+// TreeEntry is one entry of a tree object.
+type TreeEntry struct {
+	Mode string
+	Name string
+	Hash Hash
+}
+
+// IsTree reports whether the entry is a subdirectory.
+func (e TreeEntry) IsTree() bool {
+	return e.Mode == modeTree
+}
+
+// Tree reads and parses a tree object.
+func (r *Repo) Tree(h Hash) ([]TreeEntry, error) {
+	typ, data, err := r.Object(h)
+	if err != nil {
+		return nil, err
+	}
+	if typ != TypeTree {
+		return nil, fmt.Errorf("object %s is not a tree", h)
+	}
+
+	var entries []TreeEntry
+	for len(data) > 0 {
+		sp := bytes.IndexByte(data, ' ')
+		nul := bytes.IndexByte(data, 0)
+		if sp < 0 || nul < sp || nul+21 > len(data) {
+			return nil, fmt.Errorf("malformed tree %s", h)
+		}
+		var entry TreeEntry
+		entry.Mode = string(data[:sp])
+		entry.Name = string(data[sp+1 : nul])
+		copy(entry.Hash[:], data[nul+1:nul+21])
+		entries = append(entries, entry)
+		data = data[nul+21:]
+	}
+	return entries, nil
+}
+
+// Blob reads the content of a blob.
+func (r *Repo) Blob(h Hash) ([]byte, error) {
+	typ, data, err := r.Object(h)
+	if err != nil {
+		return nil, err
+	}
+	if typ != TypeBlob {
+		return nil, fmt.Errorf("object %s is not a blob", h)
+	}
+	return data, nil
+}
+
+// Files returns every file of a tree, recursively, keyed by slash-separated
+// path. Submodules are skipped.
+func (r *Repo) Files(tree Hash) (map[string]Hash, error) {
+	files := make(map[string]Hash)
+	var walk func(h Hash, prefix string) error
+	walk = func(h Hash, prefix string) error {
+		entries, err := r.Tree(h)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			p := path.Join(prefix, e.Name)
+			switch {
+			case e.IsTree():
+				if err := walk(e.Hash, p); err != nil {
+					return err
+				}
+			case e.Mode != modeSubmodule:
+				files[p] = e.Hash
+			}
+		}
+		return nil
+	}
+	return files, walk(tree, "")
+}
+
+// ChangeType describes how a file differs between two trees.
+type ChangeType int
+
+// Change types reported by DiffTrees.
+const (
+	Added ChangeType = iota
+	Modified
+	Deleted
+)
+
+// Change is a file that differs between two trees.
+type Change struct {
+	Type ChangeType
+	Path string
+	Hash Hash // The new blob, or the removed blob for deletions
+}
+
+// DiffTrees lists the files that differ between two trees. A zero from hash
+// is treated as an empty tree. Identical subtrees are skipped without being read.
+func (r *Repo) DiffTrees(from, to Hash) ([]Change, error) {
+	var changes []Change
+	if err := r.diffTrees(from, to, "", &changes); err != nil {
+		return nil, err
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+func (r *Repo) diffTrees(from, to Hash, prefix string, changes *[]Change) error {
+	if from == to {
+		return nil
+	}
+	fromEntries, err := r.treeEntries(from)
+	if err != nil {
+		return err
+	}
+	toEntries, err := r.treeEntries(to)
+	if err != nil {
+		return err
+	}
+
+	for name, newEntry := range toEntries {
+		p := path.Join(prefix, name)
+		oldEntry, existed := fromEntries[name]
+		switch {
+		case existed && oldEntry.Hash == newEntry.Hash && oldEntry.Mode == newEntry.Mode:
+			continue
+		case newEntry.IsTree():
+			var oldTree Hash
+			if existed && oldEntry.IsTree() {
+				oldTree = oldEntry.Hash
+			} else if existed && oldEntry.Mode != modeSubmodule {
+				*changes = append(*changes, Change{Type: Deleted, Path: p, Hash: oldEntry.Hash})
+			}
+			if err := r.diffTrees(oldTree, newEntry.Hash, p, changes); err != nil {
+				return err
+			}
+		case newEntry.Mode == modeSubmodule:
+			// Submodules are not listed, but what the path held before is gone.
+			if existed && oldEntry.IsTree() {
+				if err := r.diffTrees(oldEntry.Hash, Hash{}, p, changes); err != nil {
+					return err
+				}
+			} else if existed && oldEntry.Mode != modeSubmodule {
+				*changes = append(*changes, Change{Type: Deleted, Path: p, Hash: oldEntry.Hash})
+			}
+		case existed && !oldEntry.IsTree() && oldEntry.Mode != modeSubmodule:
+			*changes = append(*changes, Change{Type: Modified, Path: p, Hash: newEntry.Hash})
+		default:
+			if existed && oldEntry.IsTree() {
+				if err := r.diffTrees(oldEntry.Hash, Hash{}, p, changes); err != nil {
+					return err
+				}
+			}
+			*changes = append(*changes, Change{Type: Added, Path: p, Hash: newEntry.Hash})
+		}
+	}
+
+	for name, oldEntry := range fromEntries {
+		if _, ok := toEntries[name]; ok {
+			continue
+		}
+		p := path.Join(prefix, name)
+		switch {
+		case oldEntry.IsTree():
+			if err := r.diffTrees(oldEntry.Hash, Hash{}, p, changes); err != nil {
+				return err
+			}
+		case oldEntry.Mode != modeSubmodule:
+			*changes = append(*changes, Change{Type: Deleted, Path: p, Hash: oldEntry.Hash})
+		}
+	}
+	return nil
+}
+
+// treeEntries reads a tree as a map keyed by entry name; a zero hash is empty.
+func (r *Repo) treeEntries(h Hash) (map[string]TreeEntry, error) {
+	entries := make(map[string]TreeEntry)
+	if h.IsZero() {
+		return entries, nil
+	}
+	list, err := r.Tree(h)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range list {
+		entries[e.Name] = e
+	}
+	return entries, nil
+}
+
+// Log returns up to limit commits reachable from start in first-parent
+// order, stopping before the commit stop (which may be zero).
+func (r *Repo) Log(start, stop Hash, limit int) ([]*Commit, error) {
+	var commits []*Commit
+	for h := start; !h.IsZero() && h != stop && (limit <= 0 || len(commits) < limit); {
+		c, err := r.Commit(h)
+		if err != nil {
+			return nil, err
+		}
+		commits = append(commits, c)
+		if len(c.Parents) == 0 {
+			break
+		}
+		h = c.Parents[0]
+	}
+	return commits, nil
+}
+
+// LastCommits finds, for each path, the most recent first-parent commit from
+// start that changed it, examining at most limit commits. Paths not found
+// within the limit are missing from the result.
+func (r *Repo) LastCommits(start Hash, paths []string, limit int) (map[string]*Commit, error) {
+	pending := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		pending[p] = true
+	}
+	result := make(map[string]*Commit, len(paths))
+
+	h := start
+	for examined := 0; len(pending) > 0 && !h.IsZero() && (limit <= 0 || examined < limit); examined++ {
+		c, err := r.Commit(h)
+		if err != nil {
+			return nil, err
+		}
+		var parentTree Hash
+		var next Hash
+		if len(c.Parents) > 0 {
+			parent, err := r.Commit(c.Parents[0])
+			if err != nil {
+				return nil, err
+			}
+			parentTree, next = parent.Tree, parent.Hash
+		}
+		changes, err := r.DiffTrees(parentTree, c.Tree)
+		if err != nil {
+			return nil, err
+		}
+		for _, change := range changes {
+			if pending[change.Path] {
+				result[change.Path] = c
+				delete(pending, change.Path)
+			}
+		}
+		h = next
+	}
+	return result, nil
+}
+
+// IsAncestor reports whether ancestor is reachable from h through first parents.
+func (r *Repo) IsAncestor(ancestor, h Hash, limit int) (bool, error) {
+	commits, err := r.Log(h, Hash{}, limit)
+	if err != nil {
+		return false, err
+	}
+	for _, c := range commits {
+		if c.Hash == ancestor {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,298 @@
+package gitrepo
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Packfile entry types that are not stored as loose objects.
+const (
+	typeOfsDelta = 6
+	typeRefDelta = 7
+)
+
+// maxCachedBases bounds the number of delta bases kept in memory per pack.
+const maxCachedBases = 256
+
+type cachedObject struct {
+	typ  ObjectType
+	data []byte
+}
+
+// packfile is an opened .pack file with its version 2 .idx index.
+type packfile struct {
+	file    *os.File
+	fanout  [256]uint32
+	hashes  []byte // Sorted object IDs, 20 bytes each
+	offsets []uint32
+	large   []uint64 // 64-bit offsets for packs over 2 GiB
+
+	mu    sync.Mutex
+	cache map[int64]cachedObject
+}
+
+// openPacks opens every packfile in dir.
+func openPacks(dir string) ([]*packfile, error) {
+	idxFiles, err := filepath.Glob(filepath.Join(dir, "*.idx"))
+	if err != nil {
+		return nil, err
+	}
+	packs := make([]*packfile, 0, len(idxFiles))
+	for _, idxPath := range idxFiles {
+		p, err := openPack(idxPath, strings.TrimSuffix(idxPath, ".idx")+".pack")
+		if err != nil {
+			for _, opened := range packs {
+				opened.Close()
+			}
+			return nil, err
+		}
+		packs = append(packs, p)
+	}
+	return packs, nil
+}
+
+func openPack(idxPath, packPath string) (*packfile, error) {
+	idx, err := os.ReadFile(idxPath)
+	if err != nil {
+		return nil, err
+	}
+	const headerSize = 8
+	if len(idx) < headerSize+256*4 || !bytes.Equal(idx[:4], []byte{0xff, 't', 'O', 'c'}) ||
+		binary.BigEndian.Uint32(idx[4:8]) != 2 {
+		return nil, fmt.Errorf("%s: unsupported pack index version", idxPath)
+	}
+
+	p := &packfile{cache: make(map[int64]cachedObject)}
+	pos := headerSize
+	for i := range p.fanout {
+		p.fanout[i] = binary.BigEndian.Uint32(idx[pos:])
+		pos += 4
+	}
+	n := int(p.fanout[255])
+	need := pos + n*20 + n*4 + n*4
+	if len(idx) < need {
+		return nil, fmt.Errorf("%s: truncated pack index", idxPath)
+	}
+	p.hashes = idx[pos : pos+n*20]
+	pos += n * 20
+	pos += n * 4 // Skip CRC32 checksums
+	p.offsets = make([]uint32, n)
+	for i := range p.offsets {
+		p.offsets[i] = binary.BigEndian.Uint32(idx[pos:])
+		pos += 4
+	}
+	for pos+8 <= len(idx)-40 { // The index ends with two checksums
+		p.large = append(p.large, binary.BigEndian.Uint64(idx[pos:]))
+		pos += 8
+	}
+
+	if p.file, err = os.Open(packPath); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *packfile) Close() error {
+	return p.file.Close()
+}
+
+// find returns the offset of an object in the pack.
+func (p *packfile) find(h Hash) (int64, bool) {
+	lo := 0
+	if h[0] > 0 {
+		lo = int(p.fanout[h[0]-1])
+	}
+	hi := int(p.fanout[h[0]])
+	i := lo + sort.Search(hi-lo, func(i int) bool {
+		return bytes.Compare(p.hashes[(lo+i)*20:(lo+i+1)*20], h[:]) >= 0
+	})
+	if i >= hi || !bytes.Equal(p.hashes[i*20:(i+1)*20], h[:]) {
+		return 0, false
+	}
+
+	offset := p.offsets[i]
+	const largeOffsetFlag = 0x80000000
+	if offset&largeOffsetFlag != 0 {
+		j := int(offset &^ largeOffsetFlag)
+		if j >= len(p.large) {
+			return 0, false
+		}
+		return int64(p.large[j]), true
+	}
+	return int64(offset), true
+}
+
+// readAt reads and, if needed, undeltifies the object at offset. Reference
+// deltas may point to objects in other packs, so the repository is passed in.
+func (p *packfile) readAt(offset int64, repo *Repo) (ObjectType, []byte, error) {
+	p.mu.Lock()
+	cached, ok := p.cache[offset]
+	p.mu.Unlock()
+	if ok {
+		return cached.typ, cached.data, nil
+	}
+
+	r := bufio.NewReader(io.NewSectionReader(p.file, offset, 1<<62))
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	typ := int((b >> 4) & 0x07)
+	for b&0x80 != 0 { // The inflated size is not needed, skip it
+		if b, err = r.ReadByte(); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	var baseType ObjectType
+	var base []byte
+	switch typ {
+	case typeOfsDelta:
+		rel, err := readOffsetDelta(r)
+		if err != nil {
+			return 0, nil, err
+		}
+		if baseType, base, err = p.readAt(offset-rel, repo); err != nil {
+			return 0, nil, err
+		}
+	case typeRefDelta:
+		var h Hash
+		if _, err := io.ReadFull(r, h[:]); err != nil {
+			return 0, nil, err
+		}
+		if baseType, base, err = repo.Object(h); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	zr, err := zlib.NewReader(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer zr.Close()
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	objType := ObjectType(typ)
+	if base != nil {
+		if data, err = applyDelta(base, data); err != nil {
+			return 0, nil, err
+		}
+		objType = baseType
+	}
+
+	p.mu.Lock()
+	if len(p.cache) >= maxCachedBases {
+		clear(p.cache)
+	}
+	p.cache[offset] = cachedObject{typ: objType, data: data}
+	p.mu.Unlock()
+	return objType, data, nil
+}
+
+// readOffsetDelta reads the negative base offset of an OFS_DELTA entry.
+func readOffsetDelta(r io.ByteReader) (int64, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	offset := int64(b & 0x7f)
+	for b&0x80 != 0 {
+		if b, err = r.ReadByte(); err != nil {
+			return 0, err
+		}
+		offset = ((offset + 1) << 7) | int64(b&0x7f)
+	}
+	return offset, nil
+}
+
+var errBadDelta = errors.New("malformed delta")
+
+// applyDelta rebuilds an object from its base and a git delta.
+func applyDelta(base, delta []byte) ([]byte, error) {
+	pos := 0
+	readSize := func() (int, error) {
+		size, shift := 0, 0
+		for {
+			if pos >= len(delta) {
+				return 0, errBadDelta
+			}
+			b := delta[pos]
+			pos++
+			size |= int(b&0x7f) << shift
+			shift += 7
+			if b&0x80 == 0 {
+				return size, nil
+			}
+		}
+	}
+
+	srcSize, err := readSize()
+	if err != nil || srcSize != len(base) {
+		return nil, errBadDelta
+	}
+	dstSize, err := readSize()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, dstSize)
+	for pos < len(delta) {
+		op := delta[pos]
+		pos++
+		switch {
+		case op&0x80 != 0: // Copy from base
+			var offset, size int
+			for i := range 4 {
+				if op&(1<<i) != 0 {
+					if pos >= len(delta) {
+						return nil, errBadDelta
+					}
+					offset |= int(delta[pos]) << (8 * i)
+					pos++
+				}
+			}
+			for i := range 3 {
+				if op&(1<<(4+i)) != 0 {
+					if pos >= len(delta) {
+						return nil, errBadDelta
+					}
+					size |= int(delta[pos]) << (8 * i)
+					pos++
+				}
+			}
+			if size == 0 {
+				size = 0x10000
+			}
+			if offset+size > len(base) {
+				return nil, errBadDelta
+			}
+			out = append(out, base[offset:offset+size]...)
+		case op != 0: // Insert literal data
+			n := int(op)
+			if pos+n > len(delta) {
+				return nil, errBadDelta
+			}
+			out = append(out, delta[pos:pos+n]...)
+			pos += n
+		default:
+			return nil, errBadDelta
+		}
+	}
+	if len(out) != dstSize {
+		return nil, errBadDelta
+	}
+	return out, nil
+}
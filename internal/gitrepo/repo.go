@@ -0,0 +1,264 @@
+// Package gitrepo reads commits, trees and blobs from a local git repository
+// without shelling out to git. It supports loose objects, packfiles (with
+// offset and reference deltas), loose and packed refs, and annotated tags.
+// Only SHA-1 repositories are supported.
+package gitrepo
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ErrNotFound is returned when an object or reference does not exist.
+var ErrNotFound = errors.New("not found")
+
+// Hash is a SHA-1 object ID.
+type Hash [20]byte
+
+// ParseHash parses a 40 character hexadecimal object ID.
+func ParseHash(s string) (Hash, error) {
+	var h Hash
+	if len(s) != 40 {
+		return h, fmt.Errorf("invalid object id %q", s)
+	}
+	if _, err := hex.Decode(h[:], []byte(s)); err != nil {
+		return h, fmt.Errorf("invalid object id %q: %w", s, err)
+	}
+	return h, nil
+}
+
+// String returns the hexadecimal form of the hash.
+func (h Hash) String() string {
+	return hex.EncodeToString(h[:])
+}
+
+// IsZero reports whether the hash is unset.
+func (h Hash) IsZero() bool {
+	return h == Hash{}
+}
+
+// ObjectType is the type of a git object.
+type ObjectType int
+
+// Object types as stored in packfiles.
+const (
+	TypeCommit ObjectType = 1
+	TypeTree   ObjectType = 2
+	TypeBlob   ObjectType = 3
+	TypeTag    ObjectType = 4
+)
+
+func parseObjectType(s string) (ObjectType, error) {
+	switch s {
+	case "commit":
+		return TypeCommit, nil
+	case "tree":
+		return TypeTree, nil
+	case "blob":
+		return TypeBlob, nil
+	case "tag":
+		return TypeTag, nil
+	}
+	return 0, fmt.Errorf("unknown object type %q", s)
+}
+
+// Repo is a read-only handle on a local repository.
+type Repo struct {
+	gitDir string
+
+	packsOnce sync.Once
+	packs     []*packfile
+	packsErr  error
+}
+
+// Open opens the repository at path, which may be a working tree (containing
+// a .git directory or file) or a bare repository.
+func Open(path string) (*Repo, error) {
+	gitDir := filepath.Join(path, ".git")
+	info, err := os.Stat(gitDir)
+	switch {
+	case err == nil && info.IsDir():
+	case err == nil:
+		// A .git file points to the real directory, e.g. in worktrees.
+		data, err := os.ReadFile(gitDir)
+		if err != nil {
+			return nil, err
+		}
+		target := strings.TrimSpace(strings.TrimPrefix(string(data), "gitdir:"))
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(path, target)
+		}
+		gitDir = target
+	default:
+		gitDir = path // Bare repository
+	}
+
+	if _, err := os.Stat(filepath.Join(gitDir, "objects")); err != nil {
+		return nil, fmt.Errorf("%s is not a git repository", path)
+	}
+	return &Repo{gitDir: gitDir}, nil
+}
+
+// Close releases the packfiles opened by the repository.
+func (r *Repo) Close() error {
+	var firstErr error
+	for _, p := range r.packs {
+		if err := p.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// ResolveRef resolves a reference name ("HEAD", "main", "refs/tags/v1",
+// "origin/main") or a full object ID to a commit, peeling annotated tags.
+func (r *Repo) ResolveRef(name string) (Hash, error) {
+	if name == "" {
+		name = "HEAD"
+	}
+	if h, err := ParseHash(name); err == nil {
+		return r.peel(h)
+	}
+
+	candidates := []string{name, "refs/" + name, "refs/tags/" + name, "refs/heads/" + name, "refs/remotes/" + name}
+	for _, ref := range candidates {
+		h, err := r.readRef(ref, 0)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return Hash{}, err
+		}
+		return r.peel(h)
+	}
+	return Hash{}, fmt.Errorf("reference %q: %w", name, ErrNotFound)
+}
+
+// readRef reads a loose or packed reference, following symbolic refs.
+func (r *Repo) readRef(ref string, depth int) (Hash, error) {
+	const maxSymrefDepth = 5
+	if depth > maxSymrefDepth {
+		return Hash{}, fmt.Errorf("reference %q: too many symbolic links", ref)
+	}
+
+	data, err := os.ReadFile(filepath.Join(r.gitDir, filepath.FromSlash(ref)))
+	if err == nil {
+		content := strings.TrimSpace(string(data))
+		if target, ok := strings.CutPrefix(content, "ref:"); ok {
+			return r.readRef(strings.TrimSpace(target), depth+1)
+		}
+		return ParseHash(content)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return Hash{}, err
+	}
+	return r.readPackedRef(ref)
+}
+
+func (r *Repo) readPackedRef(ref string) (Hash, error) {
+	f, err := os.Open(filepath.Join(r.gitDir, "packed-refs"))
+	if errors.Is(err, os.ErrNotExist) {
+		return Hash{}, ErrNotFound
+	}
+	if err != nil {
+		return Hash{}, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || line[0] == '#' || line[0] == '^' {
+			continue
+		}
+		hash, name, ok := strings.Cut(line, " ")
+		if ok && name == ref {
+			return ParseHash(hash)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Hash{}, err
+	}
+	return Hash{}, ErrNotFound
+}
+
+// peel follows annotated tags until it reaches a non-tag object.
+func (r *Repo) peel(h Hash) (Hash, error) {
+	for {
+		typ, data, err := r.Object(h)
+		if err != nil {
+			return Hash{}, err
+		}
+		if typ != TypeTag {
+			return h, nil
+		}
+		target, _, ok := bytes.Cut(bytes.TrimPrefix(data, []byte("object ")), []byte("\n"))
+		if !ok {
+			return Hash{}, fmt.Errorf("malformed tag %s", h)
+		}
+		if h, err = ParseHash(string(target)); err != nil {
+			return Hash{}, err
+		}
+	}
+}
+
+// Object reads an object from the loose object store or the packfiles.
+func (r *Repo) Object(h Hash) (ObjectType, []byte, error) {
+	typ, data, err := r.looseObject(h)
+	if !errors.Is(err, ErrNotFound) {
+		return typ, data, err
+	}
+
+	r.packsOnce.Do(func() { r.packs, r.packsErr = openPacks(filepath.Join(r.gitDir, "objects", "pack")) })
+	if r.packsErr != nil {
+		return 0, nil, r.packsErr
+	}
+	for _, p := range r.packs {
+		if offset, ok := p.find(h); ok {
+			return p.readAt(offset, r)
+		}
+	}
+	return 0, nil, fmt.Errorf("object %s: %w", h, ErrNotFound)
+}
+
+func (r *Repo) looseObject(h Hash) (ObjectType, []byte, error) {
+	name := h.String()
+	f, err := os.Open(filepath.Join(r.gitDir, "objects", name[:2], name[2:]))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil, ErrNotFound
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	defer f.Close()
+
+	zr, err := zlib.NewReader(f)
+	if err != nil {
+		return 0, nil, fmt.Errorf("object %s: %w", h, err)
+	}
+	defer zr.Close()
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		return 0, nil, fmt.Errorf("object %s: %w", h, err)
+	}
+
+	header, data, ok := bytes.Cut(raw, []byte{0})
+	if !ok {
+		return 0, nil, fmt.Errorf("object %s: malformed header", h)
+	}
+	typeName, _, _ := strings.Cut(string(header), " ")
+	typ, err := parseObjectType(typeName)
+	if err != nil {
+		return 0, nil, fmt.Errorf("object %s: %w", h, err)
+	}
+	return typ, data, nil
+}
@@ -0,0 +1,148 @@
+package gitrepo
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/TonyGLL/gofetch/internal/gitrepo/gittest"
+)
+
+// newTestRepo creates a repository with three commits using the git CLI.
+func newTestRepo(t *testing.T) *gittest.Repo {
+	t.Helper()
+	repo := gittest.New(t)
+	repo.Write("README.md", "# Readme\n"+strings.Repeat("line of text\n", 50))
+	repo.Write("docs/guide.md", "guide v1\n")
+	repo.Run("add", "-A")
+	repo.Run("commit", "-q", "-m", "Initial commit")
+
+	repo.Write("docs/guide.md", "guide v2\n")
+	repo.Write("docs/old.txt", "to be removed\n")
+	repo.Run("add", "-A")
+	repo.Run("commit", "-q", "-m", "Update guide\n\nWith a body.")
+	repo.Run("tag", "-a", "v1", "-m", "First release")
+
+	repo.Run("rm", "-q", "docs/old.txt")
+	repo.Write("README.md", "# Readme\n"+strings.Repeat("line of text\n", 50)+"one more line\n")
+	repo.Run("add", "-A")
+	repo.Run("commit", "-q", "-m", "Remove old notes")
+	return repo
+}
+
+func TestRepo_ReadsLooseAndPackedObjects(t *testing.T) {
+	fixture := newTestRepo(t)
+
+	for _, packed := range []bool{false, true} {
+		if packed {
+			fixture.Run("gc", "-q", "--aggressive")
+		}
+
+		repo, err := Open(fixture.Dir)
+		if err != nil {
+			t.Fatalf("Open returned an error: %v", err)
+		}
+
+		head, err := repo.ResolveRef("HEAD")
+		if err != nil {
+			t.Fatalf("ResolveRef(HEAD) returned an error: %v", err)
+		}
+		commit, err := repo.Commit(head)
+		if err != nil {
+			t.Fatalf("Commit returned an error: %v", err)
+		}
+		if commit.Subject() != "Remove old notes" || commit.Author.Email != "ana@example.com" {
+			t.Errorf("Unexpected head commit: %+v", commit)
+		}
+
+		files, err := repo.Files(commit.Tree)
+		if err != nil {
+			t.Fatalf("Files returned an error: %v", err)
+		}
+		var paths []string
+		for p := range files {
+			paths = append(paths, p)
+		}
+		if len(paths) != 2 || files["docs/guide.md"].IsZero() || files["README.md"].IsZero() {
+			t.Errorf("Unexpected files at HEAD: %v", paths)
+		}
+		readme, err := repo.Blob(files["README.md"])
+		if err != nil || !strings.HasSuffix(string(readme), "one more line\n") {
+			t.Errorf("Unexpected README content (err %v): %q", err, readme)
+		}
+
+		tagged, err := repo.ResolveRef("v1")
+		if err != nil {
+			t.Fatalf("ResolveRef(v1) returned an error: %v", err)
+		}
+		taggedCommit, err := repo.Commit(tagged)
+		if err != nil {
+			t.Fatalf("Commit returned an error: %v", err)
+		}
+		changes, err := repo.DiffTrees(taggedCommit.Tree, commit.Tree)
+		if err != nil {
+			t.Fatalf("DiffTrees returned an error: %v", err)
+		}
+		expected := []Change{
+			{Type: Modified, Path: "README.md", Hash: files["README.md"]},
+			{Type: Deleted, Path: "docs/old.txt"},
+		}
+		if len(changes) == 2 {
+			expected[1].Hash = changes[1].Hash
+		}
+		if !reflect.DeepEqual(changes, expected) {
+			t.Errorf("Expected changes %+v, but got %+v", expected, changes)
+		}
+
+		last, err := repo.LastCommits(head, []string{"README.md", "docs/guide.md"}, 0)
+		if err != nil {
+			t.Fatalf("LastCommits returned an error: %v", err)
+		}
+		if last["README.md"].Hash != head || last["docs/guide.md"].Hash != tagged {
+			t.Errorf("Unexpected last commits: README %s, guide %s", last["README.md"].Hash, last["docs/guide.md"].Hash)
+		}
+		repo.Close()
+	}
+}
+
+func TestRepo_DiffTreesDeletesPathsReplacedBySubmodules(t *testing.T) {
+	fixture := newTestRepo(t)
+	fixture.Run("rm", "-q", "-r", "README.md", "docs")
+	for _, p := range []string{"README.md", "docs"} {
+		fixture.Run("update-index", "--add", "--cacheinfo", "160000,1111111111111111111111111111111111111111,"+p)
+	}
+	fixture.Run("commit", "-q", "-m", "Replace files with submodules")
+
+	repo, err := Open(fixture.Dir)
+	if err != nil {
+		t.Fatalf("Open returned an error: %v", err)
+	}
+	defer repo.Close()
+	head, err := repo.ResolveRef("HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit, err := repo.Commit(head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parent, err := repo.Commit(commit.Parents[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes, err := repo.DiffTrees(parent.Tree, commit.Tree)
+	if err != nil {
+		t.Fatalf("DiffTrees returned an error: %v", err)
+	}
+
+	var deleted []string
+	for _, c := range changes {
+		if c.Type != Deleted {
+			t.Errorf("Expected only deletions, got %+v", c)
+		}
+		deleted = append(deleted, c.Path)
+	}
+	if !reflect.DeepEqual(deleted, []string{"README.md", "docs/guide.md"}) {
+		t.Errorf("Expected the replaced file and directory to be deleted, got %v", deleted)
+	}
+}
@@ -0,0 +1,44 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/TonyGLL/gofetch/pkg/storage"
+)
+
+// RecordAnalyzers saves the definition of every configured analyzer in the
+// index. It warns about analyzers whose definition changed since they were
+// last recorded: documents they built keep the old terms until re-indexed.
+func (idx *Indexer) RecordAnalyzers(ctx context.Context) error {
+	stored, err := idx.mongo_store.GetAnalyzers(ctx)
+	if err != nil {
+		return fmt.Errorf("error reading recorded analyzers: %w", err)
+	}
+	previous := make(map[string]string, len(stored))
+	for _, record := range stored {
+		previous[record.Name] = record.Definition
+	}
+
+	definitions := idx.analyzer.Definitions()
+	names := make([]string, 0, len(definitions))
+	for name := range definitions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	records := make([]storage.AnalyzerRecord, 0, len(names))
+	for _, name := range names {
+		definition := definitions[name]
+		if old, ok := previous[name]; ok && old != definition {
+			fmt.Fprintf(os.Stderr, "warning: analyzer %s changed from %q to %q; re-index the documents it analyzed\n", name, old, definition)
+		}
+		records = append(records, storage.AnalyzerRecord{Name: name, Definition: definition})
+	}
+	if err := idx.mongo_store.SaveAnalyzers(ctx, records); err != nil {
+		return fmt.Errorf("error recording analyzers: %w", err)
+	}
+	return nil
+}
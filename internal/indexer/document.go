@@ -0,0 +1,297 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TonyGLL/gofetch/pkg/storage"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrInvalidDocument is returned when a DocumentInput cannot be indexed.
+var ErrInvalidDocument = errors.New("invalid document")
+
+// ErrConflict is returned when a document is upserted by another request
+// while it is being written.
+var ErrConflict = errors.New("document was written concurrently")
+
+// Status values reported for every document passed to IndexDocuments.
+const (
+	StatusCreated = "created"
+	StatusUpdated = "updated"
+	StatusError   = "error"
+)
+
+// DocumentInput is a document pushed by an external system. Documents are
+// upserted by ID, or by URL when no ID is given.
+type DocumentInput struct {
+	ID         string            `json:"id"`
+	URL        string            `json:"url"`
+	Title      string            `json:"title"`
+	Body       string            `json:"body"`
+	Headings   []string          `json:"headings,omitempty"` // Section titles, indexed as their own field
+	Fields     map[string]string `json:"fields,omitempty"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+	Language   string            `json:"language,omitempty"`    // Detected from the text when empty
+	Analyzer   string            `json:"analyzer,omitempty"`    // Named analyzer to use instead of the language's
+	Label      string            `json:"label,omitempty"`       // Source label used for filtering and facets
+	SourceType string            `json:"source_type,omitempty"` // Defaults to "api"
+	ModifiedAt time.Time         `json:"modified_at,omitempty"`
+}
+
+// key returns the external ID used for upserts.
+func (in *DocumentInput) key() string {
+	if in.ID != "" {
+		return in.ID
+	}
+	return in.URL
+}
+
+// validate checks that the input has an identity and some text to index,
+// and that its custom fields do not take the name of a standard field.
+func (in *DocumentInput) validate() error {
+	if in.key() == "" {
+		return fmt.Errorf("%w: an id or url is required", ErrInvalidDocument)
+	}
+	if strings.TrimSpace(in.Title) == "" && strings.TrimSpace(in.Body) == "" {
+		return fmt.Errorf("%w: %s has no title or body", ErrInvalidDocument, in.key())
+	}
+	for name := range in.Fields {
+		if storage.IsStandardField(fieldName(name)) {
+			return fmt.Errorf("%w: %s has a custom field named %q, which is reserved", ErrInvalidDocument, in.key(), name)
+		}
+	}
+	return nil
+}
+
+// IndexResult reports what happened to one DocumentInput.
+type IndexResult struct {
+	ID     string `json:"id"`
+	DocID  string `json:"doc_id,omitempty"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// IndexDocument indexes a single document, replacing any previous version
+// with the same ID.
+func (idx *Indexer) IndexDocument(ctx context.Context, input DocumentInput) (IndexResult, error) {
+	results, err := idx.IndexDocuments(ctx, []DocumentInput{input})
+	result := results[0]
+	if err != nil {
+		return result, err
+	}
+	if result.Status == StatusError {
+		return result, fmt.Errorf("%w: %s", ErrInvalidDocument, result.Error)
+	}
+	return result, nil
+}
+
+// IndexDocuments analyzes a batch of documents concurrently and writes them
+// with a single bulk operation. Every input gets an IndexResult: invalid inputs and
+// documents that could not be written are reported there without failing the
+// others, and the returned error is set when any document could not be
+// written. The previous version of a document is only removed once the new
+// one is written. When the same ID appears more than once, the last input wins.
+func (idx *Indexer) IndexDocuments(ctx context.Context, inputs []DocumentInput) ([]IndexResult, error) {
+	results := make([]IndexResult, len(inputs))
+	latest := make(map[string]int, len(inputs))
+	for i := range inputs {
+		results[i] = IndexResult{ID: inputs[i].key()}
+		if err := inputs[i].validate(); err != nil {
+			results[i].Status = StatusError
+			results[i].Error = err.Error()
+			continue
+		}
+		latest[inputs[i].key()] = i
+	}
+
+	// Process inputs in their original order so results and logs are stable.
+	order := make([]int, 0, len(latest))
+	for _, i := range latest {
+		order = append(order, i)
+	}
+	sort.Ints(order)
+
+	var failed []error
+	batch := make([]*indexPayload, 0, len(order))
+	batchInputs := make([]int, 0, len(order)) // Index in inputs of every payload
+	for n, prepared := range idx.prepareDocuments(ctx, inputs, order) {
+		i := order[n]
+		if prepared.err != nil {
+			results[i].Status = StatusError
+			results[i].Error = prepared.err.Error()
+			failed = append(failed, prepared.err)
+			continue
+		}
+		results[i].DocID = prepared.payload.Doc.ID.Hex()
+		results[i].Status = prepared.status
+		batch = append(batch, prepared.payload)
+		batchInputs = append(batchInputs, i)
+	}
+	for i := range inputs {
+		if results[i].Status == "" {
+			results[i].Status = StatusError
+			results[i].Error = "superseded by a later document with the same id"
+		}
+	}
+
+	written := int64(0)
+	for n, err := range idx.writeDocuments(ctx, batch) {
+		i := batchInputs[n]
+		if err != nil {
+			results[i] = IndexResult{ID: results[i].ID, Status: StatusError, Error: err.Error()}
+			failed = append(failed, err)
+			continue
+		}
+		written++
+	}
+	if written > 0 {
+		if err := idx.mongo_store.UpdateIndexStats(ctx, written); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to update index stats: %v\n", err)
+		}
+	}
+	return results, errors.Join(failed...)
+}
+
+// writeDocuments writes a batch of document payloads and removes the versions
+// they replace. It returns the error of every payload, nil when it was
+// written. When the bulk write fails, what it wrote is discarded and the
+// payloads are written one at a time, so a failing document does not fail
+// the others.
+func (idx *Indexer) writeDocuments(ctx context.Context, batch []*indexPayload) []error {
+	errs := make([]error, len(batch))
+	err := idx.writeBatch(ctx, batch)
+	if err == nil {
+		for _, payload := range batch {
+			idx.removeReplaced(ctx, payload)
+		}
+		return errs
+	}
+
+	if discardErr := idx.discardPayloads(ctx, batch); discardErr != nil {
+		// What was written is unknown, so the payloads cannot be retried.
+		err = fmt.Errorf("%w; discarding the partial write failed: %v", err, discardErr)
+	} else if len(batch) > 1 {
+		for i := range batch {
+			errs[i] = idx.writeDocuments(ctx, batch[i:i+1])[0]
+		}
+		return errs
+	}
+	for i, payload := range batch {
+		if mongo.IsDuplicateKeyError(err) {
+			errs[i] = fmt.Errorf("%w: %s", ErrConflict, payload.Doc.ExternalID)
+		} else {
+			errs[i] = fmt.Errorf("error writing document %s: %w", payload.Doc.ExternalID, err)
+		}
+	}
+	return errs
+}
+
+// preparedDocument is the payload of an input, or why it could not be built.
+type preparedDocument struct {
+	payload *indexPayload
+	status  string
+	err     error
+}
+
+// prepareDocuments prepares the inputs at the given indexes concurrently, and
+// returns them in the same order.
+func (idx *Indexer) prepareDocuments(ctx context.Context, inputs []DocumentInput, order []int) []preparedDocument {
+	prepared := make([]preparedDocument, len(order))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	workerCount := min(runtime.NumCPU(), len(order))
+	wg.Add(workerCount)
+	for range workerCount {
+		go func() {
+			defer wg.Done()
+			for n := range jobs {
+				p := &prepared[n]
+				p.payload, p.status, p.err = idx.prepareDocument(ctx, &inputs[order[n]])
+			}
+		}()
+	}
+	for n := range order {
+		jobs <- n
+	}
+	close(jobs)
+	wg.Wait()
+	return prepared
+}
+
+// prepareDocument builds the payload for the new version of the input. The
+// versions it replaces are recorded on the payload, to be removed once it is
+// written.
+func (idx *Indexer) prepareDocument(ctx context.Context, input *DocumentInput) (*indexPayload, string, error) {
+	status := StatusCreated
+	versions, err := idx.mongo_store.GetDocumentsByExternalID(ctx, input.key())
+	if err != nil {
+		return nil, "", fmt.Errorf("error checking existing document %s: %w", input.key(), err)
+	}
+	version := 1
+	if len(versions) > 0 {
+		version = versions[0].Version + 1
+		status = StatusUpdated
+	}
+
+	lang := strings.ToLower(input.Language) // Filtered on as stored
+	if lang == "" {
+		lang = idx.analyzer.Detect(input.Title + "\n" + input.Body)
+	}
+
+	sourceType := input.SourceType
+	if sourceType == "" {
+		sourceType = "api"
+	}
+	modifiedAt := input.ModifiedAt
+	if modifiedAt.IsZero() {
+		modifiedAt = time.Now()
+	}
+
+	document := storage.Document{
+		ID:         primitive.NewObjectID(),
+		SourceType: sourceType,
+		URL:        input.URL,
+		Title:      strings.TrimSpace(input.Title),
+		Content:    input.Body,
+		IndexedAt:  time.Now(),
+		ModifiedAt: modifiedAt,
+		Language:   lang,
+		Label:      input.Label,
+		ExternalID: input.key(),
+		Version:    version,
+		Fields:     input.Fields,
+		Metadata:   input.Metadata,
+	}
+	analyzerName := input.Analyzer
+	if analyzerName == "" {
+		analyzerName = lang
+	}
+	payload := idx.buildPayload(&document, analyzerName, input.Headings)
+	payload.Replaces = versions
+	return payload, status, nil
+}
+
+// DeleteDocument removes the document with the given external ID, together
+// with its postings and passages. It reports whether a document was found.
+func (idx *Indexer) DeleteDocument(ctx context.Context, id string) (bool, error) {
+	versions, err := idx.mongo_store.GetDocumentsByExternalID(ctx, id)
+	if err != nil {
+		return false, fmt.Errorf("error finding document %s: %w", id, err)
+	}
+	for _, version := range versions {
+		if err := idx.removeDocument(ctx, version); err != nil {
+			return false, fmt.Errorf("error deleting document %s: %w", id, err)
+		}
+	}
+	return len(versions) > 0, nil
+}
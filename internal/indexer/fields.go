@@ -0,0 +1,211 @@
+package indexer
+
+import (
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/TonyGLL/gofetch/internal/analysis"
+	"github.com/TonyGLL/gofetch/pkg/storage"
+)
+
+// fieldText is the text of one field of a document and the analyzer it is
+// indexed with.
+type fieldText struct {
+	field    string
+	analyzer *analysis.Analyzer
+	text     string
+}
+
+// documentFields returns the text of every field of a document with the
+// named analyzer, or the analyzer assigned to the field. The comments and
+// string literals of source code are analyzed as prose, in their own fields.
+// Several texts may share a field, whose positions then follow each other.
+func (idx *Indexer) documentFields(analyzerName string, doc *storage.Document, headings []string) []fieldText {
+	text := func(field, text string) fieldText {
+		return fieldText{field: field, analyzer: idx.analyzer.ForField(field, analyzerName), text: text}
+	}
+
+	body := doc.Content
+	var code []fieldText
+	if doc.Language == analysis.CodeLanguage {
+		parts := analysis.SplitCode(doc.Content, analysis.CodeSyntaxFor(documentPath(doc)))
+		body = parts.Code
+		prose := idx.analyzer.Detect(parts.Comments)
+		code = []fieldText{
+			{field: storage.FieldComments, analyzer: idx.analyzer.ForField(storage.FieldComments, prose), text: parts.Comments},
+			{field: storage.FieldStrings, analyzer: idx.analyzer.ForField(storage.FieldStrings, prose), text: parts.Strings},
+		}
+	}
+
+	fields := []fieldText{
+		text(storage.FieldBody, body),
+		text(storage.FieldTitle, doc.Title),
+		text(storage.FieldHeadings, strings.Join(headings, "\n")),
+		text(storage.FieldURL, doc.URL),
+	}
+	fields = append(fields, code...)
+	if idx.analyzer.FieldAnalyzer(storage.FieldPrefix) != "" {
+		fields = append(fields, text(storage.FieldPrefix, doc.Title+"\n"+body))
+	}
+	if idx.analyzer.FieldAnalyzer(storage.FieldPhonetic) != "" {
+		fields = append(fields, text(storage.FieldPhonetic, doc.Title+"\n"+body))
+	}
+	// Custom fields are indexed under their own names, to be searched on
+	// their own, and together in the custom field searched by default.
+	names := make([]string, 0, len(doc.Fields))
+	for name := range doc.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := doc.Fields[name]
+		name = fieldName(name)
+		if name == "" || storage.IsStandardField(name) {
+			continue // Would mix with the terms of a standard field
+		}
+		fields = append(fields, text(name, value), text(storage.FieldCustom, value))
+	}
+	return fields
+}
+
+// termCounts collects the terms of a document from the token streams of its
+// fields, to count the frequency and positions of each one.
+type termCounts struct {
+	terms  []string                  // Distinct terms, keyed with storage.FieldTerm
+	index  map[string]map[string]int // Index in terms of every term, by field
+	tokens []termPosition            // Every token, in order
+	next   map[string]int            // Position after the last token of every field
+}
+
+// termPosition is the position of a token of terms[term].
+type termPosition struct {
+	term     int32
+	position int32
+}
+
+func newTermCounts() *termCounts {
+	return &termCounts{index: make(map[string]map[string]int), next: make(map[string]int)}
+}
+
+// add analyzes the text of a field a chunk at a time and records its terms,
+// after the positions of any text added to the field before.
+func (c *termCounts) add(f fieldText) {
+	offset, next := c.next[f.field], 0
+	terms := c.index[f.field]
+	if terms == nil {
+		terms = make(map[string]int)
+		c.index[f.field] = terms
+	}
+	c.tokens = slices.Grow(c.tokens, len(f.text)/6) // Prose averages a word every six bytes or so
+	for stream := f.analyzer.StreamString(f.text); stream.Next(); {
+		token := stream.Token()
+		if token.Term == "" {
+			continue
+		}
+		term, ok := terms[token.Term]
+		if !ok {
+			term = len(c.terms)
+			terms[token.Term] = term
+			c.terms = append(c.terms, storage.FieldTerm(f.field, token.Term))
+		}
+		c.tokens = append(c.tokens, termPosition{term: int32(term), position: int32(offset + token.Position)})
+		next = offset + token.Position + 1
+	}
+	if next > 0 {
+		c.next[f.field] = next
+	}
+}
+
+// count returns the frequency and positions of every term. The positions of
+// all terms share one array.
+func (c *termCounts) count() (map[string]int, map[string][]int) {
+	perTerm := make([]int, len(c.terms))
+	for _, token := range c.tokens {
+		perTerm[token.term]++
+	}
+	all := make([]int, len(c.tokens))
+	positions := make([][]int, len(c.terms))
+	start := 0
+	for term, n := range perTerm {
+		positions[term] = all[start : start : start+n]
+		start += n
+	}
+	for _, token := range c.tokens {
+		positions[token.term] = append(positions[token.term], int(token.position))
+	}
+
+	freqs := make(map[string]int, len(c.terms))
+	byKey := make(map[string][]int, len(c.terms))
+	for term, key := range c.terms {
+		freqs[key] = perTerm[term]
+		byKey[key] = positions[term]
+	}
+	return freqs, byKey
+}
+
+// documentPath returns the file path of a document, for documents that have
+// one: local files, git files, or pushed documents whose URL names a file.
+func documentPath(doc *storage.Document) string {
+	if doc.FilePath != "" {
+		return doc.FilePath
+	}
+	if p := doc.Metadata["path"]; p != "" {
+		return p
+	}
+	return doc.URL
+}
+
+// codeLanguage returns the code analyzer language for recognised source
+// files, and lang in lowercase for anything else.
+func codeLanguage(path, lang string) string {
+	if analysis.IsCodeFile(path) {
+		return analysis.CodeLanguage
+	}
+	return strings.ToLower(lang)
+}
+
+// fieldName normalizes a custom field name so it can be used as an index key prefix.
+func fieldName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == ':' || r == ' ' {
+			return '_'
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(name)))
+}
+
+// extractTitle returns the first non-empty line of a text, or fallback
+// (usually the file name) when the text is blank.
+func extractTitle(text, fallback string) string {
+	for _, line := range strings.Split(text, "\n") {
+		trimmedLine := strings.TrimSpace(line)
+		if trimmedLine != "" {
+			return trimmedLine
+		}
+	}
+	return fallback
+}
+
+// markdownHeadings returns the text of the ATX headings ("# Title") in a
+// markdown document.
+func markdownHeadings(text string) []string {
+	var headings []string
+	inFence := false
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inFence = !inFence
+			continue
+		}
+		if inFence || !strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		heading := strings.TrimLeft(trimmed, "#")
+		if heading == "" || heading[0] != ' ' {
+			continue // Not a heading, e.g. "#hashtag"
+		}
+		headings = append(headings, strings.TrimSpace(strings.TrimRight(heading, "# ")))
+	}
+	return headings
+}
@@ -0,0 +1,189 @@
+package indexer
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/TonyGLL/gofetch/internal/analysis"
+	"github.com/TonyGLL/gofetch/pkg/storage"
+)
+
+func TestAnalyzeFields_SourceCode(t *testing.T) {
+	idx := NewIndexer(analysis.NewMultiAnalyzer(analysis.NewEnglishAnalyzer(), nil, analysis.NewCodeAnalyzer()), nil)
+	doc := &storage.Document{
+		FilePath: "pkg/storage/mongo_store.go",
+		Language: analysis.CodeLanguage,
+		Content:  "// GetPostingsForTerms retrieves the postings.\nfunc (s *MongoStore) GetPostingsForTerms() { log(\"fetching postings\") }\n",
+	}
+
+	lang := codeLanguage(doc.FilePath, "")
+	if lang != analysis.CodeLanguage {
+		t.Fatalf("Expected a .go file to use the code analyzer, got %q", lang)
+	}
+	fields := fieldTerms(idx, lang, doc)
+
+	if !slices.Contains(fields[storage.FieldBody], "getpostingsforterms") || !slices.Contains(fields[storage.FieldBody], "mongo") {
+		t.Errorf("Expected identifier tokens in the body, got %v", fields[storage.FieldBody])
+	}
+	if slices.Contains(fields[storage.FieldBody], "retrieves") || slices.Contains(fields[storage.FieldBody], "fetching") {
+		t.Errorf("Expected comments and strings to be left out of the body, got %v", fields[storage.FieldBody])
+	}
+	if !slices.Contains(fields[storage.FieldComments], "retriev") {
+		t.Errorf("Expected stemmed comment terms, got %v", fields[storage.FieldComments])
+	}
+	if !slices.Contains(fields[storage.FieldStrings], "fetch") {
+		t.Errorf("Expected stemmed string terms, got %v", fields[storage.FieldStrings])
+	}
+	if codeLanguage("notes.md", "spanish") != "spanish" {
+		t.Error("Expected non-code files to keep their language")
+	}
+}
+
+func TestAnalyzeFields_Prefixes(t *testing.T) {
+	multi := analysis.NewMultiAnalyzer(analysis.NewEnglishAnalyzer(), nil)
+	idx := NewIndexer(multi, nil)
+	doc := &storage.Document{Title: "Indexer", Content: "Builds the inverted index."}
+	if _, ok := fieldTerms(idx, "english", doc)[storage.FieldPrefix]; ok {
+		t.Error("Expected no prefix field unless prefixes are enabled")
+	}
+
+	index, search := analysis.NewPrefixAnalyzers(0, 0)
+	multi.Register(index)
+	multi.Register(search)
+	if err := multi.SetFieldAnalyzer(storage.FieldPrefix, index.Name()); err != nil {
+		t.Fatal(err)
+	}
+	prefixes := fieldTerms(idx, "english", doc)[storage.FieldPrefix]
+	for _, want := range []string{"in", "index", "indexer", "inverted"} {
+		if !slices.Contains(prefixes, want) {
+			t.Errorf("Expected prefix %q from the title and body, got %v", want, prefixes)
+		}
+	}
+}
+
+func TestAnalyzeFields_Phonetic(t *testing.T) {
+	multi := analysis.NewMultiAnalyzer(analysis.NewEnglishAnalyzer(), nil, analysis.NewSpanishAnalyzer())
+	multi.Register(analysis.NewPhoneticAnalyzer("english"))
+	multi.Register(analysis.NewPhoneticAnalyzer("spanish"))
+	if err := multi.SetFieldAnalyzer(storage.FieldPhonetic, analysis.PhoneticAnalyzer); err != nil {
+		t.Fatal(err)
+	}
+	if err := multi.SetFieldLanguageAnalyzer(storage.FieldPhonetic, "spanish", analysis.PhoneticSpanishAnalyzer); err != nil {
+		t.Fatal(err)
+	}
+	idx := NewIndexer(multi, nil)
+	doc := &storage.Document{Title: "Ficha", Content: "Vázquez"}
+	if codes := fieldTerms(idx, "spanish", doc)[storage.FieldPhonetic]; !slices.Equal(codes, []string{"FX", "BSKS"}) {
+		t.Errorf("Expected the Spanish codes of the title and body, got %v", codes)
+	}
+
+	payload := idx.buildPayload(doc, "spanish", nil)
+	if name := payload.Doc.FieldAnalyzers[storage.FieldPhonetic]; name != analysis.PhoneticSpanishAnalyzer {
+		t.Errorf("Expected the language analyzer to be recorded, got %q", name)
+	}
+}
+
+func TestAnalyzeFields_CustomFields(t *testing.T) {
+	idx := NewIndexer(analysis.NewMultiAnalyzer(analysis.NewEnglishAnalyzer(), nil), nil)
+	doc := &storage.Document{
+		Content: "Ticket body.",
+		Fields:  map[string]string{"Summary": "Crawler ignores robots", "status": "open"},
+	}
+	fields := fieldTerms(idx, "english", doc)
+	if got := fields["summary"]; !slices.Equal(got, []string{"crawler", "ignor", "robot"}) {
+		t.Errorf("Expected the summary under its own name, got %v", got)
+	}
+	if got := fields[storage.FieldCustom]; !slices.Equal(got, []string{"crawler", "ignor", "robot", "open"}) {
+		t.Errorf("Expected every custom field in the custom field, got %v", got)
+	}
+
+	payload := idx.buildPayload(doc, "english", nil)
+	if got := payload.Positions[storage.FieldTerm(storage.FieldCustom, "open")]; !slices.Equal(got, []int{3}) {
+		t.Errorf("Expected custom field positions to follow each other, got %v", got)
+	}
+	if got := payload.Freqs[storage.FieldTerm("summary", "robot")]; got != 1 {
+		t.Errorf("Expected the summary terms to be counted, got %d", got)
+	}
+}
+
+func TestAnalyzeFields_CustomFieldsKeepOutOfStandardFields(t *testing.T) {
+	idx := NewIndexer(analysis.NewMultiAnalyzer(analysis.NewEnglishAnalyzer(), nil), nil)
+	doc := &storage.Document{
+		Title:  "Robots",
+		Fields: map[string]string{"Title": "Sitemap", "url": "sitemap", "prefix": "sitemap"},
+	}
+	fields := fieldTerms(idx, "english", doc)
+	if got := fields[storage.FieldTitle]; !slices.Equal(got, []string{"robot"}) {
+		t.Errorf("Expected only the document title in the title field, got %v", got)
+	}
+	for _, field := range []string{storage.FieldURL, storage.FieldPrefix, storage.FieldCustom} {
+		if got := fields[field]; len(got) > 0 {
+			t.Errorf("Expected no custom field terms in %s, got %v", field, got)
+		}
+	}
+
+	for _, name := range []string{"title", " Passage", "comments", "url", "body", "custom"} {
+		input := DocumentInput{ID: "42", Body: "Ticket body.", Fields: map[string]string{name: "x"}}
+		if err := input.validate(); !errors.Is(err, ErrInvalidDocument) {
+			t.Errorf("Expected a custom field named %q to be rejected, got %v", name, err)
+		}
+	}
+	input := DocumentInput{ID: "42", Body: "Ticket body.", Fields: map[string]string{"titles": "x"}}
+	if err := input.validate(); err != nil {
+		t.Errorf("Expected a custom field named titles to be accepted, got %v", err)
+	}
+}
+
+func TestAnalyzeFields_URL(t *testing.T) {
+	multi := analysis.NewMultiAnalyzer(analysis.NewEnglishAnalyzer(), nil)
+	multi.Register(analysis.NewURLAnalyzer())
+	if err := multi.SetFieldAnalyzer(storage.FieldURL, analysis.URLAnalyzer); err != nil {
+		t.Fatal(err)
+	}
+	idx := NewIndexer(multi, nil)
+	pages := map[string]*storage.Document{
+		"effective": {SourceType: "web", URL: "https://go.dev/doc/effective_go", Title: "Effective Go"},
+		"english":   {SourceType: "file", URL: "data/plan_english.txt", Content: "A plan."},
+	}
+	for segment, doc := range pages {
+		payload := idx.buildPayload(doc, "english", nil)
+		// What a url:<segment> query looks up.
+		terms := multi.AnalyzeFieldQuery(storage.FieldURL, segment, "english")
+		if len(terms) == 0 {
+			t.Fatalf("Expected url:%s to have terms", segment)
+		}
+		for _, term := range terms {
+			if payload.Freqs[storage.FieldTerm(storage.FieldURL, term)] == 0 {
+				t.Errorf("Expected url:%s to find %s, indexed url terms: %v", segment, doc.URL, fieldTerms(idx, "english", doc)[storage.FieldURL])
+			}
+		}
+	}
+}
+
+// fieldTerms returns the terms of every field of a document, in order.
+func fieldTerms(idx *Indexer, analyzerName string, doc *storage.Document) map[string][]string {
+	fields := make(map[string][]string)
+	for _, field := range idx.documentFields(analyzerName, doc, nil) {
+		for stream := field.analyzer.StreamString(field.text); stream.Next(); {
+			fields[field.field] = append(fields[field.field], stream.Token().Term)
+		}
+	}
+	return fields
+}
+
+func BenchmarkBuildPayload(b *testing.B) {
+	idx := NewIndexer(analysis.NewMultiAnalyzer(analysis.NewEnglishAnalyzer(), nil), nil)
+	content := strings.Repeat(`The indexer walks every configured source, reads each file and analyzes
+its title and body before writing the postings to MongoDB. Running the
+analyzer over thousands of documents must stay fast: 1,250 files of 12.5 KB
+each are indexed in well under a minute on a laptop.
+`, 64)
+	doc := &storage.Document{Title: "Indexing", URL: "docs/indexing.md", Content: content}
+	b.SetBytes(int64(len(content)))
+	b.ReportAllocs()
+	for b.Loop() {
+		idx.buildPayload(doc, "english", []string{"Indexing"})
+	}
+}
@@ -0,0 +1,250 @@
+package indexer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/TonyGLL/gofetch/internal/analysis"
+	"github.com/TonyGLL/gofetch/internal/gitrepo"
+	"github.com/TonyGLL/gofetch/pkg/storage"
+)
+
+// SourceTypeGitCommit is the document source type of indexed commit messages.
+// Files keep SourceTypeGit.
+const SourceTypeGitCommit = "git-commit"
+
+const (
+	gitBatchSize       = 100
+	gitMaxBlobSize     = 1 << 20 // Larger files are skipped
+	gitBinaryProbeSize = 8000    // Bytes inspected for NUL, like git itself
+	gitHistoryLimit    = 1000    // Commits examined to find each file's last commit
+)
+
+// indexGitSource indexes the files of a repository at the source ref. The last
+// indexed commit is saved as the source state, so later runs only re-index the
+// files changed since then. When that commit is gone, the source is indexed
+// again in full and its documents missing from the ref are removed.
+func (idx *Indexer) indexGitSource(ctx context.Context, source *Source) error {
+	repo, err := gitrepo.Open(source.Root)
+	if err != nil {
+		return err
+	}
+	defer repo.Close()
+
+	ref := source.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+	head, err := repo.ResolveRef(ref)
+	if err != nil {
+		return fmt.Errorf("error resolving %s: %w", ref, err)
+	}
+	headCommit, err := repo.Commit(head)
+	if err != nil {
+		return err
+	}
+
+	stateID := "git:" + source.label() + ":" + ref
+	state, err := idx.mongo_store.GetSourceState(ctx, stateID)
+	if err != nil {
+		return fmt.Errorf("error loading source state: %w", err)
+	}
+
+	var since gitrepo.Hash
+	if state != nil {
+		if since, err = gitrepo.ParseHash(state.Cursor); err != nil {
+			return fmt.Errorf("invalid cursor in source state %s: %w", stateID, err)
+		}
+	}
+	if since == head {
+		fmt.Printf("Git source %s is up to date at %s\n", source.label(), head)
+		return nil
+	}
+
+	var sinceTree gitrepo.Hash
+	if !since.IsZero() {
+		sinceCommit, err := repo.Commit(since)
+		if err != nil {
+			// History was rewritten or the object was pruned: index from scratch.
+			fmt.Fprintf(os.Stderr, "warning: last indexed commit %s not found, re-indexing %s\n", since, source.label())
+			since = gitrepo.Hash{}
+		} else {
+			sinceTree = sinceCommit.Tree
+		}
+	}
+
+	changes, err := repo.DiffTrees(sinceTree, headCommit.Tree)
+	if err != nil {
+		return fmt.Errorf("error diffing trees: %w", err)
+	}
+	inputs, deleted, err := gitDocuments(repo, source, ref, headCommit, changes)
+	if err != nil {
+		return err
+	}
+	if source.Commits {
+		commits, err := repo.Log(head, since, 0)
+		if err != nil {
+			return fmt.Errorf("error reading commit log: %w", err)
+		}
+		for _, c := range commits {
+			inputs = append(inputs, gitCommitDocument(source, ref, c))
+		}
+	}
+
+	if sinceTree.IsZero() {
+		// A full index only lists what the ref has now: the documents of
+		// files and commits indexed before, e.g. before history was
+		// rewritten, are stale.
+		existing, err := idx.mongo_store.GetExternalIDs(ctx, gitSourcePrefix(source))
+		if err != nil {
+			return fmt.Errorf("error listing indexed documents: %w", err)
+		}
+		deleted = append(deleted, staleGitDocuments(existing, inputs, deleted)...)
+	}
+
+	for start := 0; start < len(inputs); start += gitBatchSize {
+		end := min(start+gitBatchSize, len(inputs))
+		if _, err := idx.IndexDocuments(ctx, inputs[start:end]); err != nil {
+			return err
+		}
+	}
+	for _, id := range deleted {
+		if _, err := idx.DeleteDocument(ctx, id); err != nil {
+			return err
+		}
+	}
+
+	if err := idx.mongo_store.SaveSourceState(ctx, storage.SourceState{ID: stateID, Cursor: head.String()}); err != nil {
+		return fmt.Errorf("error saving source state: %w", err)
+	}
+	fmt.Printf("Git source %s: %d documents indexed, %d removed at %s\n", source.label(), len(inputs), len(deleted), head)
+	return nil
+}
+
+// gitDocuments builds the documents for the files added or modified by
+// changes, and returns the IDs of the documents whose files were deleted.
+func gitDocuments(repo *gitrepo.Repo, source *Source, ref string, head *gitrepo.Commit, changes []gitrepo.Change) ([]DocumentInput, []string, error) {
+	var deleted, paths []string
+	for _, change := range changes {
+		if !source.includes(change.Path) {
+			continue
+		}
+		if change.Type == gitrepo.Deleted {
+			deleted = append(deleted, gitFileID(source, change.Path))
+			continue
+		}
+		paths = append(paths, change.Path)
+	}
+
+	lastCommits, err := repo.LastCommits(head.Hash, paths, gitHistoryLimit)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading file history: %w", err)
+	}
+
+	inputs := make([]DocumentInput, 0, len(paths))
+	for _, change := range changes {
+		if change.Type == gitrepo.Deleted || !source.includes(change.Path) {
+			continue
+		}
+		content, err := repo.Blob(change.Hash)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading %s: %w", change.Path, err)
+		}
+		if len(content) > gitMaxBlobSize || bytes.IndexByte(content[:min(len(content), gitBinaryProbeSize)], 0) >= 0 {
+			// The file may have been indexed before it grew or became binary.
+			deleted = append(deleted, gitFileID(source, change.Path))
+			continue
+		}
+
+		text := string(content)
+		lang := codeLanguage(change.Path, source.Language)
+		title := extractTitle(text, path.Base(change.Path))
+		if lang == analysis.CodeLanguage {
+			title = path.Base(change.Path)
+		}
+		commit := lastCommits[change.Path]
+		if commit == nil {
+			commit = head // Changed further back than the history limit
+		}
+		input := DocumentInput{
+			ID:         gitFileID(source, change.Path),
+			Title:      title,
+			Body:       text,
+			Language:   lang,
+			Label:      source.label(),
+			SourceType: SourceTypeGit,
+			ModifiedAt: commit.Committer.When,
+			Metadata: map[string]string{
+				"repo":         source.Root,
+				"ref":          ref,
+				"path":         change.Path,
+				"commit":       commit.Hash.String(),
+				"author":       commit.Author.Name,
+				"author_email": commit.Author.Email,
+				"commit_date":  commit.Committer.When.Format(time.RFC3339),
+			},
+		}
+		if strings.EqualFold(path.Ext(change.Path), ".md") {
+			input.Headings = markdownHeadings(text)
+		}
+		inputs = append(inputs, input)
+	}
+	return inputs, deleted, nil
+}
+
+// staleGitDocuments returns the IDs of the existing documents of a source
+// that a full index neither produced nor already deletes.
+func staleGitDocuments(existing []string, inputs []DocumentInput, deleted []string) []string {
+	current := make(map[string]bool, len(inputs)+len(deleted))
+	for _, input := range inputs {
+		current[input.ID] = true
+	}
+	for _, id := range deleted {
+		current[id] = true
+	}
+	var stale []string
+	for _, id := range existing {
+		if !current[id] {
+			stale = append(stale, id)
+		}
+	}
+	return stale
+}
+
+// gitCommitDocument builds the document for a commit message.
+func gitCommitDocument(source *Source, ref string, c *gitrepo.Commit) DocumentInput {
+	_, body, _ := strings.Cut(strings.TrimSpace(c.Message), "\n")
+	return DocumentInput{
+		ID:         gitSourcePrefix(source) + "commit:" + c.Hash.String(),
+		Title:      c.Subject(),
+		Body:       strings.TrimSpace(body),
+		Language:   source.Language,
+		Label:      source.label(),
+		SourceType: SourceTypeGitCommit,
+		ModifiedAt: c.Committer.When,
+		Metadata: map[string]string{
+			"repo":         source.Root,
+			"ref":          ref,
+			"commit":       c.Hash.String(),
+			"author":       c.Author.Name,
+			"author_email": c.Author.Email,
+			"commit_date":  c.Committer.When.Format(time.RFC3339),
+		},
+	}
+}
+
+// gitSourcePrefix returns the prefix of the external IDs of the documents
+// of a source.
+func gitSourcePrefix(source *Source) string {
+	return "git:" + source.label() + ":"
+}
+
+// gitFileID returns the external ID of a file document.
+func gitFileID(source *Source, p string) string {
+	return gitSourcePrefix(source) + p
+}
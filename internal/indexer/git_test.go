@@ -0,0 +1,92 @@
+package indexer
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/TonyGLL/gofetch/internal/gitrepo"
+	"github.com/TonyGLL/gofetch/internal/gitrepo/gittest"
+)
+
+func TestGitDocuments(t *testing.T) {
+	fixture := gittest.New(t)
+	fixture.Write("guide.md", "# Guide\n\n## Install\nRun make.\n")
+	fixture.Write("notes.txt", "old notes\n")
+	fixture.Write("main.go", "package main\n")
+	fixture.Write("logo.txt", "PNG\x00\x01")
+	fixture.Run("add", "-A")
+	fixture.Run("commit", "-q", "-m", "Add docs\n\nFirst version of the guide.")
+	fixture.Run("rm", "-q", "notes.txt")
+	fixture.Write("guide.md", "# Guide\n\n## Install\nRun make install.\n")
+	fixture.Run("add", "-A")
+	fixture.Run("commit", "-q", "-m", "Update guide")
+	dir := fixture.Dir
+
+	repo, err := gitrepo.Open(dir)
+	if err != nil {
+		t.Fatalf("Open returned an error: %v", err)
+	}
+	defer repo.Close()
+	head, err := repo.ResolveRef("HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	headCommit, err := repo.Commit(head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := repo.Commit(headCommit.Parents[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes, err := repo.DiffTrees(first.Tree, headCommit.Tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source := &Source{Type: SourceTypeGit, Root: dir, Label: "docs"}
+	inputs, deleted, err := gitDocuments(repo, source, "HEAD", headCommit, changes)
+	if err != nil {
+		t.Fatalf("gitDocuments returned an error: %v", err)
+	}
+	if len(inputs) != 1 || inputs[0].ID != "git:docs:guide.md" {
+		t.Fatalf("Expected only guide.md to be indexed, got %+v", inputs)
+	}
+	guide := inputs[0]
+	if guide.Title != "# Guide" || len(guide.Headings) != 2 || guide.Metadata["commit"] != head.String() ||
+		guide.Metadata["author"] != "Ana" || guide.SourceType != SourceTypeGit || guide.Label != "docs" {
+		t.Errorf("Unexpected guide document: %+v", guide)
+	}
+	if len(deleted) != 1 || deleted[0] != "git:docs:notes.txt" {
+		t.Errorf("Expected notes.txt to be deleted, got %v", deleted)
+	}
+
+	// A full index includes every text file and skips binaries.
+	changes, err = repo.DiffTrees(gitrepo.Hash{}, headCommit.Tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inputs, deleted, err = gitDocuments(repo, source, "HEAD", headCommit, changes)
+	if err != nil {
+		t.Fatalf("gitDocuments returned an error: %v", err)
+	}
+	if len(inputs) != 1 || len(deleted) != 1 || deleted[0] != "git:docs:logo.txt" {
+		t.Errorf("Expected guide.md indexed and logo.txt skipped, got %d inputs and %v", len(inputs), deleted)
+	}
+
+	commit := gitCommitDocument(source, "HEAD", first)
+	if commit.Title != "Add docs" || commit.Body != "First version of the guide." || commit.SourceType != SourceTypeGitCommit {
+		t.Errorf("Unexpected commit document: %+v", commit)
+	}
+}
+
+func TestStaleGitDocuments(t *testing.T) {
+	existing := []string{"git:docs:guide.md", "git:docs:old.md", "git:docs:logo.txt", "git:docs:commit:abc"}
+	inputs := []DocumentInput{{ID: "git:docs:guide.md"}, {ID: "git:docs:commit:def"}}
+	deleted := []string{"git:docs:logo.txt"}
+
+	stale := staleGitDocuments(existing, inputs, deleted)
+	if !slices.Equal(stale, []string{"git:docs:old.md", "git:docs:commit:abc"}) {
+		t.Errorf("Expected the old file and the rewritten commit to be stale, got %v", stale)
+	}
+}
@@ -0,0 +1,448 @@
+package indexer
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Supported import formats.
+const (
+	FormatJSONL = "jsonl"
+	FormatCSV   = "csv"
+)
+
+// maxReportedErrors bounds how many error messages an ImportReport keeps.
+const maxReportedErrors = 20
+
+// FieldMapping tells the importer which record keys (JSONL, dotted paths are
+// allowed for nested objects) or columns (CSV) hold each part of a document.
+type FieldMapping struct {
+	ID       string
+	URL      string
+	Title    string
+	Body     string
+	Language string
+	Label    string
+	Fields   []string // Extra searchable text, stored under the key name
+	Metadata []string // Stored and returned with results; "*" copies every unmapped key
+}
+
+// DefaultFieldMapping matches records that already use DocumentInput names.
+func DefaultFieldMapping() FieldMapping {
+	return FieldMapping{
+		ID:       "id",
+		URL:      "url",
+		Title:    "title",
+		Body:     "body",
+		Language: "language",
+		Label:    "label",
+	}
+}
+
+// ImportOptions configures ImportDocuments.
+type ImportOptions struct {
+	Format     string // FormatJSONL or FormatCSV
+	Mapping    FieldMapping
+	Label      string // Label for records without a mapped label
+	SourceType string // Defaults to "import"
+	BatchSize  int    // Records per batch and bulk write; defaults to defaultBatchSize
+}
+
+// ImportReport summarizes an import run. Records are counted as created or
+// updated once they are written.
+type ImportReport struct {
+	Read       int64
+	Created    int64
+	Updated    int64
+	Superseded int64 // Replaced by a later record with the same ID in the same batch
+	Failed     int64
+	Errors     []string // The first maxReportedErrors failures
+	Duration   time.Duration
+
+	mu sync.Mutex
+}
+
+// written counts the records of a batch written by the writer. A record
+// that replaced a previous version is counted as updated.
+func (r *ImportReport) written(batch []*indexPayload) {
+	for _, payload := range batch {
+		if len(payload.Replaces) > 0 {
+			atomic.AddInt64(&r.Updated, 1)
+		} else {
+			atomic.AddInt64(&r.Created, 1)
+		}
+	}
+}
+
+func (r *ImportReport) fail(format string, args ...any) {
+	atomic.AddInt64(&r.Failed, 1)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.Errors) < maxReportedErrors {
+		r.Errors = append(r.Errors, fmt.Sprintf(format, args...))
+	}
+}
+
+// importRecord is one parsed input record with its position in the input.
+type importRecord struct {
+	Line   int
+	Values map[string]any
+}
+
+// importBatch collects the inputs sent to the workers together, keeping only
+// the last input of every ID.
+type importBatch struct {
+	inputs []DocumentInput
+	lines  []int          // Input line of every input
+	byID   map[string]int // Index in inputs of every ID
+}
+
+// add adds an input read at line, and reports whether it replaced an
+// earlier input with the same ID.
+func (b *importBatch) add(input DocumentInput, line int) bool {
+	if i, ok := b.byID[input.key()]; ok {
+		b.inputs[i], b.lines[i] = input, line
+		return true
+	}
+	if b.byID == nil {
+		b.byID = make(map[string]int)
+	}
+	b.byID[input.key()] = len(b.inputs)
+	b.inputs = append(b.inputs, input)
+	b.lines = append(b.lines, line)
+	return false
+}
+
+func (b *importBatch) reset() {
+	b.inputs, b.lines = b.inputs[:0], b.lines[:0]
+	clear(b.byID)
+}
+
+// ImportDocuments streams records from r, maps them to documents and indexes
+// them through the same worker/writer pipeline used for directories. Records
+// are sent to the workers in batches; when a batch repeats the ID of a record
+// that is still on its way to the writer, the pipeline is drained first, so a
+// record always replaces the version written by an earlier record with the
+// same ID. Records that cannot be parsed, mapped or prepared are counted as
+// failed and skipped, and a failed write ends the import with an error.
+func (idx *Indexer) ImportDocuments(ctx context.Context, r io.Reader, opts ImportOptions) (*ImportReport, error) {
+	start := time.Now()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBatchSize
+	}
+	if opts.SourceType == "" {
+		opts.SourceType = "import"
+	}
+	report := &ImportReport{}
+	if err := idx.RecordAnalyzers(ctx); err != nil {
+		return report, err
+	}
+
+	channelBuffer := 100
+	records := make(chan importRecord, channelBuffer)
+	errCh := make(chan error, 1)
+
+	// 1. Start workers and writer
+	pipeline := idx.startImport(ctx, cancel, errCh, opts.BatchSize, report)
+
+	// 2. Start producer
+	go func() {
+		defer close(records)
+		if err := readRecords(ctx, r, opts.Format, records, report); err != nil {
+			reportError(errCh, err)
+			cancel()
+		}
+	}()
+
+	// 3. Map records to documents and send them to the workers in batches
+	var batch importBatch
+	dispatch := func() {
+		for _, input := range batch.inputs {
+			if pipeline.sent[input.key()] {
+				pipeline.wait(ctx)
+				pipeline = idx.startImport(ctx, cancel, errCh, opts.BatchSize, report)
+				break
+			}
+		}
+		for i, input := range batch.inputs {
+			pipeline.sent[input.key()] = true
+			select {
+			case pipeline.jobs <- importJob{Input: input, Line: batch.lines[i]}:
+			case <-ctx.Done():
+				return
+			}
+		}
+		batch.reset()
+	}
+	for record := range records {
+		input := opts.Mapping.apply(record.Values)
+		if input.Label == "" {
+			input.Label = opts.Label
+		}
+		input.SourceType = opts.SourceType
+		if err := input.validate(); err != nil {
+			report.fail("record %d: %v", record.Line, err)
+			continue
+		}
+		if batch.add(input, record.Line) {
+			atomic.AddInt64(&report.Superseded, 1)
+		}
+		if len(batch.inputs) >= opts.BatchSize {
+			dispatch()
+		}
+	}
+	dispatch()
+
+	// 4. Wait and synchronize
+	pipeline.wait(ctx)
+
+	report.Duration = time.Since(start)
+	select {
+	case err := <-errCh:
+		return report, fmt.Errorf("import failed: %w", err)
+	default:
+		return report, nil
+	}
+}
+
+// importJob is a mapped record waiting for a worker.
+type importJob struct {
+	Input DocumentInput
+	Line  int
+}
+
+// importPipeline is a running set of import workers and their writer.
+type importPipeline struct {
+	jobs      chan importJob
+	results   chan *indexPayload
+	wg        sync.WaitGroup
+	writeDone chan struct{}
+	sent      map[string]bool // IDs sent to the workers
+}
+
+// startImport starts the workers that prepare import jobs and the writer
+// that writes their payloads, counting the records it writes in report.
+func (idx *Indexer) startImport(ctx context.Context, cancel context.CancelFunc, errCh chan<- error, batchSize int, report *ImportReport) *importPipeline {
+	channelBuffer := 100
+	p := &importPipeline{
+		jobs:      make(chan importJob, channelBuffer),
+		results:   make(chan *indexPayload, channelBuffer),
+		writeDone: make(chan struct{}),
+		sent:      make(map[string]bool),
+	}
+	workerCount := runtime.NumCPU()
+	p.wg.Add(workerCount)
+	for range workerCount {
+		go idx.importWorker(ctx, &p.wg, p.jobs, p.results, report)
+	}
+	go idx.writer(ctx, p.results, errCh, cancel, p.writeDone, batchSize, report.written)
+	return p
+}
+
+// wait stops the pipeline once every job sent to it is written.
+func (p *importPipeline) wait(ctx context.Context) {
+	close(p.jobs)
+	p.wg.Wait()
+	close(p.results)
+	select {
+	case <-p.writeDone:
+	case <-ctx.Done():
+	}
+}
+
+// importWorker prepares the payloads of import jobs.
+func (idx *Indexer) importWorker(
+	ctx context.Context,
+	wg *sync.WaitGroup,
+	jobs <-chan importJob,
+	results chan<- *indexPayload,
+	report *ImportReport,
+) {
+	defer wg.Done()
+	for job := range jobs {
+		payload, _, err := idx.prepareDocument(ctx, &job.Input)
+		if err != nil {
+			report.fail("record %d: %v", job.Line, err)
+			continue
+		}
+
+		select {
+		case results <- payload:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// readRecords parses r in the given format and sends every record to out.
+func readRecords(ctx context.Context, r io.Reader, format string, out chan<- importRecord, report *ImportReport) error {
+	send := func(record importRecord) error {
+		atomic.AddInt64(&report.Read, 1)
+		select {
+		case out <- record:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	switch format {
+	case FormatJSONL:
+		return readJSONL(r, send, report)
+	case FormatCSV:
+		return readCSV(r, send, report)
+	default:
+		return fmt.Errorf("unsupported import format %q", format)
+	}
+}
+
+func readJSONL(r io.Reader, send func(importRecord) error, report *ImportReport) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64<<20)
+	line := 0
+	for scanner.Scan() {
+		line++
+		raw := strings.TrimSpace(scanner.Text())
+		if raw == "" {
+			continue
+		}
+		values := make(map[string]any)
+		if err := json.Unmarshal([]byte(raw), &values); err != nil {
+			atomic.AddInt64(&report.Read, 1)
+			report.fail("record %d: %v", line, err)
+			continue
+		}
+		if err := send(importRecord{Line: line, Values: values}); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func readCSV(r io.Reader, send func(importRecord) error, report *ImportReport) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("error reading csv header: %w", err)
+	}
+	columns := append([]string(nil), header...)
+
+	line := 1
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		line++
+		if err != nil {
+			atomic.AddInt64(&report.Read, 1)
+			report.fail("record %d: %v", line, err)
+			continue
+		}
+		values := make(map[string]any, len(columns))
+		for i, column := range columns {
+			if i < len(row) {
+				values[column] = row[i]
+			}
+		}
+		if err := send(importRecord{Line: line, Values: values}); err != nil {
+			return err
+		}
+	}
+}
+
+// apply builds a DocumentInput from a record.
+func (m *FieldMapping) apply(values map[string]any) DocumentInput {
+	input := DocumentInput{
+		ID:       lookupString(values, m.ID),
+		URL:      lookupString(values, m.URL),
+		Title:    lookupString(values, m.Title),
+		Body:     lookupString(values, m.Body),
+		Language: lookupString(values, m.Language),
+		Label:    lookupString(values, m.Label),
+	}
+
+	for _, name := range m.Fields {
+		if v := lookupString(values, name); v != "" {
+			if input.Fields == nil {
+				input.Fields = make(map[string]string)
+			}
+			input.Fields[name] = v
+		}
+	}
+
+	for _, name := range m.metadataKeys(values) {
+		if v := lookupString(values, name); v != "" {
+			if input.Metadata == nil {
+				input.Metadata = make(map[string]string)
+			}
+			input.Metadata[name] = v
+		}
+	}
+	return input
+}
+
+// metadataKeys expands "*" to every top-level key that is not mapped elsewhere.
+func (m *FieldMapping) metadataKeys(values map[string]any) []string {
+	keys := []string{}
+	for _, name := range m.Metadata {
+		if name != "*" {
+			keys = append(keys, name)
+			continue
+		}
+		mapped := map[string]bool{m.ID: true, m.URL: true, m.Title: true, m.Body: true, m.Language: true, m.Label: true}
+		for _, f := range m.Fields {
+			mapped[f] = true
+		}
+		for key := range values {
+			if !mapped[key] {
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// lookupString returns the value at a key or dotted path as a string.
+// Non-string values are JSON encoded.
+func lookupString(values map[string]any, key string) string {
+	if key == "" {
+		return ""
+	}
+	v, ok := values[key]
+	if !ok && strings.Contains(key, ".") {
+		head, rest, _ := strings.Cut(key, ".")
+		if nested, isMap := values[head].(map[string]any); isMap {
+			return lookupString(nested, rest)
+		}
+	}
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(encoded)
+	}
+}
@@ -0,0 +1,104 @@
+package indexer
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/TonyGLL/gofetch/pkg/storage"
+)
+
+func TestReadRecords_MapsCSVAndJSONL(t *testing.T) {
+	mapping := FieldMapping{
+		ID:       "page_id",
+		Title:    "name",
+		Body:     "text",
+		Fields:   []string{"summary"},
+		Metadata: []string{"*"},
+	}
+
+	testCases := []struct {
+		name   string
+		format string
+		input  string
+	}{
+		{
+			name:   "CSV with header",
+			format: FormatCSV,
+			input:  "page_id,name,text,summary,author\n42,Robots,How robots.txt works,Short intro,ana\n",
+		},
+		{
+			name:   "JSONL with a numeric id",
+			format: FormatJSONL,
+			input:  `{"page_id": 42, "name": "Robots", "text": "How robots.txt works", "summary": "Short intro", "author": "ana"}` + "\n\n",
+		},
+	}
+
+	expected := DocumentInput{
+		ID:       "42",
+		Title:    "Robots",
+		Body:     "How robots.txt works",
+		Fields:   map[string]string{"summary": "Short intro"},
+		Metadata: map[string]string{"author": "ana"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			records := make(chan importRecord, 10)
+			report := &ImportReport{}
+			if err := readRecords(context.Background(), strings.NewReader(tc.input), tc.format, records, report); err != nil {
+				t.Fatalf("readRecords returned an error: %v", err)
+			}
+			close(records)
+
+			var inputs []DocumentInput
+			for record := range records {
+				inputs = append(inputs, mapping.apply(record.Values))
+			}
+			if len(inputs) != 1 || report.Read != 1 || report.Failed != 0 {
+				t.Fatalf("Expected 1 record and no failures, got %d inputs, report %+v", len(inputs), report)
+			}
+			if !reflect.DeepEqual(inputs[0], expected) {
+				t.Errorf("Expected %+v, but got %+v", expected, inputs[0])
+			}
+		})
+	}
+}
+
+func TestImportBatch_LastRecordWins(t *testing.T) {
+	var batch importBatch
+	adds := []struct {
+		id   string
+		line int
+	}{{"a", 1}, {"b", 2}, {"a", 3}}
+	var replaced []bool
+	for _, add := range adds {
+		replaced = append(replaced, batch.add(DocumentInput{ID: add.id}, add.line))
+	}
+
+	if !reflect.DeepEqual(replaced, []bool{false, false, true}) {
+		t.Errorf("Expected only the repeated ID to replace an input, got %v", replaced)
+	}
+	if len(batch.inputs) != 2 || !reflect.DeepEqual(batch.lines, []int{3, 2}) {
+		t.Errorf("Expected inputs from lines [3 2], got %d inputs from lines %v", len(batch.inputs), batch.lines)
+	}
+
+	batch.reset()
+	if batch.add(DocumentInput{ID: "a"}, 4) || len(batch.inputs) != 1 {
+		t.Errorf("Expected a reset batch to start empty, got %d inputs", len(batch.inputs))
+	}
+}
+
+func TestImportReport_CountsWrittenRecords(t *testing.T) {
+	report := &ImportReport{}
+	report.written([]*indexPayload{
+		{},
+		{Replaces: []*storage.Document{{}}},
+		{},
+	})
+
+	if report.Created != 2 || report.Updated != 1 {
+		t.Errorf("Expected 2 created and 1 updated, got %d created and %d updated", report.Created, report.Updated)
+	}
+}
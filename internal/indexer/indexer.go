@@ -22,22 +22,34 @@ import (
 
 const maxContent = 100
 
+// defaultBatchSize is the number of documents the writer flushes at once.
+const defaultBatchSize = 100
+
+// fileJob is a file queued for indexing together with the source it belongs to.
+type fileJob struct {
+	Path   string
+	Source *Source
+}
+
 // indexPayload is the data structure that workers send to the writer.
 type indexPayload struct {
 	Doc       storage.Document
 	Freqs     map[string]int
 	Positions map[string][]int
 	FilePath  string
+	Passages  []*indexPayload     // Passages of the document, written in the same batch
+	Replaces  []*storage.Document // Previous versions of the document, removed once it is written
 }
 
 // Indexer encapsulates the indexing logic.
 type Indexer struct {
-	analyzer    *analysis.Analyzer
+	analyzer    *analysis.MultiAnalyzer
 	mongo_store *storage.MongoStore
+	passages    PassageOptions
 }
 
 // NewIndexer creates a new Indexer instance.
-func NewIndexer(analyzer *analysis.Analyzer, mongo_store *storage.MongoStore) *Indexer {
+func NewIndexer(analyzer *analysis.MultiAnalyzer, mongo_store *storage.MongoStore) *Indexer {
 	return &Indexer{
 		analyzer:    analyzer,
 		mongo_store: mongo_store,
@@ -68,21 +80,15 @@ func (idx *Indexer) IndexWebPage(ctx context.Context, urlStr, title, htmlContent
 		return fmt.Errorf("contenido muy corto, saltando: %s", urlStr)
 	}
 
-	// 2. Generate tokens using the same analyzer you use for files
-	tokens := idx.analyzer.Analyze(cleanText)
+	var headings []string
+	doc.Find("h1,h2,h3,h4,h5,h6").Each(func(_ int, s *goquery.Selection) {
+		headings = append(headings, strings.TrimSpace(s.Text()))
+	})
 
-	// 3. Count frequencies and positions (reusing your exact logic)
-	freqs := make(map[string]int)
-	positions := make(map[string][]int)
-	for i, token := range tokens {
-		if token == "" {
-			continue
-		}
-		freqs[token]++
-		positions[token] = append(positions[token], i)
-	}
+	// 2. Detect the language of the page
+	lang := idx.analyzer.Detect(cleanText)
 
-	// 4. Create the document (same as in processFile)
+	// 3. Create the document (same as in processFile)
 	document := storage.Document{
 		ID:         primitive.NewObjectID(),
 		SourceType: "web",
@@ -91,16 +97,13 @@ func (idx *Indexer) IndexWebPage(ctx context.Context, urlStr, title, htmlContent
 		Content:    cleanText,
 		IndexedAt:  time.Now(),
 		ModifiedAt: time.Now(), // o podrías usar HTTP Last-Modified si lo tienes
+		Language:   lang,
 	}
 
-	// 5. Reuse your existing writer: send the payload through the channel
+	// 4. Analyze every field with the matching analyzer, count frequencies and
+	// positions, then reuse your existing writer: send the payload through the channel.
 	// We simulate the same flow used by the file workers
-	payload := &indexPayload{
-		Doc:       document,
-		Freqs:     freqs,
-		Positions: positions,
-		FilePath:  urlStr, // solo para logging, no se usa
-	}
+	payload := idx.buildPayload(&document, lang, headings)
 
 	// Usamos el mismo writer que ya tienes corriendo (o uno temporal si no hay)
 	results := make(chan *indexPayload, 1)
@@ -110,7 +113,7 @@ func (idx *Indexer) IndexWebPage(ctx context.Context, urlStr, title, htmlContent
 	errCh := make(chan error, 1)
 	writeDone := make(chan struct{})
 
-	go idx.writer(ctx, results, errCh, func() {}, writeDone)
+	go idx.writer(ctx, results, errCh, func() {}, writeDone, defaultBatchSize, nil)
 
 	select {
 	case <-writeDone:
@@ -122,11 +125,54 @@ func (idx *Indexer) IndexWebPage(ctx context.Context, urlStr, title, htmlContent
 
 // IndexDirectory runs the concurrent pipeline to index files in a directory.
 func (idx *Indexer) IndexDirectory(dirPath string) error {
+	return idx.IndexSources([]Source{{Root: dirPath}})
+}
+
+// IndexSources indexes every source, tagging each document with the label of
+// the source it was found in. Directory sources share one concurrent pipeline;
+// git and mail sources are indexed afterwards, one at a time.
+func (idx *Indexer) IndexSources(sources []Source) error {
+	if err := idx.RecordAnalyzers(context.Background()); err != nil {
+		return err
+	}
+
+	var dirSources, gitSources, mailSources []Source
+	for _, source := range sources {
+		switch source.Type {
+		case SourceTypeGit:
+			gitSources = append(gitSources, source)
+		case SourceTypeMbox, SourceTypeMaildir:
+			mailSources = append(mailSources, source)
+		default:
+			dirSources = append(dirSources, source)
+		}
+	}
+
+	if len(dirSources) > 0 {
+		if err := idx.indexDirectories(dirSources); err != nil {
+			return err
+		}
+	}
+	for i := range gitSources {
+		if err := idx.indexGitSource(context.Background(), &gitSources[i]); err != nil {
+			return fmt.Errorf("indexing git source %s failed: %w", gitSources[i].Root, err)
+		}
+	}
+	for i := range mailSources {
+		if err := idx.indexMailSource(context.Background(), &mailSources[i]); err != nil {
+			return fmt.Errorf("indexing mail source %s failed: %w", mailSources[i].Root, err)
+		}
+	}
+	return nil
+}
+
+// indexDirectories runs the concurrent pipeline over the files of every directory source.
+func (idx *Indexer) indexDirectories(sources []Source) error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	channelBuffer := 100
-	jobs := make(chan string, channelBuffer)
+	jobs := make(chan fileJob, channelBuffer)
 	results := make(chan *indexPayload, channelBuffer)
 	errCh := make(chan error, 1)
 
@@ -143,32 +189,17 @@ func (idx *Indexer) IndexDirectory(dirPath string) error {
 
 	// 2. Start writer
 	writeDone := make(chan struct{})
-	go idx.writer(ctx, results, errCh, cancel, writeDone)
+	go idx.writer(ctx, results, errCh, cancel, writeDone, defaultBatchSize, nil)
 
 	// 3. Start producer
 	go func() {
 		defer close(jobs)
-		walkErr := filepath.WalkDir(dirPath, func(path string, d fs.DirEntry, err error) error {
-			if err != nil {
-				return err
-			}
-			if d.IsDir() {
-				return nil
-			}
-			ext := strings.ToLower(filepath.Ext(path))
-			if ext != ".txt" && ext != ".md" {
-				return nil
-			}
-			select {
-			case jobs <- path:
-			case <-ctx.Done():
-				return ctx.Err()
+		for i := range sources {
+			if err := walkSource(ctx, &sources[i], jobs); err != nil {
+				reportError(errCh, err)
+				cancel()
+				return
 			}
-			return nil
-		})
-		if walkErr != nil {
-			reportError(errCh, walkErr)
-			cancel()
 		}
 	}()
 
@@ -197,7 +228,36 @@ func (idx *Indexer) IndexDirectory(dirPath string) error {
 	}
 }
 
-func (idx *Indexer) processFile(ctx context.Context, path string) (*indexPayload, error) {
+// walkSource queues every file of a source that passes its include/exclude rules.
+func walkSource(ctx context.Context, source *Source, jobs chan<- fileJob) error {
+	return filepath.WalkDir(source.Root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(source.Root, path)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if rel != "." && source.excludes(rel) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !source.includes(rel) {
+			return nil
+		}
+		select {
+		case jobs <- fileJob{Path: path, Source: source}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		return nil
+	})
+}
+
+func (idx *Indexer) processFile(ctx context.Context, job fileJob) (*indexPayload, error) {
+	path := job.Path
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("error reading file %s: %w", path, err)
@@ -220,63 +280,136 @@ func (idx *Indexer) processFile(ctx context.Context, path string) (*indexPayload
 			fmt.Printf("Skipping unchanged file: %s\n", path)
 			return nil, nil // nil, nil indicates skipped file
 		}
-		// If the file has been modified, remove old postings and delete the old document
-		oldTerms := idx.analyzer.Analyze(existingDoc.Content)
-		if err := idx.mongo_store.RemovePostingsForDocument(ctx, existingDoc.ID, oldTerms); err != nil {
-			return nil, fmt.Errorf("error removing old postings for %s: %w", path, err)
-		}
-
-		if err := idx.mongo_store.DeleteDocument(ctx, existingDoc.ID); err != nil {
-			return nil, fmt.Errorf("error deleting existing document for %s: %w", path, err)
-		}
 	}
 
 	text := string(data)
 
-	// Title extraction logic
-	title := ""
-	lines := strings.Split(text, "\n")
-	for _, line := range lines {
-		trimmedLine := strings.TrimSpace(line)
-		if trimmedLine != "" {
-			title = trimmedLine
-			break
-		}
+	title := extractTitle(text, filepath.Base(path))
+
+	lang := codeLanguage(path, job.Source.Language)
+	if lang == "" {
+		lang = idx.analyzer.Detect(text)
+	}
+	if lang == analysis.CodeLanguage {
+		title = filepath.Base(path) // The first line of a source file is rarely a title
+	}
+	analyzerName := job.Source.Analyzer
+	if analyzerName == "" {
+		analyzerName = lang
 	}
 
-	if title == "" {
-		title = filepath.Base(path) // Fallback to filename
+	var headings []string
+	if strings.EqualFold(filepath.Ext(path), ".md") {
+		headings = markdownHeadings(text)
 	}
 
-	tokens := idx.analyzer.Analyze(text)
-	freqs := make(map[string]int)
-	positions := make(map[string][]int)
-	for i, token := range tokens {
-		if token == "" {
-			continue
+	document := storage.Document{
+		ID:         primitive.NewObjectID(),
+		SourceType: "file",
+		URL:        path,
+		Title:      title, // Set the extracted title
+		Content:    text,
+		IndexedAt:  time.Now(),
+		ModifiedAt: modifiedAt,
+		FilePath:   path,
+		Language:   lang,
+		Label:      job.Source.label(),
+	}
+
+	payload := idx.buildPayload(&document, analyzerName, headings)
+	if existingDoc != nil {
+		// The file has been modified: the old document is removed once the new one is written.
+		payload.Replaces = []*storage.Document{existingDoc}
+	}
+	return payload, nil
+}
+
+// buildPayload analyzes every field of a document with the named analyzer
+// (usually its language) and, when passages are enabled, splits it into
+// passages indexed alongside it. The analyzers used are recorded on the document.
+func (idx *Indexer) buildPayload(doc *storage.Document, analyzerName string, headings []string) *indexPayload {
+	counts := newTermCounts()
+	for _, field := range idx.documentFields(analyzerName, doc, headings) {
+		counts.add(field)
+	}
+	doc.Analyzer = idx.analyzer.For(analyzerName).Name()
+	doc.FieldAnalyzers = nil
+	for field := range counts.next {
+		if idx.analyzer.FieldAnalyzer(field) != "" {
+			if doc.FieldAnalyzers == nil {
+				doc.FieldAnalyzers = make(map[string]string)
+			}
+			doc.FieldAnalyzers[field] = idx.analyzer.ForField(field, analyzerName).Name()
 		}
+	}
+	payload := newPayload(*doc, counts)
+	payload.Passages = idx.passagePayloads(doc, analyzerName)
+	return payload
+}
+
+// newPayload counts term frequencies and positions and records the indexed
+// terms on the document so they can be removed later. Terms are keyed with
+// storage.FieldTerm.
+func newPayload(doc storage.Document, counts *termCounts) *indexPayload {
+	freqs, positions := counts.count()
+	doc.Terms = counts.terms
 
-		freqs[token]++
-		positions[token] = append(positions[token], i)
-	}
-
-	payload := &indexPayload{
-		Doc: storage.Document{
-			ID:         primitive.NewObjectID(),
-			SourceType: "file",
-			URL:        path,
-			Title:      title, // Set the extracted title
-			Content:    text,
-			IndexedAt:  time.Now(),
-			ModifiedAt: modifiedAt,
-			FilePath:   path,
-		},
+	filePath := doc.FilePath
+	if filePath == "" {
+		filePath = doc.URL // Only used for logging
+	}
+	return &indexPayload{
+		Doc:       doc,
 		Freqs:     freqs,
 		Positions: positions,
-		FilePath:  path,
+		FilePath:  filePath,
 	}
+}
 
-	return payload, nil
+// removeDocument deletes a previously indexed document and its postings.
+// Postings are found by document ID rather than by analyzing the content
+// again, so none are left behind when the analyzer changed since.
+func (idx *Indexer) removeDocument(ctx context.Context, doc *storage.Document) error {
+	if err := idx.deleteDocument(ctx, doc); err != nil {
+		return err
+	}
+	if err := idx.mongo_store.UpdateIndexStats(ctx, -1); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to update index stats: %v\n", err)
+	}
+	return nil
+}
+
+// deleteDocument deletes a document with its postings and passages, without
+// updating the index stats.
+func (idx *Indexer) deleteDocument(ctx context.Context, doc *storage.Document) error {
+	if err := idx.mongo_store.RemovePostingsForDocument(ctx, doc.ID, doc.Terms); err != nil {
+		return fmt.Errorf("error removing old postings: %w", err)
+	}
+	if err := idx.mongo_store.DeleteDocument(ctx, doc.ID); err != nil {
+		return fmt.Errorf("error deleting existing document: %w", err)
+	}
+	return idx.removePassages(ctx, doc.ID)
+}
+
+// removeReplaced removes the previous versions of a written payload. When
+// that fails they stay in the index until the document is indexed again.
+func (idx *Indexer) removeReplaced(ctx context.Context, payload *indexPayload) {
+	for _, doc := range payload.Replaces {
+		if err := idx.removeDocument(ctx, doc); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to remove the previous version of %s: %v\n", payload.FilePath, err)
+		}
+	}
+}
+
+// discardPayloads deletes whatever a failed write stored of a batch, so
+// the previous versions the payloads replace stay the current ones.
+func (idx *Indexer) discardPayloads(ctx context.Context, batch []*indexPayload) error {
+	for _, payload := range batch {
+		if err := idx.deleteDocument(ctx, &payload.Doc); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // worker is the logic executed by each goroutine in the pool.
@@ -284,17 +417,17 @@ func (idx *Indexer) processFile(ctx context.Context, path string) (*indexPayload
 func (idx *Indexer) worker(
 	ctx context.Context,
 	wg *sync.WaitGroup,
-	jobs <-chan string,
+	jobs <-chan fileJob,
 	results chan<- *indexPayload,
 	indexedFilesCh chan<- string,
 ) {
 	defer wg.Done()
-	for path := range jobs {
+	for job := range jobs {
 		select {
 		case <-ctx.Done():
 			return
 		default:
-			payload, err := idx.processFile(ctx, path)
+			payload, err := idx.processFile(ctx, job)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "%v\n", err)
 				continue
@@ -307,7 +440,7 @@ func (idx *Indexer) worker(
 			select {
 			case results <- payload:
 				select {
-				case indexedFilesCh <- path:
+				case indexedFilesCh <- job.Path:
 				case <-ctx.Done():
 					return
 				}
@@ -319,19 +452,21 @@ func (idx *Indexer) worker(
 	}
 }
 
-// writer consumes results and writes them to MongoDB in batches.
+// writer consumes results and writes them to MongoDB in batches. When
+// written is not nil, it is called with every batch once it is written.
 func (idx *Indexer) writer(
 	ctx context.Context,
 	results <-chan *indexPayload,
 	errCh chan<- error,
 	cancel context.CancelFunc,
 	done chan<- struct{},
+	batchSize int,
+	written func(batch []*indexPayload),
 ) {
 	defer close(done)
 
-	const BATCH_SIZE = 100
 	const BATCH_TIMEOUT = 5 * time.Second
-	batch := make([]*indexPayload, 0, BATCH_SIZE)
+	batch := make([]*indexPayload, 0, batchSize)
 	ticker := time.NewTicker(BATCH_TIMEOUT)
 	defer ticker.Stop()
 
@@ -345,6 +480,12 @@ func (idx *Indexer) writer(
 			reportError(errCh, err)
 			cancel()
 		} else {
+			for _, payload := range batch {
+				idx.removeReplaced(ctx, payload)
+			}
+			if written != nil {
+				written(batch)
+			}
 			totalDocsInBatch = int64(len(batch))
 			// Incrementally update stats
 			if err := idx.mongo_store.UpdateIndexStats(context.Background(), totalDocsInBatch); err != nil {
@@ -364,7 +505,7 @@ func (idx *Indexer) writer(
 				return
 			}
 			batch = append(batch, payload)
-			if len(batch) >= BATCH_SIZE {
+			if len(batch) >= batchSize {
 				flushBatch()
 				ticker.Reset(BATCH_TIMEOUT)
 			}
@@ -383,7 +524,8 @@ func (idx *Indexer) writeBatch(ctx context.Context, batch []*indexPayload) error
 	docModels := make([]mongo.WriteModel, 0, len(batch))
 	termModels := make([]mongo.WriteModel, 0, len(batch)*mul)
 
-	for _, payload := range batch {
+	var addModels func(payload *indexPayload)
+	addModels = func(payload *indexPayload) {
 		docModels = append(docModels, mongo.NewInsertOneModel().SetDocument(payload.Doc))
 
 		for term, freq := range payload.Freqs {
@@ -401,6 +543,13 @@ func (idx *Indexer) writeBatch(ctx context.Context, batch []*indexPayload) error
 				SetUpsert(true)
 			termModels = append(termModels, model)
 		}
+
+		for _, passage := range payload.Passages {
+			addModels(passage)
+		}
+	}
+	for _, payload := range batch {
+		addModels(payload)
 	}
 
 	if err := idx.mongo_store.BulkWriteDocuments(ctx, docModels); err != nil {
@@ -0,0 +1,199 @@
+package indexer
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/TonyGLL/gofetch/internal/mailbox"
+)
+
+// SourceTypeMail is the document source type of messages read from mbox
+// and Maildir sources.
+const SourceTypeMail = "mail"
+
+// mailIncludes are the mbox file patterns read when a source has no include
+// rules. Mbox files rarely have a consistent extension.
+var mailIncludes = []string{"*"}
+
+// mailMessage is a parsed message together with where it was read from.
+type mailMessage struct {
+	*mailbox.Message
+	Location string // Mailbox file relative to the source root
+}
+
+// indexMailSource indexes every message of an mbox or Maildir source.
+// Messages are upserted by Message-ID, so re-indexing a mailbox only
+// replaces the messages it already contained.
+func (idx *Indexer) indexMailSource(ctx context.Context, source *Source) error {
+	var messages []mailMessage
+	var err error
+	if source.Type == SourceTypeMaildir {
+		messages, err = readMaildir(source)
+	} else {
+		messages, err = readMboxes(source)
+	}
+	if err != nil {
+		return err
+	}
+
+	inputs := mailDocuments(source, messages)
+	failed := 0
+	for start := 0; start < len(inputs); start += defaultBatchSize {
+		end := min(start+defaultBatchSize, len(inputs))
+		results, err := idx.IndexDocuments(ctx, inputs[start:end])
+		if err != nil {
+			return err
+		}
+		for _, result := range results {
+			if result.Status == StatusError {
+				failed++
+				fmt.Fprintf(os.Stderr, "warning: skipping message %s: %s\n", result.ID, result.Error)
+			}
+		}
+	}
+	fmt.Printf("Mail source %s: %d messages indexed, %d skipped\n", source.label(), len(inputs)-failed, failed)
+	return nil
+}
+
+// readMboxes parses the mbox files of a source. Root may be a single file or
+// a directory of mbox files.
+func readMboxes(source *Source) ([]mailMessage, error) {
+	var messages []mailMessage
+	readFile := func(path, rel string) error {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return mailbox.ReadMbox(f, func(index int, msg *mailbox.Message, err error) error {
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "warning: skipping message %d of %s: %v\n", index, rel, err)
+				return nil
+			}
+			messages = append(messages, mailMessage{Message: msg, Location: fmt.Sprintf("%s#%d", rel, index)})
+			return nil
+		})
+	}
+
+	info, err := os.Stat(source.Root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return messages, readFile(source.Root, filepath.Base(source.Root))
+	}
+
+	err = filepath.WalkDir(source.Root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(source.Root, path)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if rel != "." && source.excludes(rel) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || !source.matches(rel, mailIncludes) {
+			return nil
+		}
+		return readFile(path, filepath.ToSlash(rel))
+	})
+	return messages, err
+}
+
+// readMaildir parses the messages of every Maildir folder under the source root.
+func readMaildir(source *Source) ([]mailMessage, error) {
+	files, err := mailbox.MaildirFiles(source.Root)
+	if err != nil {
+		return nil, err
+	}
+	messages := make([]mailMessage, 0, len(files))
+	for _, path := range files {
+		rel, err := filepath.Rel(source.Root, path)
+		if err != nil {
+			return nil, err
+		}
+		if source.excludes(rel) {
+			continue
+		}
+		msg, err := mailbox.ReadMaildirFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: skipping message %s: %v\n", rel, err)
+			continue
+		}
+		// Maildir flags (":2,S") change when a message is read, so they are
+		// not part of its location.
+		location, _, _ := strings.Cut(filepath.ToSlash(rel), ":")
+		messages = append(messages, mailMessage{Message: msg, Location: location})
+	}
+	return messages, nil
+}
+
+// mailDocuments builds one document per message. Messages without a
+// Message-ID get a stable ID derived from where they were found.
+func mailDocuments(source *Source, messages []mailMessage) []DocumentInput {
+	all := make([]*mailbox.Message, len(messages))
+	for i := range messages {
+		if messages[i].ID == "" {
+			sum := sha1.Sum([]byte(source.label() + "\x00" + messages[i].Location))
+			messages[i].ID = "generated-" + hex.EncodeToString(sum[:8])
+		}
+		all[i] = messages[i].Message
+	}
+	threads := mailbox.Threads(all)
+
+	inputs := make([]DocumentInput, 0, len(messages))
+	for _, msg := range messages {
+		recipients := strings.Join(append(append([]string{}, msg.To...), msg.Cc...), ", ")
+		fields := map[string]string{
+			"subject": msg.Subject,
+			"from":    msg.From,
+			"to":      recipients,
+		}
+		metadata := map[string]string{
+			"message_id": msg.ID,
+			"thread_id":  threads[msg.ID],
+			"from":       msg.From,
+			"to":         recipients,
+			"mailbox":    msg.Location,
+		}
+		if msg.InReplyTo != "" {
+			metadata["in_reply_to"] = msg.InReplyTo
+		}
+		if len(msg.Attachments) > 0 {
+			metadata["attachments"] = strings.Join(msg.Attachments, ", ")
+		}
+		if !msg.Date.IsZero() {
+			fields["date"] = msg.Date.Format("Monday 2 January 2006")
+			metadata["date"] = msg.Date.Format(time.RFC3339)
+		}
+
+		title := msg.Subject
+		if title == "" {
+			title = "(no subject)"
+		}
+		inputs = append(inputs, DocumentInput{
+			ID:         "mail:" + source.label() + ":" + msg.ID,
+			Title:      title,
+			Body:       msg.Body,
+			Fields:     fields,
+			Metadata:   metadata,
+			Language:   source.Language,
+			Label:      source.label(),
+			SourceType: SourceTypeMail,
+			ModifiedAt: msg.Date,
+		})
+	}
+	return inputs
+}
@@ -0,0 +1,48 @@
+package indexer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMailDocuments(t *testing.T) {
+	dir := t.TempDir()
+	mbox := "From a@example.com Mon Jan  6 10:00:00 2025\n" +
+		"Message-ID: <root@example.com>\nFrom: Ana <ana@example.com>\nTo: dev@example.com\n" +
+		"Subject: Release\nDate: Mon, 6 Jan 2025 10:00:00 +0000\n\nShipping today.\n\n" +
+		"From b@example.com Mon Jan  6 11:00:00 2025\n" +
+		"In-Reply-To: <root@example.com>\nFrom: bob@example.com\nCc: ana@example.com\n" +
+		"Subject: Re: Release\n\nGreat news.\n"
+	if err := os.WriteFile(filepath.Join(dir, "dev.mbox"), []byte(mbox), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	source := &Source{Type: SourceTypeMbox, Root: dir, Label: "lists"}
+	messages, err := readMboxes(source)
+	if err != nil {
+		t.Fatalf("readMboxes returned an error: %v", err)
+	}
+	inputs := mailDocuments(source, messages)
+	if len(inputs) != 2 {
+		t.Fatalf("Expected 2 documents, got %d", len(inputs))
+	}
+
+	root, reply := inputs[0], inputs[1]
+	if root.ID != "mail:lists:root@example.com" || root.Title != "Release" || root.SourceType != SourceTypeMail {
+		t.Errorf("Unexpected root document: %+v", root)
+	}
+	if root.Fields["from"] != "Ana <ana@example.com>" || root.Fields["date"] != "Monday 6 January 2025" {
+		t.Errorf("Unexpected root fields: %v", root.Fields)
+	}
+	if !strings.HasPrefix(reply.ID, "mail:lists:generated-") || reply.Fields["to"] != "ana@example.com" {
+		t.Errorf("Unexpected reply document: %+v", reply)
+	}
+	if root.Metadata["thread_id"] != "root@example.com" || reply.Metadata["thread_id"] != "root@example.com" {
+		t.Errorf("Expected both messages in the root thread, got %q and %q", root.Metadata["thread_id"], reply.Metadata["thread_id"])
+	}
+	if reply.Metadata["mailbox"] != "dev.mbox#1" {
+		t.Errorf("Expected mailbox location dev.mbox#1, got %q", reply.Metadata["mailbox"])
+	}
+}
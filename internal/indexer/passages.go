@@ -0,0 +1,199 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/TonyGLL/gofetch/pkg/storage"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Passage splitting modes.
+const (
+	PassageModeHeading = "heading" // One passage per markdown section, windowed when too long
+	PassageModeWindow  = "window"  // Overlapping windows of a fixed number of tokens
+)
+
+// defaultPassageSize is the number of words per passage when none is configured.
+const defaultPassageSize = 200
+
+var wordPattern = regexp.MustCompile(`\S+`)
+
+// PassageOptions configures how long documents are split into passages.
+// An empty Mode disables passages.
+type PassageOptions struct {
+	Mode    string
+	Size    int // Maximum words per passage
+	Overlap int // Words shared by consecutive windows
+}
+
+func (o PassageOptions) enabled() bool {
+	return o.Mode == PassageModeHeading || o.Mode == PassageModeWindow
+}
+
+func (o PassageOptions) size() int {
+	if o.Size <= 0 {
+		return defaultPassageSize
+	}
+	return o.Size
+}
+
+// step is how many words a window advances over the previous one.
+func (o PassageOptions) step() int {
+	overlap := max(o.Overlap, 0)
+	if overlap >= o.size() {
+		return 1
+	}
+	return o.size() - overlap
+}
+
+// passage is a span of a document's content, delimited by byte offsets.
+type passage struct {
+	Heading string
+	Start   int
+	End     int
+}
+
+// SetPassageOptions enables passage indexing for documents indexed afterwards.
+func (idx *Indexer) SetPassageOptions(opts PassageOptions) {
+	idx.passages = opts
+}
+
+// splitPassages splits text into passages. It returns nil when the text fits
+// in a single passage, since that passage would duplicate the document.
+func splitPassages(text string, opts PassageOptions) []passage {
+	if !opts.enabled() {
+		return nil
+	}
+
+	var passages []passage
+	if opts.Mode == PassageModeHeading {
+		for _, section := range splitSections(text) {
+			for _, w := range splitWindows(text[section.Start:section.End], opts) {
+				passages = append(passages, passage{
+					Heading: section.Heading,
+					Start:   section.Start + w.Start,
+					End:     section.Start + w.End,
+				})
+			}
+		}
+	} else {
+		passages = splitWindows(text, opts)
+	}
+
+	if len(passages) <= 1 {
+		return nil
+	}
+	return passages
+}
+
+// splitSections splits markdown text at its ATX headings. Each section starts
+// with its heading line.
+func splitSections(text string) []passage {
+	var sections []passage
+	current := passage{}
+	inFence := false
+	offset := 0
+	for _, line := range strings.SplitAfter(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inFence = !inFence
+		} else if headings := markdownHeadings(trimmed); !inFence && len(headings) == 1 {
+			if offset > current.Start {
+				current.End = offset
+				sections = append(sections, current)
+			}
+			current = passage{Heading: headings[0], Start: offset}
+		}
+		offset += len(line)
+	}
+	current.End = len(text)
+	sections = append(sections, current)
+
+	nonEmpty := sections[:0]
+	for _, s := range sections {
+		if strings.TrimSpace(text[s.Start:s.End]) != "" {
+			nonEmpty = append(nonEmpty, s)
+		}
+	}
+	return nonEmpty
+}
+
+// splitWindows splits text into overlapping windows of whole words.
+func splitWindows(text string, opts PassageOptions) []passage {
+	words := wordPattern.FindAllStringIndex(text, -1)
+	if len(words) == 0 {
+		return nil
+	}
+	size, step := opts.size(), opts.step()
+
+	var windows []passage
+	for start := 0; start < len(words); start += step {
+		end := start + size
+		if end > len(words) {
+			end = len(words)
+		}
+		windows = append(windows, passage{Start: words[start][0], End: words[end-1][1]})
+		if end == len(words) {
+			break
+		}
+	}
+	return windows
+}
+
+// removePassages deletes the passages of a document and their postings.
+func (idx *Indexer) removePassages(ctx context.Context, parentID primitive.ObjectID) error {
+	passages, err := idx.mongo_store.GetDocumentsByParent(ctx, parentID)
+	if err != nil {
+		return fmt.Errorf("error finding passages: %w", err)
+	}
+	for _, p := range passages {
+		if err := idx.mongo_store.RemovePostingsForDocument(ctx, p.ID, p.Terms); err != nil {
+			return fmt.Errorf("error removing passage postings: %w", err)
+		}
+	}
+	if err := idx.mongo_store.DeleteDocumentsByParent(ctx, parentID); err != nil {
+		return fmt.Errorf("error deleting passages: %w", err)
+	}
+	return nil
+}
+
+// passagePayloads builds one payload per passage of a document. Passage terms
+// are stored in the passage field so they do not affect document scoring.
+func (idx *Indexer) passagePayloads(parent *storage.Document, analyzerName string) []*indexPayload {
+	spans := splitPassages(parent.Content, idx.passages)
+	if len(spans) == 0 {
+		return nil
+	}
+
+	analyzer := idx.analyzer.ForField(storage.FieldBody, analyzerName)
+	payloads := make([]*indexPayload, 0, len(spans))
+	for _, span := range spans {
+		title := parent.Title
+		if span.Heading != "" {
+			title = span.Heading
+		}
+		doc := storage.Document{
+			ID:         primitive.NewObjectID(),
+			SourceType: storage.SourceTypePassage,
+			URL:        parent.URL,
+			Title:      title,
+			Content:    parent.Content[span.Start:span.End],
+			IndexedAt:  parent.IndexedAt,
+			ModifiedAt: parent.ModifiedAt,
+			Language:   parent.Language,
+			Analyzer:   analyzer.Name(),
+			Label:      parent.Label,
+			ParentID:   parent.ID,
+			Start:      span.Start,
+			End:        span.End,
+		}
+		counts := newTermCounts()
+		counts.add(fieldText{field: storage.FieldPassage, analyzer: analyzer, text: doc.Content})
+		payloads = append(payloads, newPayload(doc, counts))
+	}
+	return payloads
+}
@@ -0,0 +1,52 @@
+package indexer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitPassages(t *testing.T) {
+	markdown := "# Plan\nintro words here\n\n## Crawler\nrobots txt rules\n```\n# not a heading\n```\n## Indexer\ninverted index\n"
+
+	testCases := []struct {
+		name     string
+		text     string
+		opts     PassageOptions
+		expected []string
+	}{
+		{
+			name: "Heading mode splits at markdown sections",
+			text: markdown,
+			opts: PassageOptions{Mode: PassageModeHeading},
+			expected: []string{
+				"# Plan\nintro words here",
+				"## Crawler\nrobots txt rules\n```\n# not a heading\n```",
+				"## Indexer\ninverted index",
+			},
+		},
+		{
+			name:     "Window mode overlaps consecutive windows",
+			text:     "one two three four five six",
+			opts:     PassageOptions{Mode: PassageModeWindow, Size: 3, Overlap: 1},
+			expected: []string{"one two three", "three four five", "five six"},
+		},
+		{
+			name:     "Short text yields no passages",
+			text:     "one two",
+			opts:     PassageOptions{Mode: PassageModeWindow, Size: 3},
+			expected: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []string
+			for _, p := range splitPassages(tc.text, tc.opts) {
+				got = append(got, tc.text[p.Start:p.End])
+			}
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("Expected passages %q, but got %q", tc.expected, got)
+			}
+		})
+	}
+}
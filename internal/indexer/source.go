@@ -0,0 +1,108 @@
+package indexer
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// defaultIncludes are the file patterns indexed when a source has no include rules.
+var defaultIncludes = []string{"*.txt", "*.md"}
+
+// Source types. Directory sources are the default.
+const (
+	SourceTypeDir     = "dir"
+	SourceTypeGit     = "git"
+	SourceTypeMbox    = "mbox"
+	SourceTypeMaildir = "maildir"
+)
+
+// Source is a tree of files indexed under a common label. For git sources,
+// Root is a local clone and files are read at Ref instead of from disk.
+type Source struct {
+	Type     string
+	Root     string
+	Label    string
+	Include  []string
+	Exclude  []string
+	Language string
+	Analyzer string // Named analyzer for the source's documents; defaults to their language
+	Ref      string // Git only: branch, tag or commit to index; defaults to HEAD
+	Commits  bool   // Git only: also index commit messages as documents
+}
+
+// label returns the source label, defaulting to the name of the root directory.
+func (s *Source) label() string {
+	if s.Label != "" {
+		return s.Label
+	}
+	return filepath.Base(filepath.Clean(s.Root))
+}
+
+// includes reports whether a file, given by its path relative to Root, should be indexed.
+func (s *Source) includes(rel string) bool {
+	return s.matches(rel, defaultIncludes)
+}
+
+// matches is like includes, with the patterns used when the source has no include rules.
+func (s *Source) matches(rel string, defaults []string) bool {
+	if s.excludes(rel) {
+		return false
+	}
+	patterns := s.Include
+	if len(patterns) == 0 {
+		patterns = defaults
+	}
+	for _, pattern := range patterns {
+		if matchGlob(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// excludes reports whether a file or directory relative to Root, or any of its
+// parent directories, matches an exclude rule.
+func (s *Source) excludes(rel string) bool {
+	for p := filepath.ToSlash(rel); p != "." && p != "/"; p = path.Dir(p) {
+		for _, pattern := range s.Exclude {
+			if matchGlob(pattern, p) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchGlob matches a slash-separated relative path against a glob pattern.
+// Patterns without a slash match the base name at any depth (like .gitignore);
+// "**" matches any number of directories.
+func matchGlob(pattern, rel string) bool {
+	rel = filepath.ToSlash(rel)
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(path.Base(rel)))
+		return ok
+	}
+	return matchSegments(strings.Split(strings.Trim(pattern, "/"), "/"), strings.Split(rel, "/"))
+}
+
+func matchSegments(pattern, parts []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(parts); i++ {
+				if matchSegments(pattern[1:], parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(parts) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], parts[0]); !ok {
+			return false
+		}
+		pattern, parts = pattern[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
@@ -0,0 +1,32 @@
+package indexer
+
+import "testing"
+
+func TestSource_Includes(t *testing.T) {
+	source := &Source{
+		Root:    "docs",
+		Include: []string{"*.md", "runbooks/**/*.txt"},
+		Exclude: []string{"drafts", "**/*.tmp.md"},
+	}
+
+	testCases := []struct {
+		rel      string
+		expected bool
+	}{
+		{rel: "README.md", expected: true},
+		{rel: "guides/setup.MD", expected: true},
+		{rel: "notes.txt", expected: false},
+		{rel: "runbooks/db/restore.txt", expected: true},
+		{rel: "runbooks/restore.txt", expected: true},
+		{rel: "drafts/idea.md", expected: false},
+		{rel: "guides/wip.tmp.md", expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.rel, func(t *testing.T) {
+			if got := source.includes(tc.rel); got != tc.expected {
+				t.Errorf("Expected includes(%q) = %v, but got %v", tc.rel, tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package mailbox
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ReadMbox splits an mbox file into messages and calls fn for each of them,
+// in file order. Message bodies escaped with ">From " (mboxrd) are unescaped.
+// Messages that cannot be parsed are passed to fn with a nil message and
+// their parse error; returning an error from fn stops the iteration.
+func ReadMbox(r io.Reader, fn func(index int, msg *Message, err error) error) error {
+	br := bufio.NewReader(r)
+	var buf bytes.Buffer
+	index := 0
+	started := false
+	prevBlank := true
+
+	flush := func() error {
+		if !started {
+			return nil
+		}
+		msg, err := Parse(bytes.NewReader(buf.Bytes()))
+		buf.Reset()
+		if err := fn(index, msg, err); err != nil {
+			return err
+		}
+		index++
+		return nil
+	}
+
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			switch {
+			case prevBlank && bytes.HasPrefix(line, []byte("From ")):
+				if err := flush(); err != nil {
+					return err
+				}
+				started = true
+			case started:
+				if isEscapedFrom(line) {
+					line = line[1:]
+				}
+				buf.Write(line)
+			}
+			prevBlank = len(bytes.TrimRight(line, "\r\n")) == 0
+		}
+		if errors.Is(err, io.EOF) {
+			return flush()
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// isEscapedFrom reports whether a line is a quoted "From " line (">From ",
+// ">>From ", ...).
+func isEscapedFrom(line []byte) bool {
+	trimmed := bytes.TrimLeft(line, ">")
+	return len(trimmed) < len(line) && bytes.HasPrefix(trimmed, []byte("From "))
+}
+
+// MaildirFiles lists the message files of every Maildir folder under root
+// (any directory holding "cur" and "new"), sorted by path.
+func MaildirFiles(root string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() || !isMaildir(path) {
+			return nil
+		}
+		for _, sub := range []string{"cur", "new"} {
+			entries, err := os.ReadDir(filepath.Join(path, sub))
+			if err != nil {
+				return err
+			}
+			for _, entry := range entries {
+				if entry.Type().IsRegular() && !strings.HasPrefix(entry.Name(), ".") {
+					files = append(files, filepath.Join(path, sub, entry.Name()))
+				}
+			}
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+func isMaildir(dir string) bool {
+	for _, sub := range []string{"cur", "new"} {
+		info, err := os.Stat(filepath.Join(dir, sub))
+		if err != nil || !info.IsDir() {
+			return false
+		}
+	}
+	return true
+}
+
+// ReadMaildirFile parses one Maildir message file.
+func ReadMaildirFile(path string) (*Message, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
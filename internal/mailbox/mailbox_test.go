@@ -0,0 +1,154 @@
+package mailbox
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testMbox = `From ana@example.com Mon Jan  6 10:00:00 2025
+Message-ID: <root@example.com>
+From: Ana <ana@example.com>
+To: dev@lists.example.com
+Subject: =?UTF-8?Q?Migraci=C3=B3n?= plan
+Date: Mon, 6 Jan 2025 10:00:00 +0000
+Content-Type: text/plain; charset=utf-8
+
+Let's migrate the cluster.
+>From now on, deploys are frozen.
+
+From bob@example.com Mon Jan  6 11:00:00 2025
+Message-ID: <reply@example.com>
+In-Reply-To: <root@example.com>
+References: <root@example.com>
+From: =?ISO-8859-1?Q?Jos=E9?= <jose@example.com>
+To: dev@lists.example.com, Ana <ana@example.com>
+Subject: Re: Migration plan
+Date: Mon, 6 Jan 2025 11:00:00 +0000
+MIME-Version: 1.0
+Content-Type: multipart/mixed; boundary="outer"
+
+--outer
+Content-Type: multipart/alternative; boundary="inner"
+
+--inner
+Content-Type: text/plain; charset=iso-8859-1
+Content-Transfer-Encoding: quoted-printable
+
+Sounds good, se=F1or.
+--inner
+Content-Type: text/html; charset=utf-8
+
+<p>Sounds good, se&ntilde;or.</p>
+--inner--
+--outer
+Content-Type: application/pdf; name="plan.pdf"
+Content-Disposition: attachment; filename="plan.pdf"
+Content-Transfer-Encoding: base64
+
+JVBERi0xLjQK
+--outer--
+
+From carol@example.com Mon Jan  6 12:00:00 2025
+Message-ID: <deep@example.com>
+In-Reply-To: <reply@example.com>
+From: carol@example.com
+Subject: Re: Migration plan
+Date: Mon, 6 Jan 2025 12:00:00 +0000
+Content-Type: text/html; charset=utf-8
+Content-Transfer-Encoding: base64
+
+PGh0bWw+PGJvZHk+PHA+SSB3aWxsIHVwZGF0ZSB0aGUgPGI+cnVuYm9vazwvYj4uPC9wPjwvYm9k
+eT48L2h0bWw+
+`
+
+func readTestMbox(t *testing.T) []*Message {
+	t.Helper()
+	var messages []*Message
+	err := ReadMbox(strings.NewReader(testMbox), func(index int, msg *Message, err error) error {
+		if err != nil {
+			t.Fatalf("message %d: %v", index, err)
+		}
+		messages = append(messages, msg)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ReadMbox returned an error: %v", err)
+	}
+	return messages
+}
+
+func TestReadMbox_DecodesMessages(t *testing.T) {
+	messages := readTestMbox(t)
+	if len(messages) != 3 {
+		t.Fatalf("Expected 3 messages, got %d", len(messages))
+	}
+
+	root := messages[0]
+	if root.Subject != "Migración plan" || root.From != "Ana <ana@example.com>" {
+		t.Errorf("Unexpected headers: %q from %q", root.Subject, root.From)
+	}
+	if !strings.Contains(root.Body, "\nFrom now on") {
+		t.Errorf("Expected the escaped From line to be restored, got %q", root.Body)
+	}
+
+	reply := messages[1]
+	if reply.From != "José <jose@example.com>" || !reflect.DeepEqual(reply.To, []string{"dev@lists.example.com", "Ana <ana@example.com>"}) {
+		t.Errorf("Unexpected addresses: from %q to %v", reply.From, reply.To)
+	}
+	if reply.Body != "Sounds good, señor." {
+		t.Errorf("Expected only the plain text alternative, got %q", reply.Body)
+	}
+	if !reflect.DeepEqual(reply.Attachments, []string{"plan.pdf"}) {
+		t.Errorf("Expected plan.pdf attachment, got %v", reply.Attachments)
+	}
+
+	if messages[2].Body != "I will update the runbook." {
+		t.Errorf("Expected the HTML body as text, got %q", messages[2].Body)
+	}
+}
+
+func TestThreads(t *testing.T) {
+	messages := readTestMbox(t)
+	messages = append(messages, &Message{ID: "orphan@example.com", InReplyTo: "missing@example.com", References: []string{"first@example.com", "missing@example.com"}})
+
+	threads := Threads(messages)
+	expected := map[string]string{
+		"root@example.com":   "root@example.com",
+		"reply@example.com":  "root@example.com",
+		"deep@example.com":   "root@example.com",
+		"orphan@example.com": "first@example.com",
+	}
+	if !reflect.DeepEqual(threads, expected) {
+		t.Errorf("Expected threads %v, got %v", expected, threads)
+	}
+}
+
+func TestMaildirFiles(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"cur", "new", "tmp", ".Lists/cur", ".Lists/new"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	message := "Message-ID: <m1@example.com>\nSubject: Hello\n\nBody\n"
+	for _, name := range []string{"cur/1.host:2,S", "new/2.host", "tmp/3.host", ".Lists/cur/4.host:2,"} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(message), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := MaildirFiles(root)
+	if err != nil {
+		t.Fatalf("MaildirFiles returned an error: %v", err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("Expected 3 message files outside tmp, got %v", files)
+	}
+	msg, err := ReadMaildirFile(files[0])
+	if err != nil || msg.Subject != "Hello" || msg.Body != "Body" {
+		t.Errorf("Unexpected message %+v (err %v)", msg, err)
+	}
+}
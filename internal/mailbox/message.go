@@ -0,0 +1,327 @@
+// Package mailbox reads email archives stored as mbox files or Maildir
+// directories and decodes their messages into plain text.
+package mailbox
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// maxPartDepth bounds the nesting of multipart bodies.
+const maxPartDepth = 10
+
+// Message is a decoded email message.
+type Message struct {
+	ID          string // Message-ID without angle brackets
+	InReplyTo   string
+	References  []string // Oldest first, as in the References header
+	Subject     string
+	From        string
+	To          []string
+	Cc          []string
+	Date        time.Time
+	Body        string   // Text of the message; HTML-only messages are converted
+	Attachments []string // File names of the attachments, which are not decoded
+}
+
+// Parent returns the ID of the message this one replies to, if any.
+func (m *Message) Parent() string {
+	if m.InReplyTo != "" {
+		return m.InReplyTo
+	}
+	if len(m.References) > 0 {
+		return m.References[len(m.References)-1]
+	}
+	return ""
+}
+
+var wordDecoder = &mime.WordDecoder{CharsetReader: charsetReader}
+
+// Parse reads a single RFC 5322 message.
+func Parse(r io.Reader) (*Message, error) {
+	raw, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, err
+	}
+	h := raw.Header
+
+	m := &Message{
+		ID:         firstMessageID(h.Get("Message-Id")),
+		InReplyTo:  firstMessageID(h.Get("In-Reply-To")),
+		References: messageIDs(h.Get("References")),
+		Subject:    decodeHeader(h.Get("Subject")),
+		From:       strings.Join(addressList(h.Get("From")), ", "),
+		To:         addressList(h.Get("To")),
+		Cc:         addressList(h.Get("Cc")),
+	}
+	if date, err := h.Date(); err == nil {
+		m.Date = date
+	}
+
+	var texts, htmls []string
+	if err := m.readPart(h, raw.Body, &texts, &htmls, 0); err != nil {
+		return nil, err
+	}
+	if len(texts) > 0 {
+		m.Body = strings.Join(texts, "\n\n")
+	} else {
+		m.Body = strings.Join(htmls, "\n\n")
+	}
+	return m, nil
+}
+
+// partHeader is the subset of a MIME header needed to decode a part.
+type partHeader interface {
+	Get(key string) string
+}
+
+// readPart decodes a message part, collecting plain text and HTML bodies
+// separately so alternatives are not indexed twice.
+func (m *Message) readPart(h partHeader, body io.Reader, texts, htmls *[]string, depth int) error {
+	mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+
+	if disposition, dparams, err := mime.ParseMediaType(h.Get("Content-Disposition")); err == nil {
+		if disposition == "attachment" {
+			name := decodeHeader(dparams["filename"])
+			if name == "" {
+				name = decodeHeader(params["name"])
+			}
+			m.Attachments = append(m.Attachments, name)
+			return nil
+		}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		if depth >= maxPartDepth || params["boundary"] == "" {
+			return nil
+		}
+		var partTexts, partHTMLs []string
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextRawPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("reading %s: %w", mediaType, err)
+			}
+			if err := m.readPart(part.Header, part, &partTexts, &partHTMLs, depth+1); err != nil {
+				return err
+			}
+		}
+		if mediaType == "multipart/alternative" && len(partTexts) > 0 {
+			partHTMLs = nil // The plain text alternative is enough
+		}
+		*texts = append(*texts, partTexts...)
+		*htmls = append(*htmls, partHTMLs...)
+		return nil
+	}
+
+	if mediaType == "message/rfc822" && depth < maxPartDepth {
+		inner, err := mail.ReadMessage(decodeTransfer(h.Get("Content-Transfer-Encoding"), body))
+		if err != nil {
+			return nil // A malformed forwarded message is not fatal
+		}
+		return m.readPart(inner.Header, inner.Body, texts, htmls, depth+1)
+	}
+
+	if mediaType != "text/plain" && mediaType != "text/html" {
+		if name := decodeHeader(params["name"]); name != "" {
+			m.Attachments = append(m.Attachments, name)
+		}
+		return nil
+	}
+
+	data, err := io.ReadAll(decodeTransfer(h.Get("Content-Transfer-Encoding"), body))
+	if err != nil {
+		return err
+	}
+	text := decodeCharset(params["charset"], data)
+	if mediaType == "text/html" {
+		if text = htmlText(text); text != "" {
+			*htmls = append(*htmls, text)
+		}
+		return nil
+	}
+	if text = strings.TrimSpace(text); text != "" {
+		*texts = append(*texts, text)
+	}
+	return nil
+}
+
+// decodeTransfer undoes the Content-Transfer-Encoding of a part.
+func decodeTransfer(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, &base64Cleaner{r: r})
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	default:
+		return r
+	}
+}
+
+// base64Cleaner drops the line breaks and padding whitespace found in
+// base64 bodies, which the standard decoder rejects.
+type base64Cleaner struct {
+	r io.Reader
+}
+
+func (c *base64Cleaner) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	j := 0
+	for _, b := range p[:n] {
+		if b != '\r' && b != '\n' && b != ' ' && b != '\t' {
+			p[j] = b
+			j++
+		}
+	}
+	return j, err
+}
+
+// decodeCharset converts text in the given charset to UTF-8. Unknown
+// charsets are passed through unchanged.
+func decodeCharset(charset string, data []byte) string {
+	charset = strings.ToLower(strings.TrimSpace(charset))
+	if charset == "" || charset == "utf-8" || charset == "us-ascii" {
+		return string(data)
+	}
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return string(data)
+	}
+	decoded, err := enc.NewDecoder().Bytes(data)
+	if err != nil {
+		return string(data)
+	}
+	return string(decoded)
+}
+
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return nil, err
+	}
+	return enc.NewDecoder().Reader(input), nil
+}
+
+// decodeHeader decodes RFC 2047 encoded words, keeping the raw value when
+// it cannot be decoded.
+func decodeHeader(value string) string {
+	decoded, err := wordDecoder.DecodeHeader(value)
+	if err != nil {
+		return strings.TrimSpace(value)
+	}
+	return strings.TrimSpace(decoded)
+}
+
+// addressList decodes an address list header into "Name <addr>" strings.
+func addressList(value string) []string {
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+	parser := mail.AddressParser{WordDecoder: wordDecoder}
+	list, err := parser.ParseList(value)
+	if err != nil {
+		return []string{decodeHeader(value)}
+	}
+	formatted := make([]string, len(list))
+	for i, addr := range list {
+		if addr.Name != "" {
+			formatted[i] = addr.Name + " <" + addr.Address + ">"
+		} else {
+			formatted[i] = addr.Address
+		}
+	}
+	return formatted
+}
+
+// messageIDs extracts the <id> tokens of a Message-ID style header.
+func messageIDs(value string) []string {
+	var ids []string
+	for {
+		open := strings.IndexByte(value, '<')
+		if open < 0 {
+			break
+		}
+		closing := strings.IndexByte(value[open:], '>')
+		if closing < 0 {
+			break
+		}
+		if id := strings.TrimSpace(value[open+1 : open+closing]); id != "" {
+			ids = append(ids, id)
+		}
+		value = value[open+closing+1:]
+	}
+	return ids
+}
+
+// firstMessageID returns the first ID of a header, accepting IDs written
+// without angle brackets.
+func firstMessageID(value string) string {
+	if ids := messageIDs(value); len(ids) > 0 {
+		return ids[0]
+	}
+	return strings.TrimSpace(value)
+}
+
+// htmlText extracts the visible text of an HTML body.
+func htmlText(html string) string {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader([]byte(html)))
+	if err != nil {
+		return ""
+	}
+	doc.Find("script, style, head").Remove()
+	return strings.Join(strings.Fields(doc.Text()), " ")
+}
+
+// Threads maps the ID of every message to the ID of the root of its thread.
+// Replies are followed through the messages given; when the parent is not
+// among them the oldest reference is used as the root.
+func Threads(messages []*Message) map[string]string {
+	parents := make(map[string]string, len(messages))
+	oldest := make(map[string]string, len(messages))
+	for _, m := range messages {
+		if m.ID == "" {
+			continue
+		}
+		parents[m.ID] = m.Parent()
+		if len(m.References) > 0 {
+			oldest[m.ID] = m.References[0]
+		}
+	}
+
+	roots := make(map[string]string, len(messages))
+	for id := range parents {
+		root := id
+		seen := map[string]bool{}
+		for parents[root] != "" && !seen[root] {
+			seen[root] = true
+			parent := parents[root]
+			if _, known := parents[parent]; !known {
+				if ref := oldest[root]; ref != "" {
+					parent = ref
+				}
+				root = parent
+				break
+			}
+			root = parent
+		}
+		roots[id] = root
+	}
+	return roots
+}
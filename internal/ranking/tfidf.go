@@ -40,6 +40,64 @@ func (s *TFIDFScorer) Score(queryTerms []string, postings map[string]storage.Inv
 	return docScores
 }
 
+// ScoreFields calculates the TF-IDF score of each field separately and sums
+// them weighted by the field boosts. Postings must be keyed with storage.FieldTerm.
+func (s *TFIDFScorer) ScoreFields(queryTerms []string, boosts map[string]float64, postings map[string]storage.InvertedIndexEntry) map[string]float64 {
+	fieldTerms := make(map[string][]string, len(boosts))
+	for field := range boosts {
+		fieldTerms[field] = queryTerms
+	}
+	return s.ScoreFieldTerms(fieldTerms, boosts, postings)
+}
+
+// ScoreFieldTerms is ScoreFields for queries analyzed separately for every
+// field, so each field is scored with its own query terms.
+func (s *TFIDFScorer) ScoreFieldTerms(fieldTerms map[string][]string, boosts map[string]float64, postings map[string]storage.InvertedIndexEntry) map[string]float64 {
+	docScores := make(map[string]float64)
+
+	for field, boost := range boosts {
+		for _, term := range fieldTerms[field] {
+			entry, ok := postings[storage.FieldTerm(field, term)]
+			if !ok {
+				continue // Term not in this field
+			}
+
+			idf := s.calculateIDF(entry.DF)
+
+			for _, post := range entry.Postings {
+				docScores[post.DocID.Hex()] += boost * float64(post.Frequency) * idf
+			}
+		}
+	}
+
+	return docScores
+}
+
+// IDF returns the Inverse Document Frequency of a term found in docFrequency
+// documents, for matches scored outside the scorer such as phrases.
+func (s *TFIDFScorer) IDF(docFrequency int) float64 {
+	return s.calculateIDF(docFrequency)
+}
+
+// ProximityWindow is the largest distance, in positions, between two
+// consecutive query terms in a document that earns a proximity bonus.
+const ProximityWindow = 5
+
+// proximityWeight scales the proximity bonus: two adjacent query terms add
+// half their IDFs to the score.
+const proximityWeight = 0.5
+
+// ScoreProximity returns the bonus of a document in which two consecutive
+// query terms with the given IDFs are distance positions apart. The closer
+// and rarer the terms, the higher the bonus; terms further apart than
+// ProximityWindow earn none.
+func (s *TFIDFScorer) ScoreProximity(distance int, idfA, idfB float64) float64 {
+	if distance < 1 || distance > ProximityWindow {
+		return 0
+	}
+	return proximityWeight * (idfA + idfB) / float64(distance)
+}
+
 // calculateIDF calculates the Inverse Document Frequency for a term.
 func (s *TFIDFScorer) calculateIDF(docFrequency int) float64 {
 	if docFrequency == 0 || s.TotalDocuments == 0 {
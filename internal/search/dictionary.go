@@ -0,0 +1,243 @@
+package search
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TonyGLL/gofetch/pkg/storage"
+)
+
+// DefaultMaxExpansions is the number of terms a wildcard, regular
+// expression or fuzzy word expands to at most, when not configured.
+const DefaultMaxExpansions = 1000
+
+// unexpandedFields hold terms that are not words, such as prefixes and
+// phonetic codes: patterns are not expanded in them, and the dictionary does
+// not load their terms.
+var unexpandedFields = []string{storage.FieldPrefix, storage.FieldPhonetic, storage.FieldPassage}
+
+// indexedFields are the standard fields whose keys are prefixed with their
+// name, told apart from body terms when expanding patterns in the body.
+var indexedFields = map[string]bool{
+	storage.FieldTitle:    true,
+	storage.FieldHeadings: true,
+	storage.FieldURL:      true,
+	storage.FieldComments: true,
+	storage.FieldStrings:  true,
+	storage.FieldPrefix:   true,
+	storage.FieldPhonetic: true,
+	storage.FieldPassage:  true,
+	storage.FieldCustom:   true,
+}
+
+// termPattern matches the terms of a wildcard or regular expression query.
+// Only the terms starting with its literal prefix are tried.
+type termPattern struct {
+	prefix string
+	re     *regexp.Regexp
+}
+
+// pattern compiles the wildcard pattern of q.
+func (q *WildcardQuery) pattern() (termPattern, error) {
+	return compileWildcard(q.Pattern)
+}
+
+// pattern compiles the regular expression of q.
+func (q *RegexpQuery) pattern() (termPattern, error) {
+	return compileRegexp(q.Pattern)
+}
+
+// compileWildcard compiles a wildcard pattern, where "*" matches any run of
+// characters and "?" a single one.
+func compileWildcard(pattern string) (termPattern, error) {
+	if strings.Trim(pattern, "*?") == "" {
+		return termPattern{}, errors.New("a pattern needs a character besides wildcards")
+	}
+	var expr strings.Builder
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	prefix := pattern
+	if i := strings.IndexAny(pattern, "*?"); i >= 0 {
+		prefix = pattern[:i]
+	}
+	re, err := regexp.Compile("^(?:" + expr.String() + ")$")
+	if err != nil {
+		return termPattern{}, err
+	}
+	return termPattern{prefix: prefix, re: re}, nil
+}
+
+// compileRegexp compiles a regular expression matching whole terms.
+func compileRegexp(pattern string) (termPattern, error) {
+	re, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return termPattern{}, err
+	}
+	prefix, _ := re.LiteralPrefix()
+	return termPattern{prefix: prefix, re: re}, nil
+}
+
+// TermDictionary holds the sorted terms of the index in memory, to expand
+// wildcard, regular expression and fuzzy queries. It is loaded when a search first
+// needs it and reloaded when the index changes.
+type TermDictionary struct {
+	maxExpansions int
+
+	mu      sync.RWMutex
+	terms   []storage.DictionaryTerm // Sorted by key
+	version time.Time                // When the index the terms were loaded from last changed
+	loaded  bool
+
+	treesMu sync.Mutex
+	trees   map[string]*bkTree // By field, built for fuzzy queries
+}
+
+// NewTermDictionary creates an empty dictionary whose patterns expand to at
+// most maxExpansions terms, or DefaultMaxExpansions when it is not positive.
+func NewTermDictionary(maxExpansions int) *TermDictionary {
+	if maxExpansions <= 0 {
+		maxExpansions = DefaultMaxExpansions
+	}
+	return &TermDictionary{maxExpansions: maxExpansions}
+}
+
+// refresh loads the terms of the store unless they were loaded when the
+// index was last changed at version.
+func (d *TermDictionary) refresh(ctx context.Context, store *storage.MongoStore, version time.Time) error {
+	d.mu.RLock()
+	current := d.loaded && d.version.Equal(version)
+	d.mu.RUnlock()
+	if current {
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.loaded && d.version.Equal(version) {
+		return nil // Loaded by another search meanwhile
+	}
+	terms, err := store.GetTermDictionary(ctx, unexpandedFields)
+	if err != nil {
+		return err
+	}
+	d.setTerms(terms, version)
+	return nil
+}
+
+// setTerms replaces the terms of the dictionary. The caller holds the lock.
+func (d *TermDictionary) setTerms(terms []storage.DictionaryTerm, version time.Time) {
+	slices.SortFunc(terms, func(a, b storage.DictionaryTerm) int { return strings.Compare(a.Term, b.Term) })
+	d.terms, d.version, d.loaded = terms, version, true
+	d.trees = nil
+}
+
+// expand returns the terms of a field matching a pattern. When there are
+// more than the dictionary allows, the ones in the most documents are kept.
+func (d *TermDictionary) expand(field string, p termPattern) []string {
+	if slices.Contains(unexpandedFields, field) {
+		return nil
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	var matches []storage.DictionaryTerm
+	d.scan(field, p.prefix, func(term string, df int) {
+		if p.re.MatchString(term) {
+			matches = append(matches, storage.DictionaryTerm{Term: term, DF: df})
+		}
+	})
+	if len(matches) > d.maxExpansions {
+		slices.SortStableFunc(matches, func(a, b storage.DictionaryTerm) int { return cmp.Compare(b.DF, a.DF) })
+		matches = matches[:d.maxExpansions]
+	}
+	terms := make([]string, len(matches))
+	for i, t := range matches {
+		terms[i] = t.Term
+	}
+	return terms
+}
+
+// fuzzy returns the terms of a field within distance edits of term. When
+// there are more than the dictionary allows, the closest ones are kept, and
+// among them those in the most documents.
+func (d *TermDictionary) fuzzy(field, term string, distance int) []fuzzyMatch {
+	if slices.Contains(unexpandedFields, field) {
+		return nil
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	matches := d.tree(field).search(term, distance)
+	if len(matches) > d.maxExpansions {
+		slices.SortFunc(matches, func(a, b fuzzyMatch) int {
+			return cmp.Or(cmp.Compare(a.distance, b.distance), cmp.Compare(b.df, a.df), strings.Compare(a.term, b.term))
+		})
+		matches = matches[:d.maxExpansions]
+	}
+	return matches
+}
+
+// tree returns the BK-tree of the terms of a field, building it on first
+// use. The caller holds the read lock.
+func (d *TermDictionary) tree(field string) *bkTree {
+	d.treesMu.Lock()
+	defer d.treesMu.Unlock()
+	if tree, ok := d.trees[field]; ok {
+		return tree
+	}
+	tree := &bkTree{}
+	d.scan(field, "", tree.insert)
+	if d.trees == nil {
+		d.trees = make(map[string]*bkTree)
+	}
+	d.trees[field] = tree
+	return tree
+}
+
+// scan calls fn for every term of a field starting with prefix, in order.
+// The caller holds the read lock.
+func (d *TermDictionary) scan(field, prefix string, fn func(term string, df int)) {
+	fieldPrefix := storage.FieldTerm(field, "")
+	keyPrefix := fieldPrefix + prefix
+	start, _ := slices.BinarySearchFunc(d.terms, keyPrefix, func(t storage.DictionaryTerm, key string) int {
+		return strings.Compare(t.Term, key)
+	})
+	for _, t := range d.terms[start:] {
+		if !strings.HasPrefix(t.Term, keyPrefix) {
+			break
+		}
+		if fieldPrefix == "" {
+			if name, _, ok := strings.Cut(t.Term, ":"); ok && indexedFields[name] {
+				continue // A term of another field
+			}
+		}
+		fn(t.Term[len(fieldPrefix):], t.DF)
+	}
+}
+
+// expandsTerms reports whether q has wildcard, regular expression or fuzzy
+// clauses, expanded with the term dictionary.
+func expandsTerms(q Query) bool {
+	found := false
+	walkQuery(q, false, func(leaf Query, _ bool) {
+		switch leaf.(type) {
+		case *WildcardQuery, *RegexpQuery, *FuzzyQuery:
+			found = true
+		}
+	})
+	return found
+}
@@ -0,0 +1,58 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/TonyGLL/gofetch/pkg/storage"
+)
+
+func TestTermDictionary_Expand(t *testing.T) {
+	d := NewTermDictionary(2)
+	d.setTerms([]storage.DictionaryTerm{
+		{Term: "indexer", DF: 3},
+		{Term: "index", DF: 5},
+		{Term: "indexing", DF: 1},
+		{Term: "title:index", DF: 2},
+		{Term: "title:indexes", DF: 1},
+		{Term: "prefix:ind", DF: 9},
+		{Term: "inde", DF: 1},
+		{Term: "crawler", DF: 4},
+	}, time.Time{})
+
+	testCases := []struct {
+		field    string
+		pattern  string
+		expected []string
+	}{
+		{storage.FieldBody, "index*", []string{"index", "indexer"}}, // The most frequent of three
+		{storage.FieldBody, "index?r", []string{"indexer"}},
+		{storage.FieldTitle, "index*", []string{"index", "indexes"}},
+		{storage.FieldTitle, "*es", []string{"indexes"}},
+		{storage.FieldBody, "ti*", []string{}},
+		{storage.FieldPrefix, "ind*", []string{}},
+		{storage.FieldBody, "*ler", []string{"crawler"}},
+	}
+	for _, tc := range testCases {
+		p, err := compileWildcard(tc.pattern)
+		if err != nil {
+			t.Fatalf("compileWildcard(%q) returned an error: %v", tc.pattern, err)
+		}
+		got := d.expand(tc.field, p)
+		if !reflect.DeepEqual(got, tc.expected) && !(len(got) == 0 && len(tc.expected) == 0) {
+			t.Errorf("%s expanded %q to %v, expected %v", tc.field, tc.pattern, got, tc.expected)
+		}
+	}
+
+	p, err := compileRegexp("inde(x|xing)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.prefix != "inde" {
+		t.Errorf("Expected the literal prefix %q, got %q", "inde", p.prefix)
+	}
+	if got := d.expand(storage.FieldBody, p); !reflect.DeepEqual(got, []string{"index", "indexing"}) {
+		t.Errorf("Expected the regular expression to match whole terms, got %v", got)
+	}
+}
@@ -0,0 +1,70 @@
+package search
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/TonyGLL/gofetch/pkg/storage"
+)
+
+// DefaultFieldBoosts are the fields searched when a query does not name any,
+// with the weight each field contributes to the score.
+var DefaultFieldBoosts = map[string]float64{
+	storage.FieldBody:     1,
+	storage.FieldTitle:    3,
+	storage.FieldHeadings: 2,
+	storage.FieldURL:      1.5,
+	storage.FieldComments: 1,
+	storage.FieldStrings:  0.5,
+	storage.FieldCustom:   1,
+}
+
+// PrefixBoost is the weight of the prefix field, searched by default when
+// prefixes are indexed, so whole words still rank above words they begin.
+const PrefixBoost = 0.3
+
+// PhoneticBoost is the weight of the phonetic field in sounds-like searches,
+// so exact spellings still rank above words that only sound alike.
+const PhoneticBoost = 0.5
+
+// withField returns a copy of fields that also searches field with boost,
+// or fields itself when it already names the field.
+func withField(fields map[string]float64, field string, boost float64) map[string]float64 {
+	if _, ok := fields[field]; ok {
+		return fields
+	}
+	with := make(map[string]float64, len(fields)+1)
+	for name, b := range fields {
+		with[name] = b
+	}
+	with[field] = boost
+	return with
+}
+
+// ParseFieldBoosts parses a comma-separated list of fields with optional
+// boosts, e.g. "title^3,body". Fields without a boost get a weight of 1.
+func ParseFieldBoosts(value string) (map[string]float64, error) {
+	boosts := make(map[string]float64)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		field, boostStr, hasBoost := strings.Cut(item, "^")
+		field = strings.ToLower(strings.TrimSpace(field))
+		if field == "" {
+			return nil, fmt.Errorf("invalid field %q", item)
+		}
+		boost := 1.0
+		if hasBoost {
+			var err error
+			boost, err = strconv.ParseFloat(boostStr, 64)
+			if err != nil || boost <= 0 {
+				return nil, fmt.Errorf("invalid boost for field %q", field)
+			}
+		}
+		boosts[field] = boost
+	}
+	return boosts, nil
+}
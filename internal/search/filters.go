@@ -0,0 +1,79 @@
+package search
+
+import (
+	"strings"
+
+	"github.com/TonyGLL/gofetch/pkg/storage"
+)
+
+// splitFilters removes the metadata filters from a parsed query and returns
+// them as store filters. ParseQuery only accepts filters outside parentheses,
+// so they restrict the whole query: required and optional filters must hold,
+// except that optional filters of the same kind are alternatives, and
+// excluded filters must not.
+func splitFilters(q Query) (Query, []storage.MetadataFilter) {
+	var filters []storage.MetadataFilter
+	optional := make(map[string]int) // Index in filters of the optional values of every key
+
+	add := func(f *FilterQuery, o occur) {
+		value := f.Value
+		if f.Key != storage.FilterPath {
+			value = strings.ToLower(value) // Paths are case sensitive
+		}
+		if o == occurShould {
+			if i, ok := optional[f.Key]; ok {
+				filters[i].Values = append(filters[i].Values, value)
+				return
+			}
+			optional[f.Key] = len(filters)
+		}
+		filters = append(filters, storage.MetadataFilter{Key: f.Key, Values: []string{value}, Exclude: o == occurMustNot})
+	}
+
+	var split func(q Query, o occur) Query
+	split = func(q Query, o occur) Query {
+		switch q := q.(type) {
+		case *FilterQuery:
+			add(q, o)
+			return nil
+		case *BooleanQuery:
+			keep := func(clauses []Query, o occur) []Query {
+				var kept []Query
+				for _, c := range clauses {
+					if c = split(c, o); c != nil {
+						kept = append(kept, c)
+					}
+				}
+				return kept
+			}
+			b := &BooleanQuery{Must: keep(q.Must, occurMust), Should: keep(q.Should, occurShould), MustNot: keep(q.MustNot, occurMustNot)}
+			if len(b.Must) == 0 && len(b.Should) == 0 && len(b.MustNot) == 0 {
+				return nil
+			}
+			return b
+		}
+		return q
+	}
+
+	rest := split(q, occurShould)
+	if rest == nil {
+		rest = &BooleanQuery{}
+	}
+	return rest, filters
+}
+
+// filterLanguage returns the language the query is filtered to, when it
+// requires a single one.
+func filterLanguage(filters []storage.MetadataFilter) string {
+	language := ""
+	for _, f := range filters {
+		if f.Key != storage.FilterLang || f.Exclude {
+			continue
+		}
+		if len(f.Values) != 1 || (language != "" && language != f.Values[0]) {
+			return ""
+		}
+		language = f.Values[0]
+	}
+	return language
+}
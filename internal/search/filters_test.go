@@ -0,0 +1,72 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/TonyGLL/gofetch/pkg/storage"
+)
+
+func TestSplitFilters(t *testing.T) {
+	testCases := []struct {
+		input    string
+		query    string
+		filters  []storage.MetadataFilter
+		language string
+	}{
+		{"crawler", "crawler", nil, ""},
+		{
+			"crawler site:Go.dev",
+			"crawler",
+			[]storage.MetadataFilter{{Key: storage.FilterSite, Values: []string{"go.dev"}}},
+			"",
+		},
+		{
+			"runbook ext:md ext:txt -path:docs/Archive",
+			"runbook",
+			[]storage.MetadataFilter{
+				{Key: storage.FilterExt, Values: []string{"md", "txt"}},
+				{Key: storage.FilterPath, Values: []string{"docs/Archive"}, Exclude: true},
+			},
+			"",
+		},
+		{
+			"crawler AND source:file AND lang:spanish",
+			"+crawler",
+			[]storage.MetadataFilter{
+				{Key: storage.FilterSource, Values: []string{"file"}},
+				{Key: storage.FilterLang, Values: []string{"spanish"}},
+			},
+			"spanish",
+		},
+		{
+			"crawler lang:english lang:spanish",
+			"crawler",
+			[]storage.MetadataFilter{{Key: storage.FilterLang, Values: []string{"english", "spanish"}}},
+			"",
+		},
+		{
+			"(crawler OR indexer) AND NOT source:web robots",
+			"robots (+(crawler indexer))",
+			[]storage.MetadataFilter{{Key: storage.FilterSource, Values: []string{"web"}, Exclude: true}},
+			"",
+		},
+	}
+	for _, tc := range testCases {
+		parsed, err := ParseQuery(tc.input)
+		if err != nil {
+			t.Errorf("ParseQuery(%q) returned an error: %v", tc.input, err)
+			continue
+		}
+		q, filters := splitFilters(parsed)
+		if q.String() != tc.query {
+			t.Errorf("splitFilters(%q) left the query %q, expected %q", tc.input, q.String(), tc.query)
+		}
+		if !reflect.DeepEqual(filters, tc.filters) {
+			t.Errorf("splitFilters(%q) returned the filters %+v, expected %+v", tc.input, filters, tc.filters)
+		}
+		if language := filterLanguage(filters); language != tc.language {
+			t.Errorf("filterLanguage(%q) = %q, expected %q", tc.input, language, tc.language)
+		}
+	}
+}
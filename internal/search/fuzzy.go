@@ -0,0 +1,168 @@
+package search
+
+import "strings"
+
+// FuzzyWeight scales the score of a fuzzy match for every edit it is away
+// from the query term, so exact matches rank above one edit away, and those
+// above two edits away.
+const FuzzyWeight = 0.5
+
+// autoFuzzyDistance returns the edit distance allowed for a fuzzy term of
+// the given length in characters: none for very short terms, where any edit
+// changes the word, one up to five characters and two beyond.
+func autoFuzzyDistance(length int) int {
+	switch {
+	case length <= 2:
+		return 0
+	case length <= 5:
+		return 1
+	}
+	return MaxFuzzyDistance
+}
+
+// damerauLevenshtein returns the number of insertions, deletions,
+// substitutions and transpositions of adjacent characters that turn a into
+// b. Unlike the restricted variant, it is a metric, as a bkTree requires.
+func damerauLevenshtein(a, b []rune) int {
+	// d[i+1][j+1] is the distance between a[:i] and b[:j]; the first row and
+	// column hold an upper bound.
+	bound := len(a) + len(b)
+	d := make([][]int, len(a)+2)
+	for i := range d {
+		d[i] = make([]int, len(b)+2)
+		d[i][0] = bound
+		if i > 0 {
+			d[i][1] = i - 1
+		}
+	}
+	for j := 1; j < len(b)+2; j++ {
+		d[0][j] = bound
+		d[1][j] = j - 1
+	}
+
+	last := make(map[rune]int) // Last row where each character of a was seen
+	for i := 1; i <= len(a); i++ {
+		lastMatch := 0 // Last column of the row where b matched a[i-1]
+		for j := 1; j <= len(b); j++ {
+			i1, j1 := last[b[j-1]], lastMatch
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost, lastMatch = 0, j
+			}
+			d[i+1][j+1] = min(
+				d[i][j]+cost, // Substitution
+				d[i+1][j]+1,  // Insertion
+				d[i][j+1]+1,  // Deletion
+				d[i1][j1]+(i-i1-1)+1+(j-j1-1), // Transposition
+			)
+		}
+		last[a[i-1]] = i
+	}
+	return d[len(a)+1][len(b)+1]
+}
+
+// fuzzyMatch is a dictionary term close to a fuzzy term.
+type fuzzyMatch struct {
+	term     string
+	distance int
+	df       int
+}
+
+// bkTree indexes terms by edit distance (a Burkhard-Keller tree): the
+// children of a node are keyed by their distance to it, so a search only
+// visits the children whose distance could be within its radius.
+type bkTree struct {
+	root *bkNode
+}
+
+type bkNode struct {
+	term     string
+	runes    []rune
+	df       int
+	children map[int]*bkNode
+}
+
+func (t *bkTree) insert(term string, df int) {
+	node := &bkNode{term: term, runes: []rune(term), df: df}
+	if t.root == nil {
+		t.root = node
+		return
+	}
+	for parent := t.root; ; {
+		distance := damerauLevenshtein(node.runes, parent.runes)
+		if distance == 0 {
+			return
+		}
+		child, ok := parent.children[distance]
+		if !ok {
+			if parent.children == nil {
+				parent.children = make(map[int]*bkNode)
+			}
+			parent.children[distance] = node
+			return
+		}
+		parent = child
+	}
+}
+
+// search returns the terms within radius edits of term.
+func (t *bkTree) search(term string, radius int) []fuzzyMatch {
+	if t.root == nil {
+		return nil
+	}
+	runes := []rune(term)
+	var matches []fuzzyMatch
+	stack := []*bkNode{t.root}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		distance := damerauLevenshtein(runes, node.runes)
+		if distance <= radius {
+			matches = append(matches, fuzzyMatch{term: node.term, distance: distance, df: node.df})
+		}
+		for d := max(distance-radius, 1); d <= distance+radius; d++ {
+			if child, ok := node.children[d]; ok {
+				stack = append(stack, child)
+			}
+		}
+	}
+	return matches
+}
+
+// fuzzyQuery returns q with its plain words made fuzzy, for a second search
+// when q matched nothing. It returns false when q has no plain words.
+func fuzzyQuery(q Query) (Query, bool) {
+	switch q := q.(type) {
+	case *TermQuery:
+		words := strings.Fields(q.Text)
+		if len(words) == 1 {
+			return &FuzzyQuery{Text: words[0]}, true
+		}
+		fuzzy := &BooleanQuery{}
+		for _, word := range words {
+			fuzzy.Should = append(fuzzy.Should, &FuzzyQuery{Text: word})
+		}
+		return fuzzy, len(words) > 0
+	case *FieldQuery:
+		if inner, ok := fuzzyQuery(q.Query); ok {
+			return &FieldQuery{Field: q.Field, Query: inner}, true
+		}
+	case *BooleanQuery:
+		changed := false
+		rewrite := func(clauses []Query) []Query {
+			rewritten := make([]Query, len(clauses))
+			for i, c := range clauses {
+				var ok bool
+				if rewritten[i], ok = fuzzyQuery(c); ok {
+					changed = true
+				} else {
+					rewritten[i] = c
+				}
+			}
+			return rewritten
+		}
+		fuzzy := &BooleanQuery{Must: rewrite(q.Must), Should: rewrite(q.Should), MustNot: q.MustNot}
+		return fuzzy, changed
+	}
+	return q, false
+}
@@ -0,0 +1,102 @@
+package search
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestDamerauLevenshtein(t *testing.T) {
+	testCases := []struct {
+		a, b     string
+		expected int
+	}{
+		{"crawler", "crawler", 0},
+		{"crwaler", "crawler", 1}, // Transposition
+		{"crawlr", "crawler", 1},  // Deletion
+		{"crawlerr", "crawler", 1},
+		{"crewler", "crawler", 1},
+		{"ca", "abc", 2}, // 3 in the restricted variant
+		{"", "abc", 3},
+		{"canción", "cancion", 1},
+		{"index", "crawler", 6},
+	}
+	for _, tc := range testCases {
+		if got := damerauLevenshtein([]rune(tc.a), []rune(tc.b)); got != tc.expected {
+			t.Errorf("damerauLevenshtein(%q, %q) = %d, expected %d", tc.a, tc.b, got, tc.expected)
+		}
+		if got := damerauLevenshtein([]rune(tc.b), []rune(tc.a)); got != tc.expected {
+			t.Errorf("damerauLevenshtein(%q, %q) = %d, expected %d", tc.b, tc.a, got, tc.expected)
+		}
+	}
+}
+
+func TestBKTree_Search(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	terms := []string{"crawler", "crawl", "crawling", "brawler", "index", "indexer", "robots"}
+	for range 500 {
+		word := make([]rune, 3+rng.Intn(6))
+		for i := range word {
+			word[i] = rune('a' + rng.Intn(6))
+		}
+		terms = append(terms, string(word))
+	}
+	tree := &bkTree{}
+	for _, term := range terms {
+		tree.insert(term, 1)
+	}
+
+	// The tree must find exactly what comparing every term finds.
+	for _, query := range []string{"crwaler", "indx", "abcde", "fedcba", "robot"} {
+		for radius := 0; radius <= MaxFuzzyDistance; radius++ {
+			expected := map[string]bool{}
+			for _, term := range terms {
+				if damerauLevenshtein([]rune(query), []rune(term)) <= radius {
+					expected[term] = true
+				}
+			}
+			got := map[string]bool{}
+			for _, match := range tree.search(query, radius) {
+				got[match.term] = true
+			}
+			if fmt.Sprint(sortedSet(got)) != fmt.Sprint(sortedSet(expected)) {
+				t.Errorf("Search(%q, %d) = %v, expected %v", query, radius, sortedSet(got), sortedSet(expected))
+			}
+		}
+	}
+}
+
+func sortedSet(set map[string]bool) []string {
+	items := make([]string, 0, len(set))
+	for item := range set {
+		items = append(items, item)
+	}
+	sort.Strings(items)
+	return items
+}
+
+func TestFuzzyQuery(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+		ok       bool
+	}{
+		{"crwaler", "crwaler~", true},
+		{"crwaler robtos", "crwaler~ robtos~", true},
+		{"+crwaler -atlas", "+crwaler~ -atlas", true},
+		{"title:crwaler", "title:crwaler~", true},
+		{`"web crawler"`, `"web crawler"`, false},
+		{"index*", "index*", false},
+	}
+	for _, tc := range testCases {
+		q, err := ParseQuery(tc.input)
+		if err != nil {
+			t.Fatalf("ParseQuery(%q) returned an error: %v", tc.input, err)
+		}
+		fuzzy, ok := fuzzyQuery(q)
+		if ok != tc.ok || fuzzy.String() != tc.expected {
+			t.Errorf("fuzzyQuery(%q) = %q, %v, expected %q, %v", tc.input, fuzzy.String(), ok, tc.expected, tc.ok)
+		}
+	}
+}
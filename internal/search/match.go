@@ -0,0 +1,444 @@
+package search
+
+import (
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/TonyGLL/gofetch/internal/analysis"
+	"github.com/TonyGLL/gofetch/internal/ranking"
+	"github.com/TonyGLL/gofetch/pkg/storage"
+)
+
+// queryAnalyzer analyzes the words and phrases of a query for a field. It is
+// implemented by analysis.MultiAnalyzer.
+type queryAnalyzer interface {
+	AnalyzeFieldPhrase(field, phrase, language string) [][]analysis.Token
+}
+
+// queryMatcher scores documents against a parsed query, using the postings
+// of the terms of every field searched.
+type queryMatcher struct {
+	scorer   *ranking.TFIDFScorer
+	boosts   map[string]float64
+	analyzed map[Query]map[string][][]analysis.Token // Analyses of every word, phrase and NEAR operand by field
+	expanded map[Query]map[string][]expansion        // Terms of every pattern and fuzzy word by field
+	postings map[string]storage.InvertedIndexEntry
+
+	dictionary *TermDictionary
+}
+
+// expansion is a term a pattern or fuzzy word expands to, with the weight of
+// its matches.
+type expansion struct {
+	term   string
+	weight float64
+}
+
+// newQueryMatcher analyzes the words and phrases of q for every field in
+// boosts, or for the one field they are qualified with, and expands its
+// patterns to the terms of dictionary.
+func newQueryMatcher(q Query, boosts map[string]float64, analyzer queryAnalyzer, language string, dictionary *TermDictionary) *queryMatcher {
+	m := &queryMatcher{
+		boosts:     boosts,
+		analyzed:   make(map[Query]map[string][][]analysis.Token),
+		expanded:   make(map[Query]map[string][]expansion),
+		dictionary: dictionary,
+	}
+	m.analyze(q, boosts, analyzer, language)
+	return m
+}
+
+// analyze analyzes the words and phrases of q for fields, and expands its
+// patterns and fuzzy words.
+func (m *queryMatcher) analyze(q Query, fields map[string]float64, analyzer queryAnalyzer, language string) {
+	analyzeText := func(key Query, text string) {
+		fieldVariants := make(map[string][][]analysis.Token, len(fields))
+		for field := range fields {
+			fieldVariants[field] = analyzer.AnalyzeFieldPhrase(field, text, language)
+		}
+		m.analyzed[key] = fieldVariants
+	}
+	switch q := q.(type) {
+	case *TermQuery:
+		analyzeText(q, q.Text)
+	case *PhraseQuery:
+		analyzeText(q, q.Text)
+	case *NearQuery:
+		for _, word := range q.Terms {
+			analyzeText(word, word.Text)
+		}
+	case *WildcardQuery, *RegexpQuery:
+		m.expandPattern(q, fields)
+	case *FuzzyQuery:
+		m.expandFuzzy(q, fields, analyzer, language)
+	case *FieldQuery:
+		m.analyze(q.Query, map[string]float64{q.Field: m.boost(q.Field)}, analyzer, language)
+	case *BooleanQuery:
+		for _, clauses := range [][]Query{q.Must, q.Should, q.MustNot} {
+			for _, c := range clauses {
+				m.analyze(c, fields, analyzer, language)
+			}
+		}
+	}
+}
+
+// expandPattern expands a wildcard or regular expression query to the
+// terms of every field it matches.
+func (m *queryMatcher) expandPattern(q Query, fields map[string]float64) {
+	var p termPattern
+	switch q := q.(type) {
+	case *WildcardQuery:
+		p, _ = q.pattern() // Checked by ParseQuery
+	case *RegexpQuery:
+		p, _ = q.pattern()
+	}
+	fieldExpansions := make(map[string][]expansion, len(fields))
+	if m.dictionary != nil && p.re != nil {
+		for field := range fields {
+			for _, term := range m.dictionary.expand(field, p) {
+				fieldExpansions[field] = append(fieldExpansions[field], expansion{term: term, weight: 1})
+			}
+		}
+	}
+	m.expanded[q] = fieldExpansions
+}
+
+// expandFuzzy expands a fuzzy word to the terms of every field close to the
+// terms it is analyzed into, weighed by FuzzyWeight for every edit. Fields
+// where the word has no terms, e.g. because it is a stopword, are left out.
+func (m *queryMatcher) expandFuzzy(q *FuzzyQuery, fields map[string]float64, analyzer queryAnalyzer, language string) {
+	fieldExpansions := make(map[string][]expansion, len(fields))
+	for field := range fields {
+		terms := distinctTerms(analyzer.AnalyzeFieldPhrase(field, q.Text, language))
+		if len(terms) == 0 {
+			continue
+		}
+		weights := make(map[string]float64)
+		for _, term := range terms {
+			distance := q.Distance
+			if distance == 0 {
+				distance = autoFuzzyDistance(utf8.RuneCountInString(term))
+			}
+			if m.dictionary == nil {
+				continue
+			}
+			for _, match := range m.dictionary.fuzzy(field, term, distance) {
+				weights[match.term] = max(weights[match.term], math.Pow(FuzzyWeight, float64(match.distance)))
+			}
+		}
+		expansions := make([]expansion, 0, len(weights))
+		for term, weight := range weights {
+			expansions = append(expansions, expansion{term: term, weight: weight})
+		}
+		fieldExpansions[field] = expansions
+	}
+	m.expanded[q] = fieldExpansions
+}
+
+// boost returns the boost of a searched field. Fields only searched through
+// a qualifier weigh 1.
+func (m *queryMatcher) boost(field string) float64 {
+	if boost, ok := m.boosts[field]; ok {
+		return boost
+	}
+	return 1
+}
+
+// keys returns the inverted index keys of every term of the query, to fetch
+// their postings.
+func (m *queryMatcher) keys() []string {
+	var keys []string
+	for _, fieldVariants := range m.analyzed {
+		for field, variants := range fieldVariants {
+			for _, term := range distinctTerms(variants) {
+				keys = append(keys, storage.FieldTerm(field, term))
+			}
+		}
+	}
+	for _, fieldExpansions := range m.expanded {
+		for field, expansions := range fieldExpansions {
+			for _, e := range expansions {
+				keys = append(keys, storage.FieldTerm(field, e.term))
+			}
+		}
+	}
+	return keys
+}
+
+// match returns the score of every document matching q. It returns false
+// when q has no terms to match, e.g. when all its words are stopwords: such
+// clauses are left out of the enclosing query instead of matching nothing.
+func (m *queryMatcher) match(q Query) (map[string]float64, bool) {
+	switch q := q.(type) {
+	case *TermQuery:
+		return m.matchTerms(q)
+	case *WildcardQuery, *RegexpQuery:
+		scores, _ := m.matchExpanded(q) // A pattern matching no term matches nothing
+		return scores, true
+	case *FuzzyQuery:
+		return m.matchExpanded(q)
+	case *PhraseQuery:
+		return m.matchPhrase(q)
+	case *NearQuery:
+		return m.matchNear(q)
+	case *FieldQuery:
+		return m.match(q.Query)
+	case *BooleanQuery:
+		return m.matchBoolean(q)
+	}
+	return nil, false
+}
+
+func (m *queryMatcher) matchBoolean(q *BooleanQuery) (map[string]float64, bool) {
+	var scores map[string]float64
+	required := false
+	for _, c := range q.Must {
+		clauseScores, ok := m.match(c)
+		if !ok {
+			continue
+		}
+		if !required {
+			scores, required = clauseScores, true
+			continue
+		}
+		for id, score := range scores {
+			if clauseScore, ok := clauseScores[id]; ok {
+				scores[id] = score + clauseScore
+			} else {
+				delete(scores, id)
+			}
+		}
+	}
+
+	optional := make(map[string]float64)
+	hasOptional := false
+	for _, c := range q.Should {
+		clauseScores, ok := m.match(c)
+		if !ok {
+			continue
+		}
+		hasOptional = true
+		for id, score := range clauseScores {
+			optional[id] += score
+		}
+	}
+	if required {
+		for id := range scores {
+			scores[id] += optional[id]
+		}
+	} else if hasOptional {
+		scores = optional
+	} else {
+		return nil, false
+	}
+
+	for _, c := range q.MustNot {
+		excluded, _ := m.match(c)
+		for id := range excluded {
+			delete(scores, id)
+		}
+	}
+	return scores, true
+}
+
+// matchTerms scores the documents containing any term of q, with a bonus
+// for documents where consecutive words of q are close to each other.
+func (m *queryMatcher) matchTerms(q *TermQuery) (map[string]float64, bool) {
+	fieldTerms := make(map[string][]string, len(m.analyzed[q]))
+	boosts := make(map[string]float64, len(m.analyzed[q]))
+	found := false
+	for field, variants := range m.analyzed[q] {
+		fieldTerms[field] = distinctTerms(variants)
+		boosts[field] = m.boost(field)
+		found = found || len(fieldTerms[field]) > 0
+	}
+	if !found {
+		return nil, false
+	}
+	scores := m.scorer.ScoreFieldTerms(fieldTerms, boosts, m.postings)
+	for field, variants := range m.analyzed[q] {
+		for id, bonus := range m.proximityBonuses(field, variants) {
+			if _, ok := scores[id]; ok {
+				scores[id] += boosts[field] * bonus
+			}
+		}
+	}
+	return scores, true
+}
+
+// matchExpanded scores the documents containing any term a pattern or fuzzy
+// word expands to, like the terms of a word but for the weight of each term.
+func (m *queryMatcher) matchExpanded(q Query) (map[string]float64, bool) {
+	scores := make(map[string]float64)
+	for field, expansions := range m.expanded[q] {
+		for _, e := range expansions {
+			termScores := m.scorer.ScoreFieldTerms(
+				map[string][]string{field: {e.term}},
+				map[string]float64{field: m.boost(field) * e.weight},
+				m.postings)
+			for id, score := range termScores {
+				scores[id] += score
+			}
+		}
+	}
+	return scores, len(m.expanded[q]) > 0
+}
+
+// proximityBonuses returns the proximity bonus of the documents in which
+// consecutive words of a query occur close together in a field, see
+// ranking.TFIDFScorer.ScoreProximity. When the query was analyzed in several
+// ways, a document gets the bonus of its best analysis.
+func (m *queryMatcher) proximityBonuses(field string, variants [][]analysis.Token) map[string]float64 {
+	bonuses := make(map[string]float64)
+	for _, tokens := range variants {
+		slots := phraseSlots(tokens)
+		if len(slots) < 2 {
+			continue
+		}
+		positions := make([]map[string][]int, len(slots))
+		idfs := make([]float64, len(slots))
+		for i, slot := range slots {
+			positions[i] = termPositions(field, slot.terms, m.postings)
+			idfs[i] = m.slotIDF(field, slot)
+		}
+		variantBonuses := make(map[string]float64)
+		for i := 1; i < len(slots); i++ {
+			for id, previous := range positions[i-1] {
+				distance := minDistance(previous, positions[i][id])
+				variantBonuses[id] += m.scorer.ScoreProximity(distance, idfs[i-1], idfs[i])
+			}
+		}
+		for id, bonus := range variantBonuses {
+			bonuses[id] = max(bonuses[id], bonus)
+		}
+	}
+	return bonuses
+}
+
+// matchPhrase scores the documents containing a phrase in any searched
+// field. Each occurrence counts like an occurrence of a term whose IDF is
+// the sum of the IDFs of the phrase's words, so phrases of rare words score
+// highest. When the phrase was analyzed in several ways (for several
+// languages), a document scores with its best matching analysis.
+func (m *queryMatcher) matchPhrase(q *PhraseQuery) (map[string]float64, bool) {
+	scores := make(map[string]float64)
+	found := false
+	for field, variants := range m.analyzed[q] {
+		fieldScores := make(map[string]float64)
+		for _, tokens := range variants {
+			found = true
+			slots := phraseSlots(tokens)
+			idf := 0.0
+			for _, slot := range slots {
+				idf += m.slotIDF(field, slot)
+			}
+			for id, freq := range phraseFrequencies(field, slots, m.postings) {
+				fieldScores[id] = max(fieldScores[id], m.boost(field)*float64(freq)*idf)
+			}
+		}
+		for id, score := range fieldScores {
+			scores[id] += score
+		}
+	}
+	return scores, found
+}
+
+// matchNear scores the documents in which the words of q occur within its
+// distance, like matchPhrase scores phrases. Words analyzed into several
+// terms match at the position of any of them, and words analyzed into none
+// are left out.
+func (m *queryMatcher) matchNear(q *NearQuery) (map[string]float64, bool) {
+	scores := make(map[string]float64)
+	found := false
+	for field := range m.analyzed[q.Terms[0]] {
+		var groups [][]string
+		idf := 0.0
+		for _, word := range q.Terms {
+			terms := distinctTerms(m.analyzed[word][field])
+			if len(terms) == 0 {
+				continue
+			}
+			groups = append(groups, terms)
+			idf += m.slotIDF(field, phraseSlot{terms: terms})
+		}
+		if len(groups) == 0 {
+			continue
+		}
+		found = true
+		for id, freq := range nearFrequencies(field, groups, q.Distance, m.postings) {
+			scores[id] += m.boost(field) * float64(freq) * idf
+		}
+	}
+	return scores, found
+}
+
+// slotIDF returns the highest IDF of the terms of a slot found in a field.
+func (m *queryMatcher) slotIDF(field string, slot phraseSlot) float64 {
+	best := 0.0
+	for _, term := range slot.terms {
+		if entry, ok := m.postings[storage.FieldTerm(field, term)]; ok {
+			best = max(best, m.scorer.IDF(entry.DF))
+		}
+	}
+	return best
+}
+
+// distinctTerms returns the distinct terms of the analyses of a query, in
+// order of first appearance.
+func distinctTerms(variants [][]analysis.Token) []string {
+	seen := make(map[string]struct{})
+	var terms []string
+	for _, tokens := range variants {
+		for _, token := range tokens {
+			if _, ok := seen[token.Term]; !ok {
+				seen[token.Term] = struct{}{}
+				terms = append(terms, token.Term)
+			}
+		}
+	}
+	return terms
+}
+
+// walkQuery calls fn for every word, phrase, pattern, fuzzy word and NEAR
+// clause in q, telling whether it excludes documents.
+func walkQuery(q Query, excluded bool, fn func(leaf Query, excluded bool)) {
+	switch q := q.(type) {
+	case *TermQuery, *PhraseQuery, *NearQuery, *WildcardQuery, *RegexpQuery, *FuzzyQuery:
+		fn(q, excluded)
+	case *FieldQuery:
+		walkQuery(q.Query, excluded, fn)
+	case *BooleanQuery:
+		for _, c := range q.Must {
+			walkQuery(c, excluded, fn)
+		}
+		for _, c := range q.Should {
+			walkQuery(c, excluded, fn)
+		}
+		for _, c := range q.MustNot {
+			walkQuery(c, !excluded, fn)
+		}
+	}
+}
+
+// matchedText returns the words of the clauses of q that are not excluded,
+// the text passages are matched against.
+func matchedText(q Query) string {
+	var words []string
+	walkQuery(q, false, func(leaf Query, excluded bool) {
+		if excluded {
+			return
+		}
+		switch leaf := leaf.(type) {
+		case *TermQuery:
+			words = append(words, leaf.Text)
+		case *PhraseQuery:
+			words = append(words, leaf.Text)
+		case *NearQuery:
+			for _, word := range leaf.Terms {
+				words = append(words, word.Text)
+			}
+		}
+	})
+	return strings.Join(words, " ")
+}
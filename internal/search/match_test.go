@@ -0,0 +1,310 @@
+package search
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TonyGLL/gofetch/internal/analysis"
+	"github.com/TonyGLL/gofetch/internal/ranking"
+	"github.com/TonyGLL/gofetch/pkg/storage"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// testIndex builds the postings of documents given by name. A document text
+// may start with a title followed by "|", indexed in the title field.
+func testIndex(docs map[string]string) (map[string]storage.InvertedIndexEntry, map[string]string) {
+	postings := make(map[string]storage.InvertedIndexEntry)
+	names := make(map[string]string)
+	for name, text := range docs {
+		id := primitive.NewObjectID()
+		names[id.Hex()] = name
+		fields := map[string]string{storage.FieldBody: text}
+		if title, body, ok := strings.Cut(text, "|"); ok {
+			fields = map[string]string{storage.FieldTitle: title, storage.FieldBody: body}
+		}
+		for field, text := range fields {
+			for i, term := range strings.Fields(text) {
+				key := storage.FieldTerm(field, term)
+				entry := postings[key]
+				entry.Term = key
+				if n := len(entry.Postings); n > 0 && entry.Postings[n-1].DocID == id {
+					entry.Postings[n-1].Frequency++
+					entry.Postings[n-1].Positions = append(entry.Postings[n-1].Positions, i)
+				} else {
+					entry.Postings = append(entry.Postings, storage.Posting{DocID: id, Frequency: 1, Positions: []int{i}})
+					entry.DF++
+				}
+				postings[key] = entry
+			}
+		}
+	}
+	return postings, names
+}
+
+// testDictionary builds a term dictionary of postings.
+func testDictionary(postings map[string]storage.InvertedIndexEntry, maxExpansions int) *TermDictionary {
+	terms := make([]storage.DictionaryTerm, 0, len(postings))
+	for key, entry := range postings {
+		terms = append(terms, storage.DictionaryTerm{Term: key, DF: entry.DF})
+	}
+	d := NewTermDictionary(maxExpansions)
+	d.setTerms(terms, time.Time{})
+	return d
+}
+
+// testAnalyzer splits queries at spaces and drops the stopword "the",
+// leaving a gap in positions.
+type testAnalyzer struct{}
+
+func (testAnalyzer) AnalyzeFieldPhrase(_, phrase, _ string) [][]analysis.Token {
+	var tokens []analysis.Token
+	for i, word := range strings.Fields(strings.ToLower(phrase)) {
+		if word != "the" {
+			tokens = append(tokens, analysis.Token{Term: word, Position: i})
+		}
+	}
+	if len(tokens) == 0 {
+		return nil
+	}
+	return [][]analysis.Token{tokens}
+}
+
+// scoreQuery returns the scores of the documents matching query, by name.
+func scoreQuery(t *testing.T, query string, docs map[string]string) map[string]float64 {
+	t.Helper()
+	parsed, err := ParseQuery(query)
+	if err != nil {
+		t.Fatalf("ParseQuery(%q) returned an error: %v", query, err)
+	}
+	postings, names := testIndex(docs)
+	matcher := newQueryMatcher(parsed, map[string]float64{storage.FieldBody: 1}, testAnalyzer{}, "", testDictionary(postings, 0))
+	matcher.scorer = &ranking.TFIDFScorer{TotalDocuments: int64(len(docs))}
+	matcher.postings = postings
+	scores, _ := matcher.match(parsed)
+	named := make(map[string]float64, len(scores))
+	for id, score := range scores {
+		named[names[id]] = score
+	}
+	return named
+}
+
+// matchQuery returns the names of the documents matching query, sorted.
+func matchQuery(t *testing.T, query string, docs map[string]string) []string {
+	t.Helper()
+	matched := []string{}
+	for name := range scoreQuery(t, query, docs) {
+		matched = append(matched, name)
+	}
+	sort.Strings(matched)
+	return matched
+}
+
+func TestQueryMatcher(t *testing.T) {
+	docs := map[string]string{
+		"atlas":   "mongo atlas cloud",
+		"local":   "mongo local server",
+		"crawler": "crawler robots txt",
+		"indexer": "indexer robots",
+		"other":   "robots only",
+	}
+	testCases := []struct {
+		query    string
+		expected []string
+	}{
+		{"mongo crawler", []string{"atlas", "crawler", "local"}},
+		{"mongo -atlas", []string{"local"}},
+		{"+mongo cloud", []string{"atlas", "local"}},
+		{"(crawler OR indexer) AND robots", []string{"crawler", "indexer"}},
+		{"robots AND NOT (crawler OR indexer)", []string{"other"}},
+		{"robots AND the", []string{"crawler", "indexer", "other"}},
+		{"the", []string{}},
+		{"missing AND robots", []string{}},
+	}
+	for _, tc := range testCases {
+		if got := matchQuery(t, tc.query, docs); !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("Query %q matched %v, expected %v", tc.query, got, tc.expected)
+		}
+	}
+}
+
+func TestQueryMatcher_Phrases(t *testing.T) {
+	docs := map[string]string{
+		"exact":    "build the inverted index fast",
+		"reversed": "index inverted",
+		"apart":    "inverted files index",
+		"gap":      "state of the art",
+		"other":    "state of a art",
+		"short":    "state art",
+	}
+	testCases := []struct {
+		query    string
+		expected []string
+	}{
+		{`"inverted index"`, []string{"exact"}},
+		{`"the inverted index"`, []string{"exact"}},
+		{`"state of the art"`, []string{"gap", "other"}},
+		{`"index inverted" OR "inverted files"`, []string{"apart", "reversed"}},
+		{`inverted -"inverted index"`, []string{"apart", "reversed"}},
+		{`"the"`, []string{}},
+	}
+	for _, tc := range testCases {
+		if got := matchQuery(t, tc.query, docs); !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("Query %q matched %v, expected %v", tc.query, got, tc.expected)
+		}
+	}
+}
+
+func TestQueryMatcher_Near(t *testing.T) {
+	docs := map[string]string{
+		"close":    "the crawler obeys robots",
+		"reversed": "robots guide every crawler",
+		"far":      "crawler a b c d e f robots",
+		"alone":    "crawler only",
+		"three":    "crawler reads robots txt",
+	}
+	testCases := []struct {
+		query    string
+		expected []string
+	}{
+		{"crawler NEAR/5 robots", []string{"close", "reversed", "three"}},
+		{"crawler NEAR/2 robots", []string{"close", "three"}},
+		{"crawler NEAR robots", []string{"close", "reversed", "three"}},
+		{"crawler NEAR/7 robots", []string{"close", "far", "reversed", "three"}},
+		{"crawler NEAR/3 robots NEAR/3 txt", []string{"three"}},
+		{"crawler NEAR/2 robots NEAR/2 txt", []string{}},
+		{"crawler NEAR/5 robots -obeys", []string{"reversed", "three"}},
+		{"crawler NEAR/5 the", []string{"alone", "close", "far", "reversed", "three"}},
+	}
+	for _, tc := range testCases {
+		if got := matchQuery(t, tc.query, docs); !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("Query %q matched %v, expected %v", tc.query, got, tc.expected)
+		}
+	}
+}
+
+func TestQueryMatcher_Proximity(t *testing.T) {
+	docs := map[string]string{
+		"adjacent": "web crawler guide",
+		"near":     "web based crawler",
+		"far":      "web a b c d e f crawler",
+		"reversed": "crawler for the web",
+		"web":      "web only",
+	}
+	scores := scoreQuery(t, "web crawler", docs)
+	if len(scores) != len(docs) {
+		t.Fatalf("Expected every document to match, got %v", scores)
+	}
+	if !(scores["adjacent"] > scores["near"] && scores["near"] > scores["far"]) {
+		t.Errorf("Expected closer terms to score higher, got %v", scores)
+	}
+	if scores["reversed"] <= scores["far"] {
+		t.Errorf("Expected terms close in any order to score higher than terms far apart, got %v", scores)
+	}
+	if scores["far"] <= scores["web"] {
+		t.Errorf("Expected both terms to score higher than one, got %v", scores)
+	}
+}
+
+func TestQueryMatcher_Fields(t *testing.T) {
+	docs := map[string]string{
+		"titled":  "crawler guide | how robots work",
+		"body":    "intro | the crawler reads robots",
+		"phrase":  "web crawler | notes",
+		"neither": "indexer | robots only",
+	}
+	testCases := []struct {
+		query    string
+		expected []string
+	}{
+		{"crawler", []string{"body"}},
+		{"title:crawler", []string{"phrase", "titled"}},
+		{"title:crawler robots", []string{"body", "neither", "phrase", "titled"}},
+		{"+title:crawler +robots", []string{"titled"}},
+		{`title:"web crawler"`, []string{"phrase"}},
+		{"title:(guide OR notes) -title:web", []string{"titled"}},
+		{"robots -title:indexer", []string{"body", "titled"}},
+	}
+	for _, tc := range testCases {
+		if got := matchQuery(t, tc.query, docs); !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("Query %q matched %v, expected %v", tc.query, got, tc.expected)
+		}
+	}
+}
+
+func TestQueryMatcher_Patterns(t *testing.T) {
+	docs := map[string]string{
+		"indexer":  "the indexer runs",
+		"indexing": "indexing guide",
+		"crawler":  "crawler notes",
+		"crawling": "crawling robots",
+		"titled":   "index basics | nothing here",
+	}
+	testCases := []struct {
+		query    string
+		expected []string
+	}{
+		{"index*", []string{"indexer", "indexing"}},
+		{"title:index*", []string{"titled"}},
+		{"craw?er", []string{"crawler"}},
+		{"*ing", []string{"crawling", "indexing", "titled"}},
+		{"/crawl(er|ing)/", []string{"crawler", "crawling"}},
+		{"/crawl/", []string{}},
+		{"+zebra* crawler", []string{}},
+		{"crawl* -robots", []string{"crawler"}},
+		{"index* AND guide", []string{"indexing"}},
+	}
+	for _, tc := range testCases {
+		if got := matchQuery(t, tc.query, docs); !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("Query %q matched %v, expected %v", tc.query, got, tc.expected)
+		}
+	}
+}
+
+func TestQueryMatcher_Fuzzy(t *testing.T) {
+	docs := map[string]string{
+		"crawler":  "crawler notes",
+		"crawlers": "crawlers guide",
+		"brawler":  "brawler story",
+		"other":    "indexer notes",
+	}
+	testCases := []struct {
+		query    string
+		expected []string
+	}{
+		{"crwaler~", []string{"brawler", "crawler", "crawlers"}},
+		{"crwaler~1", []string{"crawler"}},
+		{"crwaler", []string{}},
+		{"+crwaler~1 notes", []string{"crawler"}},
+		{"zzzzzzzz~ notes", []string{"crawler", "other"}},
+		{"+zzzzzzzz~ notes", []string{}},
+		{"the~", []string{}},
+	}
+	for _, tc := range testCases {
+		if got := matchQuery(t, tc.query, docs); !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("Query %q matched %v, expected %v", tc.query, got, tc.expected)
+		}
+	}
+
+	scores := scoreQuery(t, "crawler~", docs)
+	if !(scores["crawler"] > scores["crawlers"] && scores["crawler"] > scores["brawler"]) {
+		t.Errorf("Expected the exact match to score highest, got %v", scores)
+	}
+	scores = scoreQuery(t, "crawlers~", docs)
+	if !(scores["crawlers"] > scores["crawler"] && scores["crawler"] > scores["brawler"]) {
+		t.Errorf("Expected fuzzy matches to score lower the more edits away, got %v", scores)
+	}
+}
+
+func TestMatchedText(t *testing.T) {
+	q, err := ParseQuery(`(crawler OR indexer) AND "robots txt" -sitemap mongo NEAR/3 atlas`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := matchedText(q); got != "crawler indexer robots txt mongo atlas" {
+		t.Errorf("Expected the words of the clauses that are not excluded, got %q", got)
+	}
+}
@@ -0,0 +1,90 @@
+package search
+
+import (
+	"context"
+
+	"github.com/TonyGLL/gofetch/internal/ranking"
+	"github.com/TonyGLL/gofetch/pkg/storage"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PassageResult is the best matching passage of a search result. Start and
+// End are byte offsets into the document content.
+type PassageResult struct {
+	ID    string  `json:"id"`
+	Title string  `json:"title"`
+	Text  string  `json:"text"`
+	Start int     `json:"start"`
+	End   int     `json:"end"`
+	Score float64 `json:"score"`
+}
+
+// attachBestPassages scores the passages of the documents in results and
+// attaches the best one to each result.
+func (s *searcherImpl) attachBestPassages(
+	ctx context.Context,
+	queryTerms []string,
+	scorer *ranking.TFIDFScorer,
+	results []SearchResult,
+) error {
+	if len(results) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(queryTerms))
+	for _, term := range queryTerms {
+		keys = append(keys, storage.FieldTerm(storage.FieldPassage, term))
+	}
+	postings, err := s.store.GetPostingsForTerms(ctx, keys)
+	if err != nil {
+		return err
+	}
+	passageScores := scorer.ScoreFields(queryTerms, map[string]float64{storage.FieldPassage: 1}, postings)
+	if len(passageScores) == 0 {
+		return nil
+	}
+
+	passageIDs := make([]primitive.ObjectID, 0, len(passageScores))
+	for id := range passageScores {
+		objID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return err
+		}
+		passageIDs = append(passageIDs, objID)
+	}
+	parentIDs := make([]primitive.ObjectID, 0, len(results))
+	for _, result := range results {
+		objID, err := primitive.ObjectIDFromHex(result.DocID)
+		if err != nil {
+			return err
+		}
+		parentIDs = append(parentIDs, objID)
+	}
+
+	passages, err := s.store.GetPassages(ctx, passageIDs, parentIDs)
+	if err != nil {
+		return err
+	}
+
+	best := make(map[string]*PassageResult, len(results))
+	for _, p := range passages {
+		score := passageScores[p.ID.Hex()]
+		parent := p.ParentID.Hex()
+		if current, ok := best[parent]; ok && current.Score >= score {
+			continue
+		}
+		best[parent] = &PassageResult{
+			ID:    p.ID.Hex(),
+			Title: p.Title,
+			Text:  p.Content,
+			Start: p.Start,
+			End:   p.End,
+			Score: score,
+		}
+	}
+	for i := range results {
+		results[i].Passage = best[results[i].DocID]
+	}
+	return nil
+}
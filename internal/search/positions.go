@@ -0,0 +1,148 @@
+package search
+
+import (
+	"slices"
+	"sort"
+
+	"github.com/TonyGLL/gofetch/internal/analysis"
+	"github.com/TonyGLL/gofetch/pkg/storage"
+)
+
+// phraseSlot holds the terms a query accepts at one position, relative to
+// its first word. Several terms share a position when a filter adds
+// alternatives, such as accent folded forms or synonyms.
+type phraseSlot struct {
+	offset int
+	terms  []string
+}
+
+// phraseSlots groups the tokens of an analyzed phrase by position.
+func phraseSlots(tokens []analysis.Token) []phraseSlot {
+	byPosition := make(map[int][]string)
+	for _, token := range tokens {
+		byPosition[token.Position] = append(byPosition[token.Position], token.Term)
+	}
+	positions := make([]int, 0, len(byPosition))
+	for position := range byPosition {
+		positions = append(positions, position)
+	}
+	sort.Ints(positions)
+	slots := make([]phraseSlot, len(positions))
+	for i, position := range positions {
+		slots[i] = phraseSlot{offset: position - positions[0], terms: byPosition[position]}
+	}
+	return slots
+}
+
+// termPositions returns the sorted positions of any of terms in a field, by
+// document.
+func termPositions(field string, terms []string, postings map[string]storage.InvertedIndexEntry) map[string][]int {
+	positions := make(map[string][]int)
+	for _, term := range terms {
+		for _, post := range postings[storage.FieldTerm(field, term)].Postings {
+			id := post.DocID.Hex()
+			positions[id] = append(positions[id], post.Positions...)
+		}
+	}
+	if len(terms) > 1 {
+		for id, p := range positions {
+			slices.Sort(p)
+			positions[id] = slices.Compact(p)
+		}
+	}
+	return positions
+}
+
+// phraseFrequencies returns the number of occurrences of a phrase in the
+// documents whose field contains it: the occurrences of its first slot
+// followed by a term of every other slot at the same distance as in the
+// phrase.
+func phraseFrequencies(field string, slots []phraseSlot, postings map[string]storage.InvertedIndexEntry) map[string]int {
+	slotPositions := make([]map[string][]int, len(slots))
+	for i, slot := range slots {
+		slotPositions[i] = termPositions(field, slot.terms, postings)
+	}
+
+	freqs := make(map[string]int)
+	for id, starts := range slotPositions[0] {
+		for _, start := range starts {
+			matched := true
+			for i := 1; i < len(slots) && matched; i++ {
+				_, matched = slices.BinarySearch(slotPositions[i][id], start+slots[i].offset)
+			}
+			if matched {
+				freqs[id]++
+			}
+		}
+	}
+	return freqs
+}
+
+// nearFrequencies returns the number of places where one term of every
+// group occurs within distance positions of the others, in any order, by
+// document. Each place is the smallest window ending at one of its terms.
+func nearFrequencies(field string, groups [][]string, distance int, postings map[string]storage.InvertedIndexEntry) map[string]int {
+	groupPositions := make([]map[string][]int, len(groups))
+	for i, terms := range groups {
+		groupPositions[i] = termPositions(field, terms, postings)
+	}
+
+	type hit struct{ position, group int }
+	freqs := make(map[string]int)
+	for id := range groupPositions[0] {
+		var hits []hit
+		for i, byDoc := range groupPositions {
+			if len(byDoc[id]) == 0 {
+				hits = nil
+				break
+			}
+			for _, position := range byDoc[id] {
+				hits = append(hits, hit{position, i})
+			}
+		}
+		if hits == nil {
+			continue
+		}
+		sort.Slice(hits, func(a, b int) bool { return hits[a].position < hits[b].position })
+
+		// Slide a window over the hits, keeping it as short as possible
+		// while it holds every group.
+		counts := make([]int, len(groups))
+		covered, left := 0, 0
+		for _, h := range hits {
+			if counts[h.group] == 0 {
+				covered++
+			}
+			counts[h.group]++
+			for counts[hits[left].group] > 1 {
+				counts[hits[left].group]--
+				left++
+			}
+			if covered == len(groups) && h.position-hits[left].position <= distance {
+				freqs[id]++
+			}
+		}
+	}
+	return freqs
+}
+
+// minDistance returns the smallest distance between a position in a and a
+// different position in b, both sorted, or 0 when there is none.
+func minDistance(a, b []int) int {
+	best := 0
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		d := a[i] - b[j]
+		if d < 0 {
+			d = -d
+		}
+		if d > 0 && (best == 0 || d < best) {
+			best = d
+		}
+		if a[i] < b[j] {
+			i++
+		} else {
+			j++
+		}
+	}
+	return best
+}
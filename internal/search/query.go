@@ -0,0 +1,707 @@
+package search
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/TonyGLL/gofetch/pkg/storage"
+)
+
+// Query is a node of a parsed query, see ParseQuery.
+type Query interface {
+	// String writes the query back in the query syntax, with every clause
+	// marked "+" (required), "-" (prohibited) or unmarked (optional).
+	String() string
+}
+
+// TermQuery matches documents that contain any of the terms the words of
+// Text are analyzed into. The plain words of a query are kept together in
+// one TermQuery, so multi-word synonyms still apply to them.
+type TermQuery struct {
+	Text string
+}
+
+func (q *TermQuery) String() string { return q.Text }
+
+// PhraseQuery matches documents that contain the words of Text next to each
+// other and in order, e.g. "inverted index". Stopwords removed from the
+// phrase must be in the document too, but may be any word: "state of the
+// art" also matches "state in the art".
+type PhraseQuery struct {
+	Text string
+}
+
+func (q *PhraseQuery) String() string { return `"` + q.Text + `"` }
+
+// WildcardQuery matches documents that contain any indexed term matching
+// Pattern, where "*" stands for any run of characters and "?" for one, e.g.
+// index* or craw?er. Patterns are matched against the indexed terms as they
+// are, lowercased but not stemmed.
+type WildcardQuery struct {
+	Pattern string
+}
+
+func (q *WildcardQuery) String() string { return q.Pattern }
+
+// RegexpQuery matches documents that contain any indexed term matching the
+// regular expression Pattern as a whole, e.g. /crawl(er|ing)/.
+type RegexpQuery struct {
+	Pattern string
+}
+
+func (q *RegexpQuery) String() string { return "/" + q.Pattern + "/" }
+
+// MaxFuzzyDistance is the largest edit distance of a fuzzy word.
+const MaxFuzzyDistance = 2
+
+// FuzzyQuery matches documents that contain a term within Distance edits
+// of a term of Text: an insertion, deletion or substitution of a character,
+// or a transposition of two adjacent ones, e.g. crwaler~ finds "crawler".
+// With a Distance of 0, it depends on the length of the term, see
+// autoFuzzyDistance.
+type FuzzyQuery struct {
+	Text     string
+	Distance int
+}
+
+func (q *FuzzyQuery) String() string {
+	if q.Distance == 0 {
+		return q.Text + "~"
+	}
+	return q.Text + "~" + strconv.Itoa(q.Distance)
+}
+
+// DefaultNearDistance is the distance of a NEAR operator written without
+// one.
+const DefaultNearDistance = 5
+
+// NearQuery matches documents that contain all of its words, in any order,
+// within Distance positions of each other: "crawler NEAR/5 robots" matches
+// "robots guide the crawler" and "the crawler obeys robots".
+type NearQuery struct {
+	Terms    []*TermQuery // One word each
+	Distance int
+}
+
+func (q *NearQuery) String() string {
+	words := make([]string, len(q.Terms))
+	for i, t := range q.Terms {
+		words[i] = t.Text
+	}
+	return strings.Join(words, fmt.Sprintf(" NEAR/%d ", q.Distance))
+}
+
+// FieldQuery matches Query in a single field, e.g. title:crawler or
+// url:(robots OR sitemap).
+type FieldQuery struct {
+	Field string
+	Query Query
+}
+
+func (q *FieldQuery) String() string { return q.Field + ":" + groupString(q.Query) }
+
+// FilterQuery restricts the results of the whole query to documents whose
+// metadata matches Value, e.g. site:go.dev or ext:md. Key is one of the
+// storage.Filter* keys. Filters do not score documents, see splitFilters.
+type FilterQuery struct {
+	Key   string
+	Value string
+}
+
+func (q *FilterQuery) String() string {
+	if strings.ContainsFunc(q.Value, unicode.IsSpace) {
+		return q.Key + `:"` + q.Value + `"`
+	}
+	return q.Key + ":" + q.Value
+}
+
+// queryFields are the fields a query can be restricted to with a qualifier.
+var queryFields = map[string]bool{
+	storage.FieldTitle:    true,
+	storage.FieldURL:      true,
+	storage.FieldBody:     true,
+	storage.FieldHeadings: true,
+	storage.FieldComments: true,
+	storage.FieldStrings:  true,
+	storage.FieldCustom:   true,
+}
+
+// queryFilters are the metadata a query can filter documents by.
+var queryFilters = map[string]bool{
+	storage.FilterSite:   true,
+	storage.FilterExt:    true,
+	storage.FilterSource: true,
+	storage.FilterLang:   true,
+	storage.FilterPath:   true,
+}
+
+// BooleanQuery combines clauses: a document matches when it matches every
+// Must clause, no MustNot clause and, when there are no Must clauses, at
+// least one Should clause. Its score is the sum of the scores of the Must and
+// Should clauses it matches.
+type BooleanQuery struct {
+	Must    []Query
+	Should  []Query
+	MustNot []Query
+}
+
+func (q *BooleanQuery) String() string {
+	var parts []string
+	for _, c := range q.Must {
+		parts = append(parts, "+"+groupString(c))
+	}
+	for _, c := range q.Should {
+		if t, ok := c.(*TermQuery); ok {
+			parts = append(parts, t.Text) // Optional words need no grouping
+			continue
+		}
+		parts = append(parts, groupString(c))
+	}
+	for _, c := range q.MustNot {
+		parts = append(parts, "-"+groupString(c))
+	}
+	return strings.Join(parts, " ")
+}
+
+// groupString writes a clause, in parentheses when it has several parts.
+func groupString(q Query) string {
+	switch q.(type) {
+	case *BooleanQuery, *NearQuery:
+		return "(" + q.String() + ")"
+	}
+	if t, ok := q.(*TermQuery); ok && strings.ContainsFunc(t.Text, unicode.IsSpace) {
+		return "(" + t.Text + ")"
+	}
+	return q.String()
+}
+
+// SyntaxError reports a malformed query.
+type SyntaxError struct {
+	Offset int // Byte offset in the query where the error was found
+	Msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("invalid query at offset %d: %s", e.Offset, e.Msg)
+}
+
+// ParseQuery parses a search query. Words separated by spaces are optional:
+// a document matches when it contains any of them, and scores higher the
+// more it contains. On top of that:
+//
+//   - "+word" requires a word and "-word" excludes documents with it;
+//   - "a AND b" requires both sides, "a OR b" either, and "NOT a" excludes a
+//     (the operators are only recognised in capitals);
+//   - parentheses group clauses: "(crawler OR indexer) AND robots";
+//   - double quotes match an exact phrase: "inverted index";
+//   - "a NEAR/5 b" matches words within 5 positions of each other, in any
+//     order; a bare NEAR allows DefaultNearDistance;
+//   - "index*" and "craw?er" match the indexed terms fitting a wildcard
+//     pattern, and "/crawl(er|ing)/" those matching a regular expression. A
+//     "?" at the end of a word is punctuation, not a wildcard;
+//   - "crwaler~" matches words within an edit distance that grows with the
+//     length of the word, and "crwaler~1" or "crwaler~2" within the given
+//     distance;
+//   - "title:word" matches in a single field: title, url, body, headings,
+//     comments or strings. A qualifier right before a phrase or group
+//     applies to all of it: title:"inverted index", title:(a OR b);
+//   - "site:go.dev", "ext:md", "source:file", "lang:spanish" and
+//     "path:docs/runbooks" filter the results by document metadata.
+//
+// Filters apply to the whole query, so they cannot appear in parentheses.
+// Prefixed with "-" or NOT they exclude documents, and unmarked filters of
+// the same kind are alternatives: "ext:md ext:txt" keeps either. A query
+// needs words to search besides its filters.
+//
+// NEAR binds tighter than AND, and AND tighter than OR, so "a OR b AND c" is
+// "a OR (b AND c)". A query or group that only excludes documents is an
+// error, as is an operator without a clause to apply to, an unbalanced
+// parenthesis or quote, an empty phrase and a NEAR operand other than a
+// word.
+func ParseQuery(input string) (Query, error) {
+	p := &queryParser{lexer: queryLexer{input: input}}
+	p.advance()
+	q, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind == tokRParen {
+		return nil, p.errorf("unmatched ')'")
+	}
+	if q == nil {
+		return &BooleanQuery{}, nil
+	}
+	searched := false
+	walkQuery(q, false, func(_ Query, excluded bool) { searched = searched || !excluded })
+	if !searched {
+		return nil, &SyntaxError{Offset: 0, Msg: "a query needs words to search besides its filters"}
+	}
+	return q, nil
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokLParen
+	tokRParen
+	tokAnd
+	tokOr
+	tokNot
+	tokPlus
+	tokMinus
+	tokPhrase
+	tokNear
+)
+
+type queryToken struct {
+	kind         tokenKind
+	text         string
+	offset       int
+	unterminated bool // A phrase without its closing quote
+	distance     int  // The distance of a NEAR operator, or -1 when it is not a number
+}
+
+// queryLexer splits a query into words, parentheses and operators. "+" and
+// "-" are operators only at the start of a word or group.
+type queryLexer struct {
+	input  string
+	offset int
+}
+
+func (l *queryLexer) next() queryToken {
+	for l.offset < len(l.input) {
+		r, size := utf8.DecodeRuneInString(l.input[l.offset:])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		l.offset += size
+	}
+	start := l.offset
+	if start >= len(l.input) {
+		return queryToken{kind: tokEOF, offset: start}
+	}
+	switch c := l.input[start]; c {
+	case '(':
+		l.offset++
+		return queryToken{kind: tokLParen, text: "(", offset: start}
+	case ')':
+		l.offset++
+		return queryToken{kind: tokRParen, text: ")", offset: start}
+	case '"':
+		end := strings.IndexByte(l.input[start+1:], '"')
+		if end < 0 {
+			l.offset = len(l.input)
+			return queryToken{kind: tokPhrase, offset: start, unterminated: true}
+		}
+		l.offset = start + 1 + end + 1
+		return queryToken{kind: tokPhrase, text: l.input[start+1 : start+1+end], offset: start}
+	case '+', '-':
+		if rest := l.input[start+1:]; rest != "" && (rest[0] == '(' || rest[0] == '"' || !isQueryBreak(rest)) {
+			l.offset++
+			kind := tokPlus
+			if c == '-' {
+				kind = tokMinus
+			}
+			return queryToken{kind: kind, text: string(c), offset: start}
+		}
+	}
+	for l.offset < len(l.input) && !isQueryBreak(l.input[l.offset:]) {
+		if l.input[l.offset] == '/' && (l.offset == start || l.input[l.offset-1] == ':') {
+			if end := regexpEnd(l.input, l.offset); end > 0 {
+				l.offset = end // A regular expression, which may hold parentheses
+				continue
+			}
+		}
+		_, size := utf8.DecodeRuneInString(l.input[l.offset:])
+		l.offset += size
+	}
+	text := l.input[start:l.offset]
+	switch text {
+	case "AND":
+		return queryToken{kind: tokAnd, text: text, offset: start}
+	case "OR":
+		return queryToken{kind: tokOr, text: text, offset: start}
+	case "NOT":
+		return queryToken{kind: tokNot, text: text, offset: start}
+	case "NEAR":
+		return queryToken{kind: tokNear, text: text, offset: start, distance: DefaultNearDistance}
+	}
+	if n, ok := strings.CutPrefix(text, "NEAR/"); ok {
+		distance, err := strconv.Atoi(n)
+		if err != nil || distance < 1 {
+			distance = -1
+		}
+		return queryToken{kind: tokNear, text: text, offset: start, distance: distance}
+	}
+	return queryToken{kind: tokWord, text: text, offset: start}
+}
+
+// regexpEnd returns the offset after a regular expression starting with the
+// "/" at offset in s, or -1 when the "/" does not open one: a regular
+// expression holds no spaces, ends with an unescaped "/" and is followed by
+// the end of the word.
+func regexpEnd(s string, offset int) int {
+	for i := offset + 1; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == '\\':
+			i += size + 1
+			continue
+		case unicode.IsSpace(r):
+			return -1
+		case r == '/':
+			if i == offset+1 || (i+1 < len(s) && !isQueryBreak(s[i+1:])) {
+				return -1
+			}
+			return i + 1
+		}
+		i += size
+	}
+	return -1
+}
+
+// isQueryBreak reports whether s starts with a character that ends a word.
+func isQueryBreak(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return unicode.IsSpace(r) || r == '(' || r == ')' || r == '"'
+}
+
+// occur is how a clause takes part in the enclosing BooleanQuery.
+type occur int
+
+const (
+	occurShould occur = iota
+	occurMust
+	occurMustNot
+)
+
+type clause struct {
+	occur occur
+	query Query
+}
+
+type queryParser struct {
+	lexer   queryLexer
+	tok     queryToken
+	depth   int  // Number of open groups
+	inField bool // Parsing the phrase or group of a field qualifier
+}
+
+func (p *queryParser) advance() {
+	p.tok = p.lexer.next()
+}
+
+func (p *queryParser) errorf(format string, args ...any) error {
+	return &SyntaxError{Offset: p.tok.offset, Msg: fmt.Sprintf(format, args...)}
+}
+
+// parseOr parses clauses up to the end of the query or group. It returns
+// nil for an empty query.
+func (p *queryParser) parseOr() (Query, error) {
+	var clauses []clause
+	start := p.tok.offset
+	for p.tok.kind != tokEOF && p.tok.kind != tokRParen {
+		if p.tok.kind == tokOr {
+			if len(clauses) == 0 {
+				return nil, p.errorf("OR needs a clause before it")
+			}
+			p.advance()
+			if !p.atClause() {
+				return nil, p.errorf("OR needs a clause after it")
+			}
+		}
+		c, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		clauses = append(clauses, c)
+	}
+	if len(clauses) == 0 {
+		return nil, nil
+	}
+	if len(clauses) == 1 && clauses[0].occur == occurShould {
+		return clauses[0].query, nil
+	}
+	q := newBooleanQuery(clauses)
+	if len(q.Must) == 0 && len(q.Should) == 0 {
+		return nil, &SyntaxError{Offset: start, Msg: "a query cannot only exclude terms"}
+	}
+	if len(q.Must) == 0 && len(q.MustNot) == 0 && len(q.Should) == 1 {
+		return q.Should[0], nil // Only optional words
+	}
+	return q, nil
+}
+
+// parseAnd parses a clause and the clauses joined to it with AND.
+func (p *queryParser) parseAnd() (clause, error) {
+	start := p.tok.offset
+	first, err := p.parseNear()
+	if err != nil {
+		return clause{}, err
+	}
+	if p.tok.kind != tokAnd {
+		return first, nil
+	}
+	clauses := []clause{first}
+	for p.tok.kind == tokAnd {
+		p.advance()
+		if !p.atClause() {
+			return clause{}, p.errorf("AND needs a clause after it")
+		}
+		c, err := p.parseNear()
+		if err != nil {
+			return clause{}, err
+		}
+		clauses = append(clauses, c)
+	}
+	for i := range clauses {
+		if clauses[i].occur == occurShould {
+			clauses[i].occur = occurMust
+		}
+	}
+	q := newBooleanQuery(clauses)
+	if len(q.Must) == 0 {
+		return clause{}, &SyntaxError{Offset: start, Msg: "a query cannot only exclude terms"}
+	}
+	return clause{occur: occurShould, query: q}, nil
+}
+
+// parseNear parses a clause and the words joined to it with NEAR. All the
+// NEAR operators of a chain must have the same distance.
+func (p *queryParser) parseNear() (clause, error) {
+	first, err := p.parseUnary()
+	if err != nil {
+		return clause{}, err
+	}
+	if p.tok.kind != tokNear {
+		return first, nil
+	}
+	word, ok := first.query.(*TermQuery)
+	if !ok || first.occur != occurShould {
+		return clause{}, p.errorf("%s needs a word before it", p.tok.text)
+	}
+	q := &NearQuery{Terms: []*TermQuery{word}, Distance: p.tok.distance}
+	for p.tok.kind == tokNear {
+		if p.tok.distance < 0 {
+			return clause{}, p.errorf("invalid distance in %s", p.tok.text)
+		}
+		if p.tok.distance != q.Distance {
+			return clause{}, p.errorf("%s differs from the distance of the previous NEAR", p.tok.text)
+		}
+		op := p.tok.text
+		p.advance()
+		var word Query
+		if p.tok.kind == tokWord && !isQualified(p.tok.text) {
+			word, _ = wordQuery(p.tok.text)
+		}
+		term, ok := word.(*TermQuery)
+		if !ok {
+			return clause{}, p.errorf("%s needs a word after it", op)
+		}
+		q.Terms = append(q.Terms, term)
+		p.advance()
+	}
+	return clause{occur: occurShould, query: q}, nil
+}
+
+// parseUnary parses a word or group with its "+", "-" or NOT modifier.
+func (p *queryParser) parseUnary() (clause, error) {
+	o := occurShould
+	switch p.tok.kind {
+	case tokPlus:
+		o = occurMust
+		p.advance()
+	case tokMinus, tokNot:
+		o = occurMustNot
+		op := p.tok.text
+		p.advance()
+		if !p.atClause() || p.tok.kind == tokNot {
+			return clause{}, p.errorf("%s needs a clause after it", op)
+		}
+	}
+	q, err := p.parsePrimary()
+	if err != nil {
+		return clause{}, err
+	}
+	return clause{occur: o, query: q}, nil
+}
+
+// parsePrimary parses a word, a phrase, a parenthesized group or one of them
+// with a field qualifier or metadata filter.
+func (p *queryParser) parsePrimary() (Query, error) {
+	switch p.tok.kind {
+	case tokWord:
+		if isQualified(p.tok.text) {
+			name, _, _ := strings.Cut(p.tok.text, ":")
+			return p.parseQualified(name)
+		}
+		q, err := wordQuery(p.tok.text)
+		if err != nil {
+			return nil, p.errorf("%v", err)
+		}
+		p.advance()
+		return q, nil
+	case tokLParen:
+		open := p.tok
+		p.advance()
+		p.depth++
+		q, err := p.parseOr()
+		p.depth--
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokRParen {
+			return nil, &SyntaxError{Offset: open.offset, Msg: "unmatched '('"}
+		}
+		if q == nil {
+			return nil, &SyntaxError{Offset: open.offset, Msg: "empty group"}
+		}
+		p.advance()
+		return q, nil
+	case tokPhrase:
+		if p.tok.unterminated {
+			return nil, p.errorf("unmatched '\"'")
+		}
+		if strings.TrimSpace(p.tok.text) == "" {
+			return nil, p.errorf("empty phrase")
+		}
+		q := &PhraseQuery{Text: p.tok.text}
+		p.advance()
+		return q, nil
+	case tokAnd, tokOr, tokNear:
+		return nil, p.errorf("%s needs a clause before it", p.tok.text)
+	case tokEOF:
+		return nil, p.errorf("unexpected end of query")
+	}
+	return nil, p.errorf("unexpected %q", p.tok.text)
+}
+
+// parseQualified parses a word starting with a field qualifier or metadata
+// filter, e.g. title:crawler or site:go.dev. A qualifier followed directly by
+// a phrase or group, as in title:"inverted index", applies to all of it.
+func (p *queryParser) parseQualified(name string) (Query, error) {
+	qualifier := p.tok
+	value := strings.TrimPrefix(qualifier.text, name+":")
+	p.advance()
+	adjacent := p.tok.offset == qualifier.offset+len(qualifier.text)
+	filter := queryFilters[name]
+	if !filter && p.inField {
+		return nil, &SyntaxError{Offset: qualifier.offset, Msg: "field qualifiers cannot be nested"}
+	}
+	if filter && p.depth > 0 {
+		return nil, &SyntaxError{Offset: qualifier.offset, Msg: name + ": filters the whole query and cannot be grouped"}
+	}
+
+	if value != "" {
+		if filter {
+			return &FilterQuery{Key: name, Value: value}, nil
+		}
+		q, err := wordQuery(value)
+		if err != nil {
+			return nil, &SyntaxError{Offset: qualifier.offset + len(name) + 1, Msg: err.Error()}
+		}
+		return &FieldQuery{Field: name, Query: q}, nil
+	}
+	switch {
+	case adjacent && p.tok.kind == tokPhrase && filter:
+		if p.tok.unterminated {
+			return nil, p.errorf("unmatched '\"'")
+		}
+		value = strings.TrimSpace(p.tok.text)
+		if value == "" {
+			return nil, p.errorf("empty phrase")
+		}
+		p.advance()
+		return &FilterQuery{Key: name, Value: value}, nil
+	case adjacent && (p.tok.kind == tokPhrase || p.tok.kind == tokLParen) && !filter:
+		p.depth++
+		p.inField = true
+		q, err := p.parsePrimary()
+		p.depth--
+		p.inField = false
+		if err != nil {
+			return nil, err
+		}
+		return &FieldQuery{Field: name, Query: q}, nil
+	}
+	return nil, &SyntaxError{Offset: qualifier.offset, Msg: qualifier.text + " needs a value"}
+}
+
+// isQualified reports whether a word starts with a field qualifier or
+// metadata filter.
+func isQualified(word string) bool {
+	name, _, ok := strings.Cut(word, ":")
+	return ok && (queryFields[name] || queryFilters[name])
+}
+
+// wordQuery returns the query of a word: a regular expression between
+// slashes, a wildcard pattern, a fuzzy word or a plain word.
+func wordQuery(text string) (Query, error) {
+	if len(text) > 2 && text[0] == '/' && text[len(text)-1] == '/' {
+		q := &RegexpQuery{Pattern: text[1 : len(text)-1]}
+		if _, err := q.pattern(); err != nil {
+			return nil, fmt.Errorf("invalid regular expression: %v", err)
+		}
+		return q, nil
+	}
+	if strings.ContainsRune(text, '*') || strings.ContainsRune(strings.TrimSuffix(text, "?"), '?') {
+		q := &WildcardQuery{Pattern: strings.ToLower(text)}
+		if _, err := q.pattern(); err != nil {
+			return nil, err
+		}
+		return q, nil
+	}
+	if i := strings.LastIndexByte(text, '~'); i > 0 {
+		distance := 0
+		if n := text[i+1:]; n != "" {
+			var err error
+			if distance, err = strconv.Atoi(n); err != nil {
+				return &TermQuery{Text: text}, nil // A word with a "~" inside
+			}
+			if distance < 1 || distance > MaxFuzzyDistance {
+				return nil, fmt.Errorf("the distance of %s must be 1 or 2", text)
+			}
+		}
+		return &FuzzyQuery{Text: text[:i], Distance: distance}, nil
+	}
+	return &TermQuery{Text: text}, nil
+}
+
+// atClause reports whether the current token can start a clause.
+func (p *queryParser) atClause() bool {
+	switch p.tok.kind {
+	case tokWord, tokPhrase, tokLParen, tokPlus, tokMinus, tokNot:
+		return true
+	}
+	return false
+}
+
+// newBooleanQuery sorts clauses by occurrence. The optional words are joined
+// into a single TermQuery, analyzed together like a plain query.
+func newBooleanQuery(clauses []clause) *BooleanQuery {
+	q := &BooleanQuery{}
+	var words []string
+	for _, c := range clauses {
+		switch c.occur {
+		case occurMust:
+			q.Must = append(q.Must, c.query)
+		case occurMustNot:
+			q.MustNot = append(q.MustNot, c.query)
+		default:
+			if t, ok := c.query.(*TermQuery); ok {
+				words = append(words, t.Text)
+				continue
+			}
+			q.Should = append(q.Should, c.query)
+		}
+	}
+	if len(words) > 0 {
+		q.Should = append([]Query{&TermQuery{Text: strings.Join(words, " ")}}, q.Should...)
+	}
+	return q
+}
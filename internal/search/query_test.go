@@ -0,0 +1,112 @@
+package search
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseQuery(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"inverted index", "inverted index"},
+		{"mongo -atlas", "mongo -atlas"},
+		{"+go -java rust", "+go rust -java"},
+		{"(crawler OR indexer) AND robots", "+(crawler indexer) +robots"},
+		{"a OR b AND c", "a (+b +c)"},
+		{"a AND NOT b", "+a -b"},
+		{"-(draft OR wip) report", "report -(draft wip)"},
+		{"state-of-the-art e-mail", "state-of-the-art e-mail"},
+		{"go - tutorial", "go - tutorial"},
+		{"and or not", "and or not"},
+		{`"inverted index" mongo`, `mongo "inverted index"`},
+		{`+"state of the art" -"work in progress"`, `+"state of the art" -"work in progress"`},
+		{`say"hello"`, `say "hello"`},
+		{"crawler NEAR/3 robots", "crawler NEAR/3 robots"},
+		{"crawler NEAR robots NEAR txt", "crawler NEAR/5 robots NEAR/5 txt"},
+		{"go crawler NEAR/3 robots", "go (crawler NEAR/3 robots)"},
+		{"go crawler NEAR/3 robots AND mongo", "go (+(crawler NEAR/3 robots) +mongo)"},
+		{"title:crawler robots", "robots title:crawler"},
+		{`title:"inverted index" -url:draft`, `title:"inverted index" -url:draft`},
+		{"title:(crawler OR indexer) AND robots", "+title:(crawler indexer) +robots"},
+		{"crawler site:go.dev ext:md -source:web", "crawler site:go.dev ext:md -source:web"},
+		{`runbook path:"docs/on call"`, `runbook path:"docs/on call"`},
+		{"url:https://go.dev/doc", "url:https://go.dev/doc"},
+		{"std::vector Title:x", "std::vector Title:x"},
+		{"Index* craw?er", "index* craw?er"},
+		{"what is go?", "what is go?"},
+		{"/crawl(er|ing)/ robots", "robots /crawl(er|ing)/"},
+		{"title:/crawl(er|ing)/ url:docs*", "title:/crawl(er|ing)/ url:docs*"},
+		{"/usr/bin (a/b/)", "/usr/bin a/b/"},
+		{`/a\/b/`, `/a\/b/`},
+		{"crwaler~ robots~1 title:indxer~2", "crwaler~ robots~1 title:indxer~2"},
+		{"~/docs a~b", "~/docs a~b"},
+		{"", ""},
+	}
+	for _, tc := range testCases {
+		q, err := ParseQuery(tc.input)
+		if err != nil {
+			t.Errorf("ParseQuery(%q) returned an error: %v", tc.input, err)
+			continue
+		}
+		if q.String() != tc.expected {
+			t.Errorf("ParseQuery(%q) = %q, expected %q", tc.input, q.String(), tc.expected)
+		}
+	}
+}
+
+func TestParseQuery_Errors(t *testing.T) {
+	testCases := []struct {
+		input  string
+		offset int
+	}{
+		{"(crawler OR indexer", 0},
+		{"crawler)", 7},
+		{"crawler AND", 11},
+		{"OR crawler", 0},
+		{"crawler OR", 10},
+		{"AND crawler", 0},
+		{"-atlas", 0},
+		{"-java AND NOT rust", 0},
+		{"go ()", 3},
+		{"NOT NOT go", 4},
+		{`mongo "inverted index`, 6},
+		{`mongo "  "`, 6},
+		{"NEAR/3 robots", 0},
+		{"crawler NEAR/3", 14},
+		{"crawler NEAR/0 robots", 8},
+		{"crawler NEAR/x robots", 8},
+		{"crawler NEAR/3 robots NEAR/4 txt", 22},
+		{`"web crawler" NEAR robots`, 14},
+		{"crawler NEAR (robots OR txt)", 13},
+		{"-crawler NEAR robots", 9},
+		{"site:go.dev", 0},
+		{"-site:go.dev", 0},
+		{"crawler (site:go.dev OR indexer)", 9},
+		{"crawler title:(robots site:go.dev)", 22},
+		{"crawler title:(url:robots)", 15},
+		{"crawler title:", 8},
+		{"crawler ext: md", 8},
+		{`crawler path:"docs`, 13},
+		{"crawler *", 8},
+		{"crawler ??", 8},
+		{"/crawl(er/", 0},
+		{"crawler title:/a(/", 14},
+		{"crawler NEAR index*", 13},
+		{"crawler NEAR title:robots", 13},
+		{"crawler robots~3", 8},
+		{"crawler NEAR robots~", 13},
+	}
+	for _, tc := range testCases {
+		_, err := ParseQuery(tc.input)
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("ParseQuery(%q): expected a syntax error, got %v", tc.input, err)
+			continue
+		}
+		if syntaxErr.Offset != tc.offset {
+			t.Errorf("ParseQuery(%q): expected the error at offset %d, got %v", tc.input, tc.offset, syntaxErr)
+		}
+	}
+}
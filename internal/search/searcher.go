@@ -11,52 +11,99 @@ import (
 
 // Searcher defines the interface for searching documents.
 type Searcher interface {
-	Search(ctx context.Context, query string, pagination storage.GetDocumentsFilter) (SearchDocumentResponse, error)
+	Search(ctx context.Context, query string, opts SearchOptions) (SearchDocumentResponse, error)
 }
 
+// SearchOptions holds the pagination and metadata filters passed to the
+// store together with the options that change how a query is scored.
+type SearchOptions struct {
+	storage.GetDocumentsFilter
+	Fields   map[string]float64 // Fields to match and their boosts; when empty, DefaultFieldBoosts and the prefix field if indexed
+	Passages bool               // Attach the best matching passage to every result
+	// SoundsLike also matches words that sound like the query terms, in the
+	// phonetic field indexed when indexer.phonetic is enabled.
+	SoundsLike bool
+	// AutoFuzzy searches again with every plain word of the query made fuzzy
+	// when the query finds nothing, e.g. because of a typo.
+	AutoFuzzy bool
+}
+
+// facetFields are the document fields counted for every search response.
+var facetFields = []string{"label", "language"}
+
 // SearchResult represents a single search result.
 type SearchDocumentResponse struct {
-	Data  []SearchResult `json:"data,omitempty"`
-	Page  int            `json:"page"`
-	Limit int            `json:"limit"`
-	Total int            `json:"total"`
+	Data   []SearchResult            `json:"data,omitempty"`
+	Page   int                       `json:"page"`
+	Limit  int                       `json:"limit"`
+	Total  int                       `json:"total"`
+	Facets map[string]map[string]int `json:"facets,omitempty"`
+	Fuzzy  bool                      `json:"fuzzy,omitempty"` // The results are those of the query made fuzzy, see SearchOptions.AutoFuzzy
 }
 type SearchResult struct {
-	DocID string  `json:"docID"`
-	Title string  `json:"title"`
-	URL   string  `json:"url"`
-	Score float64 `json:"-"`
+	DocID      string            `json:"docID"`
+	Title      string            `json:"title"`
+	URL        string            `json:"url"`
+	SourceType string            `json:"sourceType,omitempty"`
+	Language   string            `json:"language,omitempty"`
+	Label      string            `json:"label,omitempty"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+	Passage    *PassageResult    `json:"passage,omitempty"`
+	Score      float64           `json:"-"`
+}
+
+// SearcherOptions configures a searcher.
+type SearcherOptions struct {
+	// MaxExpansions is the number of terms a wildcard, regular expression or
+	// fuzzy word expands to at most; DefaultMaxExpansions when not positive.
+	MaxExpansions int
 }
 
 // searcherImpl is the concrete implementation of the Searcher interface.
 type searcherImpl struct {
-	analyzer *analysis.Analyzer
-	store    *storage.MongoStore
+	analyzer   *analysis.MultiAnalyzer
+	store      *storage.MongoStore
+	dictionary *TermDictionary
 }
 
 // NewSearcher creates a new instance of the searcher.
-func NewSearcher(analyzer *analysis.Analyzer, store *storage.MongoStore) Searcher {
+func NewSearcher(analyzer *analysis.MultiAnalyzer, store *storage.MongoStore, opts SearcherOptions) Searcher {
 	return &searcherImpl{
-		analyzer: analyzer,
-		store:    store,
+		analyzer:   analyzer,
+		store:      store,
+		dictionary: NewTermDictionary(opts.MaxExpansions),
 	}
 }
 
 // Search performs a search for the given query.
-func (s *searcherImpl) Search(ctx context.Context, query string, pagination storage.GetDocumentsFilter) (SearchDocumentResponse, error) {
-	// 1. Analyze the query string.
-	queryTerms := s.analyzer.Analyze(query)
+func (s *searcherImpl) Search(ctx context.Context, query string, opts SearchOptions) (SearchDocumentResponse, error) {
+	pagination := opts.GetDocumentsFilter
+	fields := opts.Fields
+	if len(fields) == 0 {
+		fields = DefaultFieldBoosts
+		if s.analyzer.FieldAnalyzer(storage.FieldPrefix) != "" {
+			fields = withField(fields, storage.FieldPrefix, PrefixBoost)
+		}
+	}
+	if opts.SoundsLike {
+		fields = withField(fields, storage.FieldPhonetic, PhoneticBoost)
+	}
 
-	// 2. Fetch index data for the query terms from the store.
-	postings, err := s.store.GetPostingsForTerms(ctx, queryTerms)
+	// 1. Parse the query and move its metadata filters to the store filters.
+	parsed, err := ParseQuery(query)
 	if err != nil {
 		return SearchDocumentResponse{
 			Page:  int(pagination.Page),
 			Limit: int(pagination.Limit),
 		}, err
 	}
+	parsed, filters := splitFilters(parsed)
+	pagination.Filters = append(pagination.Filters, filters...)
+	if pagination.Language == "" {
+		pagination.Language = filterLanguage(filters)
+	}
 
-	// 3. Fetch global index stats for scoring.
+	// 2. Fetch global index stats for scoring.
 	stats, err := s.store.GetIndexStats(ctx)
 	if err != nil {
 		return SearchDocumentResponse{
@@ -64,46 +111,124 @@ func (s *searcherImpl) Search(ctx context.Context, query string, pagination stor
 			Limit: int(pagination.Limit),
 		}, err
 	}
-
-	// 4. Score the documents using the TF-IDF ranker.
 	scorer := ranking.NewTFIDFScorer(*stats)
-	docScores := scorer.Score(queryTerms, postings)
 
-	// 5. Fetch document metadata for the top-scoring documents.
-	docIDs := make([]string, 0, len(docScores))
-	for id := range docScores {
-		docIDs = append(docIDs, id)
-	}
-	documents, total, err := s.store.GetDocuments(ctx, docIDs, pagination)
+	// 3. Match and score the documents, and fetch the metadata of the
+	// top-scoring ones. When nothing matches, try again with the query made
+	// fuzzy if requested.
+	docScores, documents, total, err := s.findDocuments(ctx, parsed, fields, pagination, scorer, stats)
 	if err != nil {
 		return SearchDocumentResponse{
 			Page:  int(pagination.Page),
 			Limit: int(pagination.Limit),
 		}, err
 	}
+	fuzzy := false
+	if total == 0 && opts.AutoFuzzy {
+		if fuzzyParsed, ok := fuzzyQuery(parsed); ok {
+			docScores, documents, total, err = s.findDocuments(ctx, fuzzyParsed, fields, pagination, scorer, stats)
+			if err != nil {
+				return SearchDocumentResponse{
+					Page:  int(pagination.Page),
+					Limit: int(pagination.Limit),
+				}, err
+			}
+			fuzzy = true
+		}
+	}
+	docIDs := make([]string, 0, len(docScores))
+	for id := range docScores {
+		docIDs = append(docIDs, id)
+	}
 
-	// 6. Build the final search results.
+	// 4. Build the final search results.
 	results := make([]SearchResult, 0, len(documents))
 	for _, doc := range documents {
 		results = append(results, SearchResult{
-			DocID: doc.ID.Hex(),
-			Title: doc.Title,
-			URL:   doc.URL,
-			Score: docScores[doc.ID.Hex()],
+			DocID:      doc.ID.Hex(),
+			Title:      doc.Title,
+			URL:        doc.URL,
+			SourceType: doc.SourceType,
+			Language:   doc.Language,
+			Label:      doc.Label,
+			Metadata:   doc.Metadata,
+			Score:      docScores[doc.ID.Hex()],
 		})
 	}
 
-	// 7. Sort the results by score in descending order.
+	// 5. Sort the results by score in descending order.
 	sort.Slice(results, func(i, j int) bool {
 		return results[i].Score > results[j].Score
 	})
 
+	// 6. Find the best passage of every result when requested.
+	if opts.Passages {
+		if err := s.attachBestPassages(ctx, s.analyzer.AnalyzeFieldQuery(storage.FieldBody, matchedText(parsed), pagination.Language), scorer, results); err != nil {
+			return SearchDocumentResponse{
+				Page:  int(pagination.Page),
+				Limit: int(pagination.Limit),
+			}, err
+		}
+	}
+
+	// 7. Count the matching documents per label and language.
+	facets := make(map[string]map[string]int, len(facetFields))
+	for _, field := range facetFields {
+		counts, err := s.store.CountDocumentsBy(ctx, docIDs, pagination, field)
+		if err != nil {
+			return SearchDocumentResponse{
+				Page:  int(pagination.Page),
+				Limit: int(pagination.Limit),
+			}, err
+		}
+		if len(counts) > 0 {
+			facets[field] = counts
+		}
+	}
+
 	response := SearchDocumentResponse{
-		Data:  results,
-		Page:  int(pagination.Page),
-		Limit: int(pagination.Limit),
-		Total: total,
+		Data:   results,
+		Page:   int(pagination.Page),
+		Limit:  int(pagination.Limit),
+		Total:  total,
+		Facets: facets,
+		Fuzzy:  fuzzy,
 	}
 
 	return response, nil
 }
+
+// findDocuments matches the documents against q and scores them with the
+// TF-IDF ranker, weighting each field by its boost. It returns the scores of
+// all matching documents, and the page of them passing the filters with their
+// total.
+func (s *searcherImpl) findDocuments(ctx context.Context, q Query, fields map[string]float64, pagination storage.GetDocumentsFilter, scorer *ranking.TFIDFScorer, stats *storage.IndexStats) (map[string]float64, []*storage.Document, int, error) {
+	// Reload the term dictionary when the query expands terms and the index
+	// changed since it was loaded, then analyze the query words with the
+	// analyzer of every searched field, for the requested language or all of
+	// them.
+	if expandsTerms(q) {
+		if err := s.dictionary.refresh(ctx, s.store, stats.LastIndexedAt); err != nil {
+			return nil, nil, 0, err
+		}
+	}
+	matcher := newQueryMatcher(q, fields, s.analyzer, pagination.Language, s.dictionary)
+
+	// Fetch index data for the query terms in every searched field.
+	postings, err := s.store.GetPostingsForTerms(ctx, matcher.keys())
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	matcher.scorer, matcher.postings = scorer, postings
+	docScores, _ := matcher.match(q)
+
+	docIDs := make([]string, 0, len(docScores))
+	for id := range docScores {
+		docIDs = append(docIDs, id)
+	}
+	documents, total, err := s.store.GetDocuments(ctx, docIDs, pagination)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	return docScores, documents, total, nil
+}
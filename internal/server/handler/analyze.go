@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/TonyGLL/gofetch/internal/analysis"
+)
+
+// AnalyzerLookup is the part of the analyzer used by the analyze endpoint.
+type AnalyzerLookup interface {
+	Analyzer(name string) (*analysis.Analyzer, bool)
+	For(language string) *analysis.Analyzer
+	ForField(field, language string) *analysis.Analyzer
+}
+
+// Analyze is the handler for inspecting how a text is analyzed.
+type Analyze struct {
+	Analyzer AnalyzerLookup
+}
+
+// ServeHTTP handles GET /analyze?text=...&analyzer=...&field=... The
+// analyzer is a language or registered analyzer name, the default analyzer
+// when empty; with a field, the analyzer assigned to the field is used.
+func (a *Analyze) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	text := r.URL.Query().Get("text")
+	if text == "" {
+		http.Error(w, "query parameter 'text' is missing", http.StatusBadRequest)
+		return
+	}
+
+	name := r.URL.Query().Get("analyzer")
+	analyzer := a.Analyzer.For(name)
+	if name != "" {
+		var ok bool
+		if analyzer, ok = a.Analyzer.Analyzer(name); !ok {
+			http.Error(w, fmt.Sprintf("unknown analyzer %q", name), http.StatusBadRequest)
+			return
+		}
+	}
+	if field := r.URL.Query().Get("field"); field != "" {
+		analyzer = a.Analyzer.ForField(field, analyzer.Name())
+	}
+
+	writeJSON(w, http.StatusOK, analyzer.Explanation(text))
+}
@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/TonyGLL/gofetch/internal/indexer"
+)
+
+const (
+	maxDocumentBytes  = 10 << 20  // 10 MiB for a single document
+	maxBulkBytes      = 100 << 20 // 100 MiB for a bulk request
+	bulkBatchSize     = 100       // Documents written per bulk operation
+	maxBulkLineLength = maxDocumentBytes
+)
+
+// DocumentIndexer is the part of the indexer used by the document endpoints.
+type DocumentIndexer interface {
+	IndexDocument(ctx context.Context, input indexer.DocumentInput) (indexer.IndexResult, error)
+	IndexDocuments(ctx context.Context, inputs []indexer.DocumentInput) ([]indexer.IndexResult, error)
+}
+
+// Documents is the handler for pushing a single document into the index.
+type Documents struct {
+	Indexer DocumentIndexer
+}
+
+// ServeHTTP handles POST /documents with a JSON DocumentInput body.
+func (d *Documents) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !requireContentType(w, r, "application/json") {
+		return
+	}
+	var input indexer.DocumentInput
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxDocumentBytes))
+	if err := decoder.Decode(&input); err != nil {
+		http.Error(w, fmt.Sprintf("invalid document: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	result, err := d.Indexer.IndexDocument(r.Context(), input)
+	if err != nil {
+		if errors.Is(err, indexer.ErrInvalidDocument) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, indexer.ErrConflict) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		log.Printf("error indexing document: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	status := http.StatusOK
+	if result.Status == indexer.StatusCreated {
+		status = http.StatusCreated
+	}
+	writeJSON(w, status, result)
+}
+
+// BulkDocuments is the handler for pushing many documents as NDJSON.
+type BulkDocuments struct {
+	Indexer DocumentIndexer
+}
+
+// BulkResponse summarizes a bulk request, with one item per input line.
+type BulkResponse struct {
+	Took   int64                 `json:"took_ms"`
+	Errors bool                  `json:"errors"`
+	Items  []indexer.IndexResult `json:"items"`
+}
+
+// ServeHTTP handles POST /documents/_bulk. Every non-empty line of the body is
+// a JSON DocumentInput, sent as application/x-ndjson or application/json. Malformed lines and documents that could not be
+// written are reported per item, as the documents before them are already
+// written; a body that cannot be read further ends the request with an item
+// reporting why.
+func (b *BulkDocuments) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !requireContentType(w, r, "application/x-ndjson", "application/json") {
+		return
+	}
+	start := time.Now()
+	scanner := bufio.NewScanner(http.MaxBytesReader(w, r.Body, maxBulkBytes))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxBulkLineLength)
+
+	response := BulkResponse{Items: []indexer.IndexResult{}}
+	batch := make([]indexer.DocumentInput, 0, bulkBatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		results, err := b.Indexer.IndexDocuments(r.Context(), batch)
+		if err != nil {
+			log.Printf("error indexing bulk documents: %v", err)
+		}
+		response.Items = append(response.Items, results...)
+		batch = batch[:0]
+	}
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+		var input indexer.DocumentInput
+		if err := json.Unmarshal(raw, &input); err != nil {
+			flush() // Keep results in input order
+			response.Items = append(response.Items, indexer.IndexResult{
+				Status: indexer.StatusError,
+				Error:  fmt.Sprintf("line %d: %v", line, err),
+			})
+			continue
+		}
+		batch = append(batch, input)
+		if len(batch) >= bulkBatchSize {
+			flush()
+		}
+	}
+	flush()
+	if err := scanner.Err(); err != nil {
+		response.Items = append(response.Items, indexer.IndexResult{
+			Status: indexer.StatusError,
+			Error:  fmt.Sprintf("line %d: invalid bulk body, the remaining lines were not read: %v", line+1, err),
+		})
+	}
+
+	for _, item := range response.Items {
+		if item.Status == indexer.StatusError {
+			response.Errors = true
+			break
+		}
+	}
+	response.Took = time.Since(start).Milliseconds()
+	writeJSON(w, http.StatusOK, response)
+}
+
+// requireContentType reports whether the request body has one of the given
+// media types, and otherwise answers 415 Unsupported Media Type. Browsers send
+// such bodies to another origin only after a CORS preflight, so web pages on
+// other sites cannot post documents.
+func requireContentType(w http.ResponseWriter, r *http.Request, mediaTypes ...string) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err == nil {
+		for _, allowed := range mediaTypes {
+			if mediaType == allowed {
+				return true
+			}
+		}
+	}
+	http.Error(w, fmt.Sprintf("unsupported content type %q, expected %s", r.Header.Get("Content-Type"), strings.Join(mediaTypes, " or ")), http.StatusUnsupportedMediaType)
+	return false
+}
+
+// writeJSON writes v as a JSON response with the given status code.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		// This error is harder to handle as the headers might already be written.
+		log.Printf("error encoding response: %v", err)
+	}
+}
@@ -2,6 +2,8 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
@@ -54,10 +56,28 @@ func (s *Search) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
-	results, err := s.Searcher.Search(r.Context(), query, storage.GetDocumentsFilter{
-		Page:  pageInt64,
-		Limit: limitInt64,
+	fields, err := search.ParseFieldBoosts(r.URL.Query().Get("fields"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("query parameter 'fields' is invalid: %v", err), http.StatusBadRequest)
+		return
+	}
+	results, err := s.Searcher.Search(r.Context(), query, search.SearchOptions{
+		GetDocumentsFilter: storage.GetDocumentsFilter{
+			Page:     pageInt64,
+			Limit:    limitInt64,
+			Language: r.URL.Query().Get("lang"),
+			Label:    r.URL.Query().Get("label"),
+		},
+		Fields:     fields,
+		Passages:   r.URL.Query().Get("passages") == "true",
+		SoundsLike: r.URL.Query().Get("sounds_like") == "true",
+		AutoFuzzy:  r.URL.Query().Get("auto_fuzzy") == "true",
 	})
+	var syntaxErr *search.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		http.Error(w, fmt.Sprintf("query parameter 'q' is invalid: %v", syntaxErr), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		// Log the error internally
 		// In a real app, you'd use a structured logger.
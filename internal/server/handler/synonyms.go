@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+)
+
+// SynonymReloader is the part of the analyzer used by the synonym endpoint.
+type SynonymReloader interface {
+	ReloadQuerySynonyms() (int, error)
+}
+
+// SynonymsResponse reports the number of query synonym rules in use.
+type SynonymsResponse struct {
+	Rules int `json:"rules"`
+}
+
+// Synonyms is the handler for reloading the query synonym file.
+type Synonyms struct {
+	Analyzer SynonymReloader
+}
+
+// ServeHTTP handles POST /synonyms/_reload. The new rules apply to the next
+// queries; on error the previous rules stay in use.
+func (s *Synonyms) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rules, err := s.Analyzer.ReloadQuerySynonyms()
+	if err != nil {
+		log.Printf("error reloading synonyms: %v", err)
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	log.Printf("Reloaded %d query synonym rules", rules)
+	writeJSON(w, http.StatusOK, SynonymsResponse{Rules: rules})
+}
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/TonyGLL/gofetch/pkg/storage"
+)
+
+// ThreadReader is the part of the store used by the thread endpoint.
+type ThreadReader interface {
+	GetThread(ctx context.Context, threadID string) ([]*storage.Document, error)
+}
+
+// ThreadMessage is one message of a mail thread.
+type ThreadMessage struct {
+	DocID     string    `json:"doc_id"`
+	MessageID string    `json:"message_id"`
+	InReplyTo string    `json:"in_reply_to,omitempty"`
+	Subject   string    `json:"subject"`
+	From      string    `json:"from"`
+	Date      time.Time `json:"date"`
+	Label     string    `json:"label"`
+}
+
+// ThreadResponse lists the messages of a thread, oldest first.
+type ThreadResponse struct {
+	ThreadID string          `json:"thread_id"`
+	Messages []ThreadMessage `json:"messages"`
+}
+
+// Threads is the handler for reading the mail thread of a search result.
+type Threads struct {
+	Store ThreadReader
+}
+
+// ServeHTTP handles GET /threads/{id}, where id is the thread_id metadata of a result.
+func (t *Threads) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	threadID := r.PathValue("id")
+	documents, err := t.Store.GetThread(r.Context(), threadID)
+	if err != nil {
+		log.Printf("error reading thread %s: %v", threadID, err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if len(documents) == 0 {
+		http.Error(w, "thread not found", http.StatusNotFound)
+		return
+	}
+
+	response := ThreadResponse{ThreadID: threadID, Messages: make([]ThreadMessage, 0, len(documents))}
+	for _, doc := range documents {
+		response.Messages = append(response.Messages, ThreadMessage{
+			DocID:     doc.ID.Hex(),
+			MessageID: doc.Metadata["message_id"],
+			InReplyTo: doc.Metadata["in_reply_to"],
+			Subject:   doc.Title,
+			From:      doc.Metadata["from"],
+			Date:      doc.ModifiedAt,
+			Label:     doc.Label,
+		})
+	}
+	writeJSON(w, http.StatusOK, response)
+}
@@ -2,12 +2,13 @@ package middleware
 
 import "net/http"
 
-// CORS is a middleware that adds Cross-Origin Resource Sharing headers.
+// CORS is a middleware that adds Cross-Origin Resource Sharing headers. Any
+// origin may read from the API, but only the same origin may write to it.
 func CORS(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Set the headers that allow cross-origin requests.
 		w.Header().Set("Access-Control-Allow-Origin", "*") // Allow any origin
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 
 		// If it's a preflight request (OPTIONS), we handle it here and stop the chain.
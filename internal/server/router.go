@@ -7,7 +7,6 @@ import (
 
 	"github.com/TonyGLL/gofetch/internal/builder"
 	"github.com/TonyGLL/gofetch/internal/config"
-	"github.com/TonyGLL/gofetch/internal/search"
 	"github.com/TonyGLL/gofetch/internal/server/handler"
 	"github.com/TonyGLL/gofetch/internal/server/middleware"
 )
@@ -26,22 +25,54 @@ func NewRouter() *http.ServeMux {
 	}
 
 	// 2. Create the analyzer.
-	analyzer := builder.NewAnalyzer()
+	analyzer, err := builder.NewAnalyzer(&cfg)
+	if err != nil {
+		log.Fatalf("Failed to create analyzer: %v", err)
+	}
 
 	// 3. Create the searcher with its dependencies.
-	searcher := search.NewSearcher(analyzer, store)
+	searcher := builder.NewSearcher(analyzer, store, &cfg)
 
 	// 4. Create the search handler with its dependency.
 	searchHandler := &handler.Search{
 		Searcher: searcher,
 	}
 
+	// 5. Create the indexer and the document ingestion handlers.
+	idx := builder.NewIndexer(analyzer, store, &cfg)
+	documentsHandler := &handler.Documents{
+		Indexer: idx,
+	}
+	bulkDocumentsHandler := &handler.BulkDocuments{
+		Indexer: idx,
+	}
+
+	// 6. Create the mail thread handler.
+	threadsHandler := &handler.Threads{
+		Store: store,
+	}
+
+	// 7. Create the query synonym reload handler.
+	synonymsHandler := &handler.Synonyms{
+		Analyzer: analyzer,
+	}
+
+	// 8. Create the analyzer inspection handler.
+	analyzeHandler := &handler.Analyze{
+		Analyzer: analyzer,
+	}
+
 	// --- Routing ---
 
 	mux := http.NewServeMux()
 
 	v1 := http.NewServeMux()
 	v1.Handle("GET /search", searchHandler)
+	v1.Handle("POST /documents", documentsHandler)
+	v1.Handle("POST /documents/_bulk", bulkDocumentsHandler)
+	v1.Handle("GET /threads/{id}", threadsHandler)
+	v1.Handle("POST /synonyms/_reload", synonymsHandler)
+	v1.Handle("GET /analyze", analyzeHandler)
 
 	// Chain middleware
 	v1WithMiddleware := middleware.Chain(
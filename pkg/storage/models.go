@@ -8,14 +8,83 @@ import (
 
 // Document (no changes)
 type Document struct {
-	ID         primitive.ObjectID `bson:"_id,omitempty"`
-	SourceType string             `bson:"source_type"`
-	URL        string             `bson:"url"`
-	Title      string             `bson:"title"`
-	Content    string             `bson:"content"`
-	IndexedAt  time.Time          `bson:"indexed_at"`
-	ModifiedAt time.Time          `bson:"modified_at"`
-	FilePath   string             `bson:"file_path"`
+	ID             primitive.ObjectID `bson:"_id,omitempty"`
+	SourceType     string             `bson:"source_type"`
+	URL            string             `bson:"url"`
+	Title          string             `bson:"title"`
+	Content        string             `bson:"content"`
+	IndexedAt      time.Time          `bson:"indexed_at"`
+	ModifiedAt     time.Time          `bson:"modified_at"`
+	FilePath       string             `bson:"file_path"`
+	Language       string             `bson:"language"`
+	Analyzer       string             `bson:"analyzer,omitempty"`        // Name of the analyzer that built the document's postings
+	FieldAnalyzers map[string]string  `bson:"field_analyzers,omitempty"` // Fields analyzed with an analyzer of their own
+	Label          string             `bson:"label"`                     // Name of the source the document was indexed from
+	ExternalID     string             `bson:"external_id,omitempty"`     // Caller-provided ID for documents pushed through the API
+	Version        int                `bson:"version,omitempty"`         // For documents with an external ID: incremented on every upsert
+	Fields         map[string]string  `bson:"fields,omitempty"`          // Additional searchable text, e.g. "summary"
+	Metadata       map[string]string  `bson:"metadata,omitempty"`        // Arbitrary key/value data returned with results
+	Terms          []string           `bson:"terms,omitempty"`           // Index terms written for this document, to find its postings quickly
+	ParentID       primitive.ObjectID `bson:"parent_id,omitempty"`       // For passages: the document they were split from
+	Start          int                `bson:"start,omitempty"`           // For passages: byte offset of the passage in the parent content
+	End            int                `bson:"end,omitempty"`             // For passages: byte offset where the passage ends
+}
+
+// Standard document fields with their own postings. Custom fields from
+// Document.Fields are indexed under their own names, and together in FieldCustom.
+const (
+	FieldBody     = "body"
+	FieldTitle    = "title"
+	FieldHeadings = "headings"
+	FieldURL      = "url"
+	FieldPassage  = "passage"  // Terms of passage documents, kept apart from whole documents
+	FieldComments = "comments" // Comments of source code documents
+	FieldStrings  = "strings"  // String literals of source code documents
+	FieldPrefix   = "prefix"   // Edge n-grams of the title and body, for prefix matches
+	FieldPhonetic = "phonetic" // Phonetic codes of the title and body, for sounds-like matches
+	FieldCustom   = "custom"   // Text of every custom field, so plain queries find it
+)
+
+// IsStandardField reports whether name is one of the standard Field* fields,
+// which custom fields cannot be named after.
+func IsStandardField(name string) bool {
+	switch name {
+	case FieldBody, FieldTitle, FieldHeadings, FieldURL, FieldPassage, FieldComments,
+		FieldStrings, FieldPrefix, FieldPhonetic, FieldCustom:
+		return true
+	}
+	return false
+}
+
+// SourceTypePassage marks documents that are passages of a longer parent document.
+const SourceTypePassage = "passage"
+
+// Metadata filter keys: the document attributes search results can be
+// restricted to, see MetadataFilter.
+const (
+	FilterSite   = "site"   // Host of the URL or one of its subdomains, e.g. "go.dev"
+	FilterExt    = "ext"    // Extension of the file path or URL, e.g. "md"
+	FilterSource = "source" // Source type, e.g. "file" or "web"
+	FilterLang   = "lang"   // Language, e.g. "spanish"
+	FilterPath   = "path"   // Directory of the file path or URL, e.g. "docs/runbooks"
+)
+
+// MetadataFilter restricts documents by one of their attributes, named by a
+// Filter* key.
+type MetadataFilter struct {
+	Key     string
+	Values  []string // A document matches when any value matches
+	Exclude bool     // Keep the documents that match no value instead
+}
+
+// FieldTerm returns the inverted index key of a term within a field, e.g.
+// "title:index". Body terms are stored unprefixed so indexes built before
+// fields existed keep working.
+func FieldTerm(field, term string) string {
+	if field == "" || field == FieldBody {
+		return term
+	}
+	return field + ":" + term
 }
 
 // Posting (with the Positions field added)
@@ -33,6 +102,28 @@ type InvertedIndexEntry struct {
 	DF       int       `bson:"df"` // ADDED: Document Frequency
 }
 
+// DictionaryTerm is an inverted index key with the number of documents
+// containing it, without its postings.
+type DictionaryTerm struct {
+	Term string `bson:"_id"`
+	DF   int    `bson:"df"`
+}
+
+// SourceState records how far an incremental source has been indexed,
+// e.g. the last indexed commit of a git repository.
+type SourceState struct {
+	ID        string    `bson:"_id"`
+	Cursor    string    `bson:"cursor"`
+	UpdatedAt time.Time `bson:"updated_at"`
+}
+
+// AnalyzerRecord is the definition of an analyzer the index was built with.
+type AnalyzerRecord struct {
+	Name       string    `bson:"_id"`
+	Definition string    `bson:"definition"`
+	UpdatedAt  time.Time `bson:"updated_at"`
+}
+
 // IndexStats (no changes)
 type IndexStats struct {
 	ID             primitive.ObjectID `bson:"_id,omitempty"`
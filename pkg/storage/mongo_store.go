@@ -4,6 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
+	"regexp"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -18,11 +21,16 @@ type MongoStore struct {
 	documentCollection *mongo.Collection
 	indexCollection    *mongo.Collection
 	statsCollection    *mongo.Collection
+	stateCollection    *mongo.Collection
+	analyzerCollection *mongo.Collection
 }
 
 type GetDocumentsFilter struct {
-	Page  int64
-	Limit int64
+	Page     int64
+	Limit    int64
+	Language string           // Optional: only return documents in this language
+	Label    string           // Optional: only return documents from the source with this label
+	Filters  []MetadataFilter // Optional: only return documents matching every filter
 }
 
 // NewMongoStore is a constructor function that initializes an instance of MongoStore.
@@ -58,11 +66,32 @@ func (s *MongoStore) Connect(ctx context.Context, mongoURI, dbName string) error
 	s.documentCollection = s.database.Collection("documents")
 	s.indexCollection = s.database.Collection("inverted_index")
 	s.statsCollection = s.database.Collection("stats")
+	s.stateCollection = s.database.Collection("source_state")
+	s.analyzerCollection = s.database.Collection("analyzers")
+
+	if err := s.ensureIndexes(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to create indexes: %v\n", err)
+	}
 
 	fmt.Println("Connected to MongoDB successfully.")
 	return nil
 }
 
+// ensureIndexes creates the indexes the store relies on. Documents are
+// unique by external ID and version, so when the same document is upserted
+// concurrently only one of the writes succeeds. Creating the index fails
+// while older duplicates of an external ID remain.
+func (s *MongoStore) ensureIndexes(ctx context.Context) error {
+	_, err := s.documentCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "external_id", Value: 1}, {Key: "version", Value: 1}},
+		Options: options.Index().
+			SetName("external_id_version").
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{"external_id": bson.M{"$exists": true}}),
+	})
+	return err
+}
+
 // Disconnect safely closes the database connection.
 func (s *MongoStore) Disconnect(ctx context.Context) error {
 	if s.client == nil {
@@ -107,18 +136,20 @@ func (s *MongoStore) UpsertTerm(ctx context.Context, term string, posting Postin
 
 const statsDocumentID = "global_stats"
 
-// UpdateIndexStats updates the global statistics document.
-// It uses an upsert operation to create the document if it does not exist.
-func (s *MongoStore) UpdateIndexStats(ctx context.Context, totalDocs int64) error {
+// UpdateIndexStats updates the global statistics document, adding delta
+// (which is negative when documents are removed) to the document count.
+// The count is incremented rather than set: callers only know how many
+// documents a batch wrote or removed, and setting it to that number left the
+// total at the size of the last batch. It uses an upsert operation to create
+// the document if it does not exist.
+func (s *MongoStore) UpdateIndexStats(ctx context.Context, delta int64) error {
 	// The filter targets the unique statistics document.
 	filter := bson.M{"_id": statsDocumentID}
 
-	// The update operation sets the total number of documents and the last update time.
+	// The update operation increments the total number of documents and sets the last update time.
 	update := bson.M{
-		"$set": bson.M{
-			"total_documents": totalDocs,
-			"last_indexed_at": time.Now(),
-		},
+		"$inc": bson.M{"total_documents": delta},
+		"$set": bson.M{"last_indexed_at": time.Now()},
 	}
 
 	// SetUpsert(true) ensures the document is created on the first run.
@@ -141,6 +172,60 @@ func (s *MongoStore) GetIndexStats(ctx context.Context) (*IndexStats, error) {
 	return &stats, nil
 }
 
+// GetSourceState retrieves the saved state of an incremental source, or nil
+// when the source has never been indexed.
+func (s *MongoStore) GetSourceState(ctx context.Context, id string) (*SourceState, error) {
+	var state SourceState
+	err := s.stateCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&state)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &state, nil
+}
+
+// SaveSourceState creates or replaces the state of an incremental source.
+func (s *MongoStore) SaveSourceState(ctx context.Context, state SourceState) error {
+	state.UpdatedAt = time.Now()
+	opts := options.Replace().SetUpsert(true)
+	_, err := s.stateCollection.ReplaceOne(ctx, bson.M{"_id": state.ID}, state, opts)
+	return err
+}
+
+// GetAnalyzers retrieves the definitions of the analyzers the index was built with.
+func (s *MongoStore) GetAnalyzers(ctx context.Context) ([]AnalyzerRecord, error) {
+	cursor, err := s.analyzerCollection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var records []AnalyzerRecord
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// SaveAnalyzers creates or replaces the definitions of the given analyzers.
+func (s *MongoStore) SaveAnalyzers(ctx context.Context, records []AnalyzerRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	models := make([]mongo.WriteModel, 0, len(records))
+	for _, record := range records {
+		record.UpdatedAt = time.Now()
+		models = append(models, mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"_id": record.Name}).
+			SetReplacement(record).
+			SetUpsert(true))
+	}
+	_, err := s.analyzerCollection.BulkWrite(ctx, models)
+	return err
+}
+
 // GetPostingsForTerms retrieves the inverted index entries for a given list of terms.
 func (s *MongoStore) GetPostingsForTerms(ctx context.Context, terms []string) (map[string]InvertedIndexEntry, error) {
 	if len(terms) == 0 {
@@ -170,21 +255,109 @@ func (s *MongoStore) GetPostingsForTerms(ctx context.Context, terms []string) (m
 	return results, nil
 }
 
-func (s *MongoStore) GetDocuments(ctx context.Context, docIDs []string, pagination GetDocumentsFilter) ([]*Document, int, error) {
-	if len(docIDs) == 0 {
-		return []*Document{}, 0, nil
+// GetTermDictionary retrieves every inverted index key still found in a
+// document, sorted, except those of the given fields.
+func (s *MongoStore) GetTermDictionary(ctx context.Context, skipFields []string) ([]DictionaryTerm, error) {
+	filter := bson.M{"df": bson.M{"$gt": 0}}
+	if len(skipFields) > 0 {
+		quoted := make([]string, len(skipFields))
+		for i, field := range skipFields {
+			quoted[i] = regexp.QuoteMeta(field)
+		}
+		filter["_id"] = bson.M{"$not": primitive.Regex{Pattern: "^(" + strings.Join(quoted, "|") + "):"}}
+	}
+	findOptions := options.Find().
+		SetProjection(bson.M{"df": 1}).
+		SetSort(bson.D{{Key: "_id", Value: 1}})
+	cursor, err := s.indexCollection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
 	}
+	defer cursor.Close(ctx)
+
+	var terms []DictionaryTerm
+	if err := cursor.All(ctx, &terms); err != nil {
+		return nil, err
+	}
+	return terms, nil
+}
 
+// documentsFilter builds the query matching the given document IDs and the
+// optional metadata filters.
+func documentsFilter(docIDs []string, pagination GetDocumentsFilter) (bson.M, error) {
 	objectIDs := make([]primitive.ObjectID, len(docIDs))
 	for i, id := range docIDs {
 		objID, err := primitive.ObjectIDFromHex(id)
 		if err != nil {
-			return nil, 0, err // Invalid ID format
+			return nil, err // Invalid ID format
 		}
 		objectIDs[i] = objID
 	}
 
 	filter := bson.M{"_id": bson.M{"$in": objectIDs}}
+	if pagination.Language != "" {
+		filter["language"] = strings.ToLower(pagination.Language) // Languages are stored lowercase
+	}
+	if pagination.Label != "" {
+		filter["label"] = pagination.Label
+	}
+	var conditions []bson.M
+	for _, f := range pagination.Filters {
+		matches := make([]bson.M, 0, len(f.Values))
+		for _, value := range f.Values {
+			condition, err := metadataCondition(f.Key, value)
+			if err != nil {
+				return nil, err
+			}
+			matches = append(matches, condition)
+		}
+		if f.Exclude {
+			conditions = append(conditions, bson.M{"$nor": matches})
+		} else {
+			conditions = append(conditions, bson.M{"$or": matches})
+		}
+	}
+	if len(conditions) > 0 {
+		filter["$and"] = conditions
+	}
+	return filter, nil
+}
+
+// metadataCondition returns the condition matching the documents whose
+// attribute named by key matches value. Sites match the host of web URLs and
+// its subdomains; extensions and directories match file paths and URLs.
+func metadataCondition(key, value string) (bson.M, error) {
+	switch key {
+	case FilterSite:
+		host := regexp.QuoteMeta(strings.TrimSuffix(value, "."))
+		return bson.M{"url": primitive.Regex{Pattern: `^[a-z][a-z0-9+.-]*://([^/?#@]*@)?([^/?#]*\.)?` + host + `(:[0-9]+)?([/?#]|$)`, Options: "i"}}, nil
+	case FilterExt:
+		ext := regexp.QuoteMeta(strings.TrimPrefix(value, "."))
+		return bson.M{"$or": []bson.M{
+			{"file_path": primitive.Regex{Pattern: `\.` + ext + `$`, Options: "i"}},
+			{"url": primitive.Regex{Pattern: `\.` + ext + `([?#]|$)`, Options: "i"}},
+		}}, nil
+	case FilterSource:
+		return bson.M{"source_type": value}, nil
+	case FilterLang:
+		return bson.M{"language": strings.ToLower(value)}, nil
+	case FilterPath:
+		dir := regexp.QuoteMeta(strings.Trim(value, "/"))
+		pattern := primitive.Regex{Pattern: `(^|/)` + dir + `([/?#]|$)`}
+		return bson.M{"$or": []bson.M{{"file_path": pattern}, {"url": pattern}}}, nil
+	}
+	return nil, fmt.Errorf("unknown metadata filter %q", key)
+}
+
+func (s *MongoStore) GetDocuments(ctx context.Context, docIDs []string, pagination GetDocumentsFilter) ([]*Document, int, error) {
+	if len(docIDs) == 0 {
+		return []*Document{}, 0, nil
+	}
+
+	filter, err := documentsFilter(docIDs, pagination)
+	if err != nil {
+		return nil, 0, err
+	}
 
 	findOptions := options.Find()
 	findOptions.SetSkip((pagination.Page - 1) * pagination.Limit)
@@ -208,6 +381,64 @@ func (s *MongoStore) GetDocuments(ctx context.Context, docIDs []string, paginati
 	return documents, int(total), nil
 }
 
+// CountDocumentsBy counts the matching documents grouped by the values of a
+// document field, e.g. "label" or "language". Empty values are not counted.
+// The filter on the field itself is left out, so the counts also show the
+// values the search is not restricted to.
+func (s *MongoStore) CountDocumentsBy(ctx context.Context, docIDs []string, pagination GetDocumentsFilter, field string) (map[string]int, error) {
+	counts := make(map[string]int)
+	if len(docIDs) == 0 {
+		return counts, nil
+	}
+
+	switch field {
+	case "label":
+		pagination.Label = ""
+	case "language":
+		pagination.Language = ""
+		pagination.Filters = withoutFilter(pagination.Filters, FilterLang)
+	}
+	filter, err := documentsFilter(docIDs, pagination)
+	if err != nil {
+		return nil, err
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$group", Value: bson.M{"_id": "$" + field, "count": bson.M{"$sum": 1}}}},
+	}
+	cursor, err := s.documentCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var bucket struct {
+			Value string `bson:"_id"`
+			Count int    `bson:"count"`
+		}
+		if err := cursor.Decode(&bucket); err != nil {
+			return nil, err
+		}
+		if bucket.Value != "" {
+			counts[bucket.Value] = bucket.Count
+		}
+	}
+	return counts, cursor.Err()
+}
+
+// withoutFilter returns the metadata filters with a different key than key.
+func withoutFilter(filters []MetadataFilter, key string) []MetadataFilter {
+	kept := make([]MetadataFilter, 0, len(filters))
+	for _, f := range filters {
+		if f.Key != key {
+			kept = append(kept, f)
+		}
+	}
+	return kept
+}
+
 // BulkWriteDocuments performs a bulk write operation on the documents collection.
 func (s *MongoStore) BulkWriteDocuments(ctx context.Context, models []mongo.WriteModel) error {
 	if len(models) == 0 {
@@ -242,20 +473,123 @@ func (s *MongoStore) GetDocumentByPath(ctx context.Context, filePath string) (*D
 	return &doc, nil
 }
 
+// GetDocumentsByExternalID retrieves the versions of the document with the
+// ID its producer assigned to it, newest first. There is more than one only
+// while an upsert is replacing the previous version, or when removing it failed.
+func (s *MongoStore) GetDocumentsByExternalID(ctx context.Context, externalID string) ([]*Document, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "version", Value: -1}})
+	cursor, err := s.documentCollection.Find(ctx, bson.M{"external_id": externalID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var documents []*Document
+	if err := cursor.All(ctx, &documents); err != nil {
+		return nil, err
+	}
+	return documents, nil
+}
+
+// GetExternalIDs retrieves the distinct external IDs starting with prefix,
+// e.g. those of the documents of one source.
+func (s *MongoStore) GetExternalIDs(ctx context.Context, prefix string) ([]string, error) {
+	filter := bson.M{"external_id": primitive.Regex{Pattern: "^" + regexp.QuoteMeta(prefix)}}
+	values, err := s.documentCollection.Distinct(ctx, "external_id", filter)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(values))
+	for _, v := range values {
+		if id, ok := v.(string); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// GetDocumentsByParent retrieves the passages split from a document.
+func (s *MongoStore) GetDocumentsByParent(ctx context.Context, parentID primitive.ObjectID) ([]*Document, error) {
+	cursor, err := s.documentCollection.Find(ctx, bson.M{"parent_id": parentID})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var documents []*Document
+	if err := cursor.All(ctx, &documents); err != nil {
+		return nil, err
+	}
+	return documents, nil
+}
+
+// GetThread retrieves the messages of a mail thread, oldest first.
+func (s *MongoStore) GetThread(ctx context.Context, threadID string) ([]*Document, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "modified_at", Value: 1}})
+	cursor, err := s.documentCollection.Find(ctx, bson.M{"metadata.thread_id": threadID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var documents []*Document
+	if err := cursor.All(ctx, &documents); err != nil {
+		return nil, err
+	}
+	return documents, nil
+}
+
+// GetPassages retrieves the passages with the given IDs that belong to one of
+// the given parent documents.
+func (s *MongoStore) GetPassages(ctx context.Context, passageIDs, parentIDs []primitive.ObjectID) ([]*Document, error) {
+	if len(passageIDs) == 0 || len(parentIDs) == 0 {
+		return []*Document{}, nil
+	}
+	filter := bson.M{
+		"_id":       bson.M{"$in": passageIDs},
+		"parent_id": bson.M{"$in": parentIDs},
+	}
+	cursor, err := s.documentCollection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var documents []*Document
+	if err := cursor.All(ctx, &documents); err != nil {
+		return nil, err
+	}
+	return documents, nil
+}
+
+// DeleteDocumentsByParent deletes every passage split from a document.
+func (s *MongoStore) DeleteDocumentsByParent(ctx context.Context, parentID primitive.ObjectID) error {
+	_, err := s.documentCollection.DeleteMany(ctx, bson.M{"parent_id": parentID})
+	return err
+}
+
 // DeleteDocument deletes a document from the 'documents' collection by its ID.
 func (s *MongoStore) DeleteDocument(ctx context.Context, docID primitive.ObjectID) error {
 	_, err := s.documentCollection.DeleteOne(ctx, bson.M{"_id": docID})
 	return err
 }
 
-// RemovePostingsForDocument removes all postings for a given document ID from the inverted index for a given list of terms.
+// RemovePostingsForDocument removes the postings of a document from the
+// inverted index. terms, the keys recorded when the document was indexed,
+// narrow the entries to update; when there are none, e.g. for documents
+// indexed before terms were recorded, every entry holding a posting of the
+// document is updated.
 func (s *MongoStore) RemovePostingsForDocument(ctx context.Context, docID primitive.ObjectID, terms []string) error {
-	if len(terms) == 0 {
-		return nil
+	// Only touch entries that still hold a posting for the document, so the
+	// document frequency is decremented exactly once per term.
+	filter := bson.M{"postings.doc_id": docID}
+	if len(terms) > 0 {
+		filter["_id"] = bson.M{"$in": terms}
+	}
+	update := bson.M{
+		"$pull": bson.M{"postings": bson.M{"doc_id": docID}},
+		"$inc":  bson.M{"df": -1},
 	}
-
-	filter := bson.M{"_id": bson.M{"_in": terms}}
-	update := bson.M{"_pull": bson.M{"postings": bson.M{"doc_id": docID}}}
 
 	_, err := s.indexCollection.UpdateMany(ctx, filter, update)
 	return err